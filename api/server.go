@@ -2,32 +2,16 @@ package api
 
 import (
 	"fmt"
-	"log"
-	"os"
+	"video-catalog/config"
 	"video-catalog/controllers"
-
-	"github.com/joho/godotenv"
 )
 
 var server = controllers.Server{}
 
-func init() {
-	// loads values from .env into the system
-	if err := godotenv.Load(); err != nil {
-		log.Print("sad .env file found")
-	}
-}
-
-// Run starts api services
-func Run() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("Error getting env, %v", err)
-	}
-	fmt.Println("Getting env values...")
-
-	server.Initialize(os.Getenv("DB_DRIVER"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_PORT"), os.Getenv("DB_HOST"), os.Getenv("DB_NAME"), os.Getenv("DB_DEBUG"))
+// Run starts api services and blocks until they are shut down
+func Run(cfg *config.Config) error {
+	server.Initialize(cfg)
 
-	apiPort := fmt.Sprintf(":%s", os.Getenv("API_PORT"))
-	fmt.Printf("Listening to port %s\n", apiPort)
-	server.Run(apiPort)
+	fmt.Printf("Listening to port %s\n", cfg.HTTP.Addr())
+	return server.Run()
 }
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"video-catalog/config"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Principal models the authenticated caller of a request, either a user
+// holding roles or an api key holding scopes
+type Principal struct {
+	Subject  string
+	Roles    []Role
+	Claims   map[string]interface{}
+	APIKeyID string
+	Scopes   []string
+}
+
+// Can tells whether the principal holds permission
+func (p *Principal) Can(permission Permission) bool {
+	if p.APIKeyID != "" {
+		return Granted(p.Scopes, permission)
+	}
+	return Allowed(p.Roles, permission)
+}
+
+// CanGrant tells whether the principal holds every permission of scope, so
+// it may hand scope over to an api key. A resource:* scope requires every
+// action over the resource.
+func (p *Principal) CanGrant(scope Permission) bool {
+	if scope.Action != "*" {
+		return p.Can(scope)
+	}
+	if p.APIKeyID != "" && Granted(p.Scopes, scope) {
+		return true
+	}
+	for _, action := range Actions {
+		if !p.Can(Permission{Resource: scope.Resource, Action: action}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Authenticator validates bearer tokens signed with HS256 by the configured
+// secret or with RS256 by a key of the configured JWKS
+type Authenticator struct {
+	secret   []byte
+	jwks     *JWKS
+	audience string
+	issuer   string
+}
+
+// NewAuthenticator returns an authenticator for the auth configuration
+func NewAuthenticator(cfg config.AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		secret:   []byte(cfg.Secret),
+		audience: cfg.Audience,
+		issuer:   cfg.Issuer,
+	}
+
+	switch {
+	case cfg.JWKSFile != "":
+		jwks, err := NewJWKSFromFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.jwks = jwks
+	case cfg.JWKSURL != "":
+		a.jwks = NewJWKSFromURL(cfg.JWKSURL)
+	}
+	return a, nil
+}
+
+// Authenticate validates token and returns its principal
+func (a *Authenticator) Authenticate(token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, a.key)
+	if err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Inner != nil {
+			return nil, ve.Inner
+		}
+		return nil, err
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("Token has no expiration")
+	}
+	if a.audience != "" && !hasAudience(claims["aud"], a.audience) {
+		return nil, errors.New("Token audience is not accepted")
+	}
+	if a.issuer != "" && !claims.VerifyIssuer(a.issuer, true) {
+		return nil, errors.New("Token issuer is not accepted")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("Token has no subject")
+	}
+
+	return &Principal{Subject: subject, Roles: RolesFromClaims(claims), Claims: claims}, nil
+}
+
+// key selects the verification key, refusing algorithms not configured
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if len(a.secret) == 0 {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		return a.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		if a.jwks == nil {
+			return nil, errors.New("RS256 tokens are not accepted")
+		}
+		kid, _ := token.Header["kid"].(string)
+		return a.jwks.Key(kid)
+	}
+	return nil, fmt.Errorf("Signing algorithm %s is not accepted", token.Method.Alg())
+}
+
+// hasAudience accepts aud as a single string or a list of strings
+func hasAudience(aud interface{}, expected string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == expected
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
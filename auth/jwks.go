@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval bounds how often an unknown key id triggers a fetch
+const jwksRefreshInterval = time.Minute
+
+// jwksTTL is how long fetched keys are trusted before being fetched again
+const jwksTTL = 10 * time.Minute
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS holds the RSA public keys of a JSON Web Key Set read from a file
+// or an url
+type JWKS struct {
+	load func() ([]byte, error)
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWKSFromFile returns a key set read once from path
+func NewJWKSFromFile(path string) (*JWKS, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read jwks file: %v", err)
+	}
+	j := &JWKS{load: func() ([]byte, error) { return content, nil }}
+	if err := j.refresh(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// NewJWKSFromURL returns a key set fetched from url and refreshed
+// periodically and whenever a token is signed by an unknown key
+func NewJWKSFromURL(url string) *JWKS {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return &JWKS{load: func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("jwks url answered %s", resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}}
+}
+
+// Key returns the public key identified by kid
+func (j *JWKS) Key(kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key, ok := j.keys[kid]
+	stale := time.Since(j.fetchedAt) > jwksTTL
+	if (!ok || stale) && time.Since(j.fetchedAt) > jwksRefreshInterval {
+		if err := j.refreshLocked(); err != nil && !ok {
+			return nil, err
+		}
+		key, ok = j.keys[kid]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (j *JWKS) refresh() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.refreshLocked()
+}
+
+func (j *JWKS) refreshLocked() error {
+	j.fetchedAt = time.Now()
+	content, err := j.load()
+	if err != nil {
+		return fmt.Errorf("cannot load jwks: %v", err)
+	}
+
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := json.Unmarshal(content, &set); err != nil {
+		return fmt.Errorf("cannot parse jwks: %v", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.rsaPublicKey()
+		if err != nil {
+			return fmt.Errorf("invalid jwks key %q: %v", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("jwks has no RSA signing key")
+	}
+	j.keys = keys
+	return nil
+}
+
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role models a catalog user role
+type Role string
+
+// Action models an operation over a resource
+type Action string
+
+// Resource models a protected catalog entity type
+type Resource string
+
+// catalog roles
+const (
+	RoleAdmin  Role = "admin"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+// resource actions
+const (
+	ActionRead    Action = "read"
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionRestore Action = "restore"
+)
+
+// protected resources
+const (
+	ResourceCategory   Resource = "category"
+	ResourceGenre      Resource = "genre"
+	ResourceCastMember Resource = "cast_member"
+	ResourceVideo      Resource = "video"
+	ResourceSeries     Resource = "series"
+	ResourceAPIKey     Resource = "api_key"
+	ResourceAudit      Resource = "audit"
+	ResourceWebhook    Resource = "webhook"
+)
+
+// Resources lists every protected resource
+var Resources = []Resource{ResourceCategory, ResourceGenre, ResourceCastMember, ResourceVideo, ResourceSeries, ResourceAPIKey, ResourceAudit, ResourceWebhook}
+
+// Actions lists every resource action
+var Actions = []Action{ActionRead, ActionCreate, ActionUpdate, ActionDelete, ActionRestore}
+
+// Permission models an action over a resource, written as resource:action
+type Permission struct {
+	Resource Resource
+	Action   Action
+}
+
+func (p Permission) String() string {
+	return string(p.Resource) + ":" + string(p.Action)
+}
+
+var catalogPolicy = map[Action][]Role{
+	ActionRead:    {RoleAdmin, RoleEditor, RoleViewer},
+	ActionCreate:  {RoleAdmin, RoleEditor},
+	ActionUpdate:  {RoleAdmin, RoleEditor},
+	ActionDelete:  {RoleAdmin},
+	ActionRestore: {RoleAdmin, RoleEditor},
+}
+
+var adminPolicy = map[Action][]Role{
+	ActionRead:   {RoleAdmin},
+	ActionCreate: {RoleAdmin},
+	ActionUpdate: {RoleAdmin},
+	ActionDelete: {RoleAdmin},
+}
+
+var auditPolicy = map[Action][]Role{
+	ActionRead: {RoleAdmin},
+}
+
+// Policy lists the roles allowed to run each action over each resource
+var Policy = map[Resource]map[Action][]Role{
+	ResourceCategory:   catalogPolicy,
+	ResourceGenre:      catalogPolicy,
+	ResourceCastMember: catalogPolicy,
+	ResourceVideo:      catalogPolicy,
+	ResourceSeries:     catalogPolicy,
+	ResourceAPIKey:     adminPolicy,
+	ResourceAudit:      auditPolicy,
+	ResourceWebhook:    adminPolicy,
+}
+
+// ParsePermission parses a resource:action scope. The action may be * to
+// grant every action over the resource.
+func ParsePermission(scope string) (Permission, error) {
+	parts := strings.SplitN(scope, ":", 2)
+	if len(parts) != 2 {
+		return Permission{}, fmt.Errorf("Scope %q must be resource:action", scope)
+	}
+
+	p := Permission{Resource: Resource(parts[0]), Action: Action(parts[1])}
+	if _, ok := Policy[p.Resource]; !ok {
+		return Permission{}, fmt.Errorf("Scope %q has an unknown resource", scope)
+	}
+	if p.Action == "*" {
+		return p, nil
+	}
+	for _, a := range Actions {
+		if a == p.Action {
+			return p, nil
+		}
+	}
+	return Permission{}, fmt.Errorf("Scope %q has an unknown action", scope)
+}
+
+// Granted tells whether any of scopes grants permission
+func Granted(scopes []string, permission Permission) bool {
+	for _, scope := range scopes {
+		if scope == permission.String() || scope == string(permission.Resource)+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed tells whether any of roles grants permission
+func Allowed(roles []Role, permission Permission) bool {
+	for _, allowed := range Policy[permission.Resource][permission.Action] {
+		for _, role := range roles {
+			if role == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RolesFromClaims maps the roles, role and realm_access.roles token claims
+// to catalog roles, ignoring unknown values
+func RolesFromClaims(claims map[string]interface{}) []Role {
+	values := []interface{}{}
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		values = append(values, roles...)
+	} else if roles, ok := claims["roles"].(string); ok {
+		for _, r := range strings.Fields(strings.Replace(roles, ",", " ", -1)) {
+			values = append(values, r)
+		}
+	}
+	if role, ok := claims["role"]; ok {
+		values = append(values, role)
+	}
+	if realm, ok := claims["realm_access"].(map[string]interface{}); ok {
+		if roles, ok := realm["roles"].([]interface{}); ok {
+			values = append(values, roles...)
+		}
+	}
+
+	roles := []Role{}
+	seen := map[Role]bool{}
+	for _, v := range values {
+		s, _ := v.(string)
+		role := Role(strings.ToLower(s))
+		if (role == RoleAdmin || role == RoleEditor || role == RoleViewer) && !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
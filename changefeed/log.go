@@ -0,0 +1,162 @@
+package changefeed
+
+import (
+	"sync"
+	"video-catalog/events"
+)
+
+// Entry models an event of the feed. ID is the outbox sequence of the
+// event, increasing in commit order.
+type Entry struct {
+	ID    uint64
+	Event events.Event
+}
+
+// Log keeps the latest entries in memory and fans them out to
+// subscribers. A subscriber that doesn't keep up is dropped instead of
+// slowing down the others, it resumes from the log when it reconnects.
+type Log struct {
+	mu          sync.Mutex
+	capacity    int
+	entries     []Entry
+	floor       uint64
+	subscribers map[*Subscription]bool
+	closed      bool
+}
+
+// Subscription receives the entries appended after it was created
+type Subscription struct {
+	C <-chan Entry
+
+	ch      chan Entry
+	dropped bool
+}
+
+// Dropped tells whether the subscription was closed because its buffer
+// was full, rather than because the log was closed
+func (s *Subscription) Dropped() bool {
+	return s.dropped
+}
+
+// NewLog returns a log keeping up to capacity entries
+func NewLog(capacity int) *Log {
+	return &Log{capacity: capacity, subscribers: map[*Subscription]bool{}}
+}
+
+// Append adds entries to the log and sends them to the subscribers
+func (l *Log) Append(entries ...Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+
+	for _, e := range entries {
+		l.entries = append(l.entries, e)
+		for s := range l.subscribers {
+			select {
+			case s.ch <- e:
+			default:
+				s.dropped = true
+				l.unsubscribe(s)
+			}
+		}
+	}
+	if overflow := len(l.entries) - l.capacity; overflow > 0 {
+		l.floor = l.entries[overflow-1].ID
+		l.entries = append([]Entry{}, l.entries[overflow:]...)
+	}
+}
+
+// SetFloor records that the entries up to id are not in the log, so
+// resuming from an earlier id is refused
+func (l *Log) SetFloor(id uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if id > l.floor {
+		l.floor = id
+	}
+}
+
+// Since returns the entries after id. ok is false when entries after id
+// were already evicted, so the caller can't resume without a gap.
+func (l *Log) Since(id uint64) (entries []Entry, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.since(id)
+}
+
+func (l *Log) since(id uint64) ([]Entry, bool) {
+	if id < l.floor {
+		return nil, false
+	}
+	for i, e := range l.entries {
+		if e.ID > id {
+			return append([]Entry{}, l.entries[i:]...), true
+		}
+	}
+	return nil, true
+}
+
+// Last returns the id of the latest entry, 0 when the log is empty
+func (l *Log) Last() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == 0 {
+		return 0
+	}
+	return l.entries[len(l.entries)-1].ID
+}
+
+// Subscribe returns the entries after id and a subscription to the next
+// ones, atomically so none is missed. buffer bounds the entries the
+// subscriber may lag behind.
+func (l *Log) Subscribe(id uint64, buffer int) ([]Entry, *Subscription, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	backlog, ok := l.since(id)
+	return backlog, l.subscribe(buffer), ok
+}
+
+// Tail returns a subscription to the entries appended from now on
+func (l *Log) Tail(buffer int) *Subscription {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.subscribe(buffer)
+}
+
+func (l *Log) subscribe(buffer int) *Subscription {
+	ch := make(chan Entry, buffer)
+	s := &Subscription{C: ch, ch: ch}
+	if l.closed {
+		close(ch)
+		return s
+	}
+	l.subscribers[s] = true
+	return s
+}
+
+// Unsubscribe stops and closes s
+func (l *Log) Unsubscribe(s *Subscription) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.unsubscribe(s)
+}
+
+func (l *Log) unsubscribe(s *Subscription) {
+	if l.subscribers[s] {
+		delete(l.subscribers, s)
+		close(s.ch)
+	}
+}
+
+// Close closes every subscription, ending the streams reading them
+func (l *Log) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	for s := range l.subscribers {
+		l.unsubscribe(s)
+	}
+}
@@ -0,0 +1,89 @@
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+	"video-catalog/events"
+	"video-catalog/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// pollBatch bounds the messages read per poll
+const pollBatch = 500
+
+// Poller fills a log with the events written to the outbox, by this
+// replica or any other one
+type Poller struct {
+	db       *gorm.DB
+	log      *Log
+	interval time.Duration
+
+	tail events.OutboxTail
+}
+
+// NewPoller returns a poller of db feeding l
+func NewPoller(db *gorm.DB, l *Log, interval time.Duration) *Poller {
+	return &Poller{db: db, log: l, interval: interval}
+}
+
+// Run loads the latest events then polls for new ones until ctx is
+// cancelled
+func (p *Poller) Run(ctx context.Context) error {
+	if err := p.load(); err != nil {
+		log.Printf("Error loading change feed: %v", err)
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if err := p.Poll(); err != nil {
+			log.Printf("Error polling change feed: %v", err)
+		}
+	}
+}
+
+// load fills the log with the latest events, so clients can resume across
+// restarts
+func (p *Poller) load() error {
+	outbox := models.OutboxMessage{}
+	messages, err := outbox.FindLatest(p.db, p.log.capacity)
+	if err != nil || len(*messages) == 0 {
+		return err
+	}
+
+	p.log.SetFloor((*messages)[0].Sequence - 1)
+	p.append(*messages)
+	return nil
+}
+
+// Poll appends the events committed since the previous poll
+func (p *Poller) Poll() error {
+	ready, err := p.tail.Next(p.db, pollBatch)
+	if err != nil {
+		return err
+	}
+	p.append(ready)
+	return nil
+}
+
+func (p *Poller) append(messages []models.OutboxMessage) {
+	entries := []Entry{}
+	for _, m := range messages {
+		event := events.Event{}
+		if err := json.Unmarshal(m.Payload, &event); err != nil {
+			log.Printf("Skipping malformed outbox event %s: %v", m.EventID, err)
+			continue
+		}
+		entries = append(entries, Entry{ID: m.Sequence, Event: event})
+		p.tail.Last = m.Sequence
+	}
+	p.log.Append(entries...)
+}
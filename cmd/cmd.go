@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"video-catalog/config"
+	"video-catalog/database"
+
+	"github.com/jinzhu/gorm"
+	"github.com/joho/godotenv"
+)
+
+// command models a cli subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "runs the catalog api", runServe},
+	{"migrate", "applies pending schema migrations", runMigrate},
+	{"seed", "loads demo categories, genres, cast members and videos", runSeed},
+	{"import", "imports the catalog from NDJSON", runImport},
+	{"export", "exports the catalog as NDJSON", runExport},
+	{"reindex", "rebuilds the embedded search index", runReindex},
+}
+
+// Execute runs the subcommand named by the first argument and returns the
+// process exit code. Without arguments it runs serve.
+func Execute(args []string) int {
+	// loads values from .env into the system
+	if err := godotenv.Load(); err != nil {
+		log.Print("no .env file found")
+	}
+
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(args); err != nil {
+				if err != flag.ErrHelp {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				}
+				return 1
+			}
+			return 0
+		}
+	}
+
+	usage()
+	if name == "help" || name == "-h" || name == "--help" {
+		return 0
+	}
+	return 2
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' to list the command flags.\n", os.Args[0])
+}
+
+// newFlagSet returns a flag set with the shared configuration flags
+func newFlagSet(name string) (*flag.FlagSet, *config.Loader) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	return fs, config.NewLoader(fs)
+}
+
+// parse parses args and loads the configuration
+func parse(fs *flag.FlagSet, loader *config.Loader, args []string) (*config.Config, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return loader.Load()
+}
+
+// openDB connects to the database, applying pending migrations when the
+// configuration allows it
+func openDB(cfg *config.Config) (*gorm.DB, error) {
+	db, err := database.Open(cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.DB.AutoMigrate {
+		if _, err := database.Migrate(db); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
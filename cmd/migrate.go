@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"video-catalog/database"
+)
+
+func runMigrate(args []string) error {
+	fs, loader := newFlagSet("migrate")
+	status := fs.Bool("status", false, "only list pending migrations")
+	cfg, err := parse(fs, loader, args)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Open(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *status {
+		pending, err := database.Pending(db)
+		if err != nil {
+			return err
+		}
+		for _, id := range pending {
+			fmt.Printf("pending %s\n", id)
+		}
+		fmt.Printf("%d pending migration(s)\n", len(pending))
+		return nil
+	}
+
+	applied, err := database.Migrate(db)
+	for _, id := range applied {
+		fmt.Printf("applied %s\n", id)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d migration(s) applied\n", len(applied))
+	return nil
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+	"video-catalog/search"
+)
+
+// runReindex rebuilds the embedded search index from the database. A
+// running api loads the new index on its next flush.
+func runReindex(args []string) error {
+	fs, loader := newFlagSet("reindex")
+	cfg, err := parse(fs, loader, args)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	started := time.Now()
+	index := search.NewEmbedded(db, cfg.Search.IndexPath, cfg.Search.SyncInterval, cfg.Search.FlushInterval)
+	count, err := index.Rebuild()
+	if err != nil {
+		return err
+	}
+	if err := index.Save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%d document(s) indexed into %s in %s\n", count, cfg.Search.IndexPath, time.Since(started).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"video-catalog/controllers"
+	"video-catalog/models"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+var seedCategories = []models.Category{
+	{Name: "Filmes", Description: "Longas e curtas metragens"},
+	{Name: "Séries", Description: "Séries e minisséries"},
+	{Name: "Documentários", Description: "Documentários e reportagens"},
+	{Name: "Infantil", Description: "Conteúdo para crianças"},
+}
+
+var seedGenres = []models.Genre{
+	{Name: "Ação"},
+	{Name: "Comédia"},
+	{Name: "Drama"},
+	{Name: "Terror"},
+	{Name: "Animação"},
+}
+
+var seedCastMembers = []models.CastMember{
+	{Name: "Fernanda Montenegro", Type: models.TypeActor},
+	{Name: "Wagner Moura", Type: models.TypeActor},
+	{Name: "Alice Braga", Type: models.TypeActor},
+	{Name: "Fernando Meirelles", Type: models.TypeDirector},
+	{Name: "Walter Salles", Type: models.TypeDirector},
+}
+
+var seedVideos = []models.Video{
+	{
+		Title:        "Cidade de Deus",
+		Description:  "Dois garotos crescem em uma favela do Rio de Janeiro e seguem caminhos muito diferentes entre o crime e a fotografia.",
+		YearLaunched: 2002,
+		Rating:       "16",
+		Duration:     130,
+	},
+	{
+		Title:        "Central do Brasil",
+		Description:  "Uma ex-professora que escreve cartas para analfabetos atravessa o sertão ao lado de um menino em busca do pai.",
+		YearLaunched: 1998,
+		Rating:       "14",
+		Duration:     113,
+	},
+	{
+		Title:        "Tropa de Elite",
+		Description:  "O capitão de uma tropa de operações especiais da polícia precisa encontrar um substituto antes da visita do papa.",
+		YearLaunched: 2007,
+		Rating:       "18",
+		Duration:     115,
+	},
+	{
+		Title:        "Rio",
+		Description:  "Uma arara azul criada em Minnesota viaja até o Rio de Janeiro e vive uma grande aventura no carnaval carioca.",
+		YearLaunched: 2011,
+		Rating:       "L",
+		Duration:     96,
+	},
+}
+
+func runSeed(args []string) error {
+	fs, loader := newFlagSet("seed")
+	truncate := fs.Bool("truncate", false, "permanently delete existing catalog rows before seeding")
+	cfg, err := parse(fs, loader, args)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.Begin()
+	if *truncate {
+		if err := truncateCatalog(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	created, err := seed(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	fmt.Printf("%d record(s) created\n", created)
+	return nil
+}
+
+// seedActor is the audit actor of the records written by seed
+const seedActor = "seed"
+
+// truncateCatalog permanently deletes the catalog rows, recording each
+// deletion so the search index, webhooks and the change feed drop them too
+func truncateCatalog(tx *gorm.DB) error {
+	for _, entity := range []struct {
+		entityType string
+		model      interface{}
+	}{
+		{"video", &models.Video{}},
+		{"cast_member", &models.CastMember{}},
+		{"genre", &models.Genre{}},
+		{"category", &models.Category{}},
+	} {
+		rows := []struct {
+			ID   string
+			Data models.JSON
+		}{}
+		table := tx.NewScope(entity.model).TableName()
+		if err := tx.Raw(fmt.Sprintf("SELECT id, to_jsonb(t) AS data FROM %s t", table)).Scan(&rows).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(entity.model).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := controllers.RecordWrite(tx, seedActor, entity.entityType, row.ID, row.Data, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// seed creates the demo records that are missing, matching them by name
+func seed(tx *gorm.DB) (int, error) {
+	created := 0
+	isTrue := true
+	isFalse := false
+
+	for _, category := range seedCategories {
+		category.ID = uuid.NewV4().String()
+		category.IsActive = &isTrue
+		category.Prepare()
+		if err := category.Validate(); err != nil {
+			return created, fmt.Errorf("category %s: %v", category.Name, err)
+		}
+		ok, err := createMissing(tx, "category", &models.Category{}, "name = ?", category.Name, &category)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+
+	for _, genre := range seedGenres {
+		genre.ID = uuid.NewV4().String()
+		genre.IsActive = &isTrue
+		genre.Prepare()
+		if err := genre.Validate(); err != nil {
+			return created, fmt.Errorf("genre %s: %v", genre.Name, err)
+		}
+		ok, err := createMissing(tx, "genre", &models.Genre{}, "name = ?", genre.Name, &genre)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+
+	for _, castMember := range seedCastMembers {
+		castMember.ID = uuid.NewV4().String()
+		castMember.Prepare()
+		if err := castMember.Validate("create"); err != nil {
+			return created, fmt.Errorf("cast member %s: %v", castMember.Name, err)
+		}
+		ok, err := createMissing(tx, "cast_member", &models.CastMember{}, "name = ?", castMember.Name, &castMember)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+
+	for _, video := range seedVideos {
+		video.ID = uuid.NewV4().String()
+		video.Opened = &isFalse
+		video.Prepare()
+		if err := video.Validate("create"); err != nil {
+			return created, fmt.Errorf("video %s: %v", video.Title, err)
+		}
+		ok, err := createMissing(tx, "video", &models.Video{}, "title = ?", video.Title, &video)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+
+	return created, nil
+}
+
+// createMissing creates value unless a row of model matches the condition,
+// then gives it its slug and records its creation
+func createMissing(tx *gorm.DB, entityType string, model interface{}, query string, arg interface{}, value interface{}) (bool, error) {
+	count := 0
+	if err := tx.Model(model).Where(query, arg).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := tx.Create(value).Error; err != nil {
+		return false, err
+	}
+	id := fmt.Sprint(tx.NewScope(value).PrimaryKeyValue())
+	if _, err := models.RefreshSlug(tx, entityType, id); err != nil {
+		return false, err
+	}
+	if err := tx.Where("id = ?", id).Take(model).Error; err != nil {
+		return false, err
+	}
+	return true, controllers.RecordWrite(tx, seedActor, entityType, id, nil, model)
+}
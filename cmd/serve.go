@@ -0,0 +1,13 @@
+package cmd
+
+import "video-catalog/api"
+
+func runServe(args []string) error {
+	fs, loader := newFlagSet("serve")
+	cfg, err := parse(fs, loader, args)
+	if err != nil {
+		return err
+	}
+
+	return api.Run(cfg)
+}
@@ -0,0 +1,415 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"video-catalog/controllers"
+	"video-catalog/models"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// record models one NDJSON line of a catalog export
+type record struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// catalogTypes lists the exported entity types in dependency order
+var catalogTypes = []string{"category", "genre", "cast_member", "series", "season", "video"}
+
+func runExport(args []string) error {
+	fs, loader := newFlagSet("export")
+	out := fs.String("out", "-", "output file, - for stdout")
+	types := fs.String("types", strings.Join(catalogTypes, ","), "comma separated entity types to export")
+	withDeleted := fs.Bool("with-deleted", false, "include soft deleted records")
+	cfg, err := parse(fs, loader, args)
+	if err != nil {
+		return err
+	}
+
+	selected, err := parseTypes(*types)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if *withDeleted {
+		db = db.Unscoped()
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	buf := bufio.NewWriter(w)
+	defer buf.Flush()
+
+	count := 0
+	for _, t := range selected {
+		n, err := exportType(db, json.NewEncoder(buf), t)
+		if err != nil {
+			return fmt.Errorf("exporting %s: %v", t, err)
+		}
+		count += n
+	}
+
+	fmt.Fprintf(os.Stderr, "%d record(s) exported\n", count)
+	return nil
+}
+
+func exportType(db *gorm.DB, enc *json.Encoder, t string) (int, error) {
+	var rows []interface{}
+
+	switch t {
+	case "category":
+		list := []models.Category{}
+		if err := db.Order("created_at").Find(&list).Error; err != nil {
+			return 0, err
+		}
+		for i := range list {
+			rows = append(rows, list[i])
+		}
+	case "genre":
+		list := []models.Genre{}
+		if err := db.Order("created_at").Find(&list).Error; err != nil {
+			return 0, err
+		}
+		for i := range list {
+			rows = append(rows, list[i])
+		}
+	case "cast_member":
+		list := []models.CastMember{}
+		if err := db.Order("created_at").Find(&list).Error; err != nil {
+			return 0, err
+		}
+		for i := range list {
+			rows = append(rows, list[i])
+		}
+	case "series":
+		list := []models.Series{}
+		if err := db.Order("created_at").Find(&list).Error; err != nil {
+			return 0, err
+		}
+		for i := range list {
+			rows = append(rows, list[i])
+		}
+	case "season":
+		list := []models.Season{}
+		if err := db.Order("created_at").Find(&list).Error; err != nil {
+			return 0, err
+		}
+		for i := range list {
+			rows = append(rows, list[i])
+		}
+	case "video":
+		list := []models.Video{}
+		if err := db.Order("created_at").Find(&list).Error; err != nil {
+			return 0, err
+		}
+		if err := models.LoadVideoRelations(db, list); err != nil {
+			return 0, err
+		}
+		for i := range list {
+			rows = append(rows, list[i])
+		}
+	}
+
+	for _, row := range rows {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return 0, err
+		}
+		if err := enc.Encode(record{Type: t, Data: data}); err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
+
+func runImport(args []string) error {
+	fs, loader := newFlagSet("import")
+	in := fs.String("in", "-", "input file, - for stdin")
+	dryRun := fs.Bool("dry-run", false, "validate records without writing them")
+	cfg, err := parse(fs, loader, args)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// every record is written in a single transaction so a bad line leaves
+	// the catalog untouched
+	tx := db.Begin()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	line := 0
+	count := 0
+	categoryIDs := []string{}
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		rec := record{}
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		id, err := importRecord(tx, rec)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		if rec.Type == "category" {
+			categoryIDs = append(categoryIDs, id)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := validateCategoryTree(tx, categoryIDs); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if *dryRun {
+		tx.Rollback()
+		fmt.Fprintf(os.Stderr, "%d record(s) valid, nothing written\n", count)
+		return nil
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d record(s) imported\n", count)
+	return nil
+}
+
+// importRecord validates a record and inserts it, or updates the existing
+// row with the same id, recording the change. Records are stored as exported, without escaping
+// their strings again; slugs are kept when they still follow the name.
+func importRecord(tx *gorm.DB, rec record) (string, error) {
+	switch rec.Type {
+	case "category":
+		category := models.Category{}
+		if err := json.Unmarshal(rec.Data, &category); err != nil {
+			return "", err
+		}
+		if category.ID == "" {
+			category.ID = uuid.NewV4().String()
+		}
+		if err := category.Validate(); err != nil {
+			return "", err
+		}
+		return category.ID, saveRecord(tx, "category", category.ID, &category, &models.Category{}, &models.Category{})
+	case "genre":
+		genre := models.Genre{}
+		if err := json.Unmarshal(rec.Data, &genre); err != nil {
+			return "", err
+		}
+		if genre.ID == "" {
+			genre.ID = uuid.NewV4().String()
+		}
+		if err := genre.Validate(); err != nil {
+			return "", err
+		}
+		return genre.ID, saveRecord(tx, "genre", genre.ID, &genre, &models.Genre{}, &models.Genre{})
+	case "cast_member":
+		castMember := models.CastMember{}
+		if err := json.Unmarshal(rec.Data, &castMember); err != nil {
+			return "", err
+		}
+		if castMember.ID == "" {
+			castMember.ID = uuid.NewV4().String()
+		}
+		if err := castMember.Validate("create"); err != nil {
+			return "", err
+		}
+		return castMember.ID, saveRecord(tx, "cast_member", castMember.ID, &castMember, &models.CastMember{}, &models.CastMember{})
+	case "series":
+		series := models.Series{}
+		if err := json.Unmarshal(rec.Data, &series); err != nil {
+			return "", err
+		}
+		if series.ID == "" {
+			series.ID = uuid.NewV4().String()
+		}
+		if err := series.Validate(); err != nil {
+			return "", err
+		}
+		return series.ID, saveRecord(tx, "series", series.ID, &series, &models.Series{}, &models.Series{})
+	case "season":
+		season := models.Season{}
+		if err := json.Unmarshal(rec.Data, &season); err != nil {
+			return "", err
+		}
+		if season.ID == "" {
+			season.ID = uuid.NewV4().String()
+		}
+		if err := season.Validate(); err != nil {
+			return "", err
+		}
+		count := 0
+		if err := tx.Model(&models.Series{}).Where("id = ?", season.SeriesID).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return "", models.ErrSeriesNotFound
+		}
+		return season.ID, saveRecord(tx, "season", season.ID, &season, &models.Season{}, &models.Season{})
+	case "video":
+		video := models.Video{}
+		if err := json.Unmarshal(rec.Data, &video); err != nil {
+			return "", err
+		}
+		if video.ID == "" {
+			video.ID = uuid.NewV4().String()
+		}
+		if err := video.Validate("create"); err != nil {
+			return "", err
+		}
+		if err := video.ValidateRelations(tx); err != nil {
+			return "", err
+		}
+		if err := video.ValidateEpisode(tx); err != nil {
+			return "", err
+		}
+		return video.ID, saveRecord(tx, "video", video.ID, &video, &models.Video{}, &models.Video{})
+	}
+	return "", fmt.Errorf("unknown record type %q", rec.Type)
+}
+
+// importActor is the audit actor of the records written by import
+const importActor = "import"
+
+// saveRecord inserts or updates value, gives it its slug and records the
+// change, so the search index, webhooks and the change feed learn about it.
+// before and after receive the stored row around the write.
+func saveRecord(tx *gorm.DB, entityType, id string, value, before, after interface{}) error {
+	found, err := storedRecord(tx, id, before)
+	if err != nil {
+		return err
+	}
+	if err := tx.Save(value).Error; err != nil {
+		return err
+	}
+	if _, ok := models.SlugTables()[entityType]; ok {
+		if _, err := models.RefreshSlug(tx, entityType, id); err != nil {
+			return err
+		}
+	}
+	if video, ok := value.(*models.Video); ok {
+		if err := video.SyncRelations(tx); err != nil {
+			return err
+		}
+	}
+	if _, err := storedRecord(tx, id, after); err != nil {
+		return err
+	}
+
+	if !found {
+		before = nil
+	}
+	return controllers.RecordWrite(tx, importActor, entityType, id, before, after)
+}
+
+// storedRecord loads the row with id into model, deleted or not, with the
+// relations of a video. It tells whether the row exists.
+func storedRecord(tx *gorm.DB, id string, model interface{}) (bool, error) {
+	err := tx.Unscoped().Where("id = ?", id).Take(model).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if video, ok := model.(*models.Video); ok {
+		if err := video.LoadRelations(tx); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// validateCategoryTree checks the imported live categories fit in the tree
+// as their moves would: records may name a missing parent, build a cycle
+// or nest too deep. The tree stays locked until the import commits.
+func validateCategoryTree(tx *gorm.DB, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := models.LockCategoryTree(tx); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		category := models.Category{}
+		err := tx.Where("id = ?", id).Take(&category).Error
+		if gorm.IsRecordNotFoundError(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := category.ValidateParent(tx, category.ParentID); err != nil {
+			return fmt.Errorf("category %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func parseTypes(value string) ([]string, error) {
+	selected := []string{}
+	for _, t := range strings.Split(value, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		known := false
+		for _, c := range catalogTypes {
+			if c == t {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown type %q, use %s", t, strings.Join(catalogTypes, ","))
+		}
+		selected = append(selected, t)
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no type selected")
+	}
+	return selected, nil
+}
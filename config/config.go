@@ -0,0 +1,427 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"video-catalog/i18n"
+	"video-catalog/rating"
+)
+
+// Config models the application configuration
+type Config struct {
+	AppEnv  string
+	HTTP    HTTPConfig
+	DB      DBConfig
+	Storage StorageConfig
+	Auth    AuthConfig
+	Broker  BrokerConfig
+	Outbox  OutboxConfig
+	Encoder EncoderConfig
+	Webhook WebhookConfig
+	Changes ChangesConfig
+	Search  SearchConfig
+	I18n    I18nConfig
+	Ratings RatingsConfig
+}
+
+// HTTPConfig models http server settings
+type HTTPConfig struct {
+	Port            int
+	GinMode         string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+
+	WorkerShutdownTimeout time.Duration
+	HealthCheckTimeout    time.Duration
+}
+
+// DBConfig models database connection and pool settings
+type DBConfig struct {
+	Driver          string
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	Debug           bool
+	AutoMigrate     bool
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// StorageConfig models video file storage settings
+type StorageConfig struct {
+	Driver    string
+	LocalPath string
+	BaseURL   string
+}
+
+// AuthConfig models authentication settings
+type AuthConfig struct {
+	Secret   string
+	JWKSURL  string
+	JWKSFile string
+	Audience string
+	Issuer   string
+}
+
+// BrokerConfig models the message broker settings
+type BrokerConfig struct {
+	Driver         string
+	URL            string
+	Exchange       string
+	ConfirmTimeout time.Duration
+}
+
+// OutboxConfig models the outbox relay settings
+type OutboxConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxBackoff   time.Duration
+	Retention    time.Duration
+	ClaimTimeout time.Duration
+}
+
+// EncoderConfig models the consumer of encoder results. An empty queue
+// disables it.
+type EncoderConfig struct {
+	ResultsQueue string
+	MaxRetries   int
+	MaxBackoff   time.Duration
+}
+
+// WebhookConfig models the webhook delivery settings
+type WebhookConfig struct {
+	Timeout      time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	MaxBackoff   time.Duration
+	DisableAfter int
+}
+
+// ChangesConfig models the change feed settings
+type ChangesConfig struct {
+	LogSize      int
+	PollInterval time.Duration
+	Heartbeat    time.Duration
+	ClientBuffer int
+}
+
+// SearchConfig models the search backend settings. The embedded backend
+// keeps an inverted index in IndexPath, synced from the outbox. Searches
+// with fewer than SuggestBelow hits get spelling suggestions.
+type SearchConfig struct {
+	Backend            string
+	IndexPath          string
+	SyncInterval       time.Duration
+	FlushInterval      time.Duration
+	SuggestBelow       int
+	VocabularyInterval time.Duration
+}
+
+// I18nConfig models the locales of the catalog. Stored texts are in
+// DefaultLocale; Locales lists the translated ones, comma separated, and
+// Fallbacks the comma separated locale:fallback pairs used when a
+// translation is missing.
+type I18nConfig struct {
+	DefaultLocale string
+	Locales       string
+	Fallbacks     string
+}
+
+// RatingsConfig models the content rating settings. Equivalences maps the
+// levels of every rating system to ClassInd ones, as comma separated
+// system:level=classind pairs, for "max rating" filters across systems.
+type RatingsConfig struct {
+	Equivalences string
+}
+
+// ValidationError lists every invalid configuration value
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e, "; ")
+}
+
+// Default returns the configuration used when no source sets a value
+func Default() *Config {
+	return &Config{
+		AppEnv: "local",
+		HTTP: HTTPConfig{
+			Port:            8888,
+			GinMode:         "debug",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    60 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+
+			WorkerShutdownTimeout: 30 * time.Second,
+			HealthCheckTimeout:    2 * time.Second,
+		},
+		DB: DBConfig{
+			Driver:          "postgres",
+			Port:            5432,
+			SSLMode:         "disable",
+			AutoMigrate:     true,
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Storage: StorageConfig{
+			Driver:    "local",
+			LocalPath: "./data/storage",
+		},
+		Broker: BrokerConfig{
+			Driver:         "none",
+			Exchange:       "catalog.events",
+			ConfirmTimeout: 5 * time.Second,
+		},
+		Outbox: OutboxConfig{
+			PollInterval: time.Second,
+			BatchSize:    100,
+			MaxBackoff:   5 * time.Minute,
+			Retention:    24 * time.Hour,
+			ClaimTimeout: time.Minute,
+		},
+		Encoder: EncoderConfig{
+			MaxRetries: 5,
+			MaxBackoff: 5 * time.Minute,
+		},
+		Webhook: WebhookConfig{
+			Timeout:      10 * time.Second,
+			PollInterval: time.Second,
+			BatchSize:    50,
+			MaxAttempts:  10,
+			MaxBackoff:   time.Hour,
+			DisableAfter: 20,
+		},
+		Changes: ChangesConfig{
+			LogSize:      10000,
+			PollInterval: 500 * time.Millisecond,
+			Heartbeat:    15 * time.Second,
+			ClientBuffer: 256,
+		},
+		Search: SearchConfig{
+			Backend:            "embedded",
+			IndexPath:          "./data/search",
+			SyncInterval:       time.Second,
+			FlushInterval:      30 * time.Second,
+			SuggestBelow:       3,
+			VocabularyInterval: 10 * time.Second,
+		},
+		I18n: I18nConfig{
+			DefaultLocale: "pt-BR",
+			Locales:       "pt-PT,es",
+		},
+		Ratings: RatingsConfig{
+			Equivalences: rating.DefaultEquivalences,
+		},
+	}
+}
+
+// Addr returns the address the http server listens to
+func (h HTTPConfig) Addr() string {
+	return fmt.Sprintf(":%d", h.Port)
+}
+
+// DSN returns the database connection string
+func (d DBConfig) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s dbname=%s sslmode=%s password=%s", d.Host, d.Port, d.User, d.Name, d.SSLMode, d.Password)
+}
+
+// Validate checks every value and reports all problems at once
+func (c *Config) Validate() error {
+	var errs ValidationError
+
+	if !oneOf(c.HTTP.GinMode, "debug", "release", "test") {
+		errs = append(errs, fmt.Sprintf("GIN_MODE must be one of debug, release or test, got %q", c.HTTP.GinMode))
+	}
+	if c.HTTP.Port < 1 || c.HTTP.Port > 65535 {
+		errs = append(errs, fmt.Sprintf("API_PORT must be between 1 and 65535, got %d", c.HTTP.Port))
+	}
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.HTTP.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTP.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", c.HTTP.ShutdownTimeout},
+		{"WORKER_SHUTDOWN_TIMEOUT", c.HTTP.WorkerShutdownTimeout},
+		{"HEALTH_CHECK_TIMEOUT", c.HTTP.HealthCheckTimeout},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			errs = append(errs, fmt.Sprintf("%s must be greater than 0", timeout.name))
+		}
+	}
+
+	if c.DB.Driver != "postgres" {
+		errs = append(errs, fmt.Sprintf("DB_DRIVER must be postgres, got %q", c.DB.Driver))
+	}
+	if c.DB.Host == "" {
+		errs = append(errs, "DB_HOST is required")
+	}
+	if c.DB.Port < 1 || c.DB.Port > 65535 {
+		errs = append(errs, fmt.Sprintf("DB_PORT must be between 1 and 65535, got %d", c.DB.Port))
+	}
+	if c.DB.User == "" {
+		errs = append(errs, "DB_USER is required")
+	}
+	if c.DB.Name == "" {
+		errs = append(errs, "DB_NAME is required")
+	}
+	if c.DB.MaxOpenConns < 1 {
+		errs = append(errs, "DB_MAX_OPEN_CONNS must be greater than 0")
+	}
+	if c.DB.MaxIdleConns < 0 || c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		errs = append(errs, "DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS")
+	}
+	if c.DB.ConnMaxLifetime < 0 {
+		errs = append(errs, "DB_CONN_MAX_LIFETIME must not be negative")
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+		if c.Storage.LocalPath == "" {
+			errs = append(errs, "STORAGE_LOCAL_PATH is required when STORAGE_DRIVER is local")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("STORAGE_DRIVER must be local, got %q", c.Storage.Driver))
+	}
+
+	switch c.Broker.Driver {
+	case "none", "memory":
+	case "amqp":
+		if c.Broker.URL == "" {
+			errs = append(errs, "AMQP_URL is required when BROKER_DRIVER is amqp")
+		}
+		if c.Broker.Exchange == "" {
+			errs = append(errs, "AMQP_EXCHANGE is required when BROKER_DRIVER is amqp")
+		}
+		if c.Broker.ConfirmTimeout <= 0 {
+			errs = append(errs, "AMQP_CONFIRM_TIMEOUT must be greater than 0")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("BROKER_DRIVER must be one of none, memory or amqp, got %q", c.Broker.Driver))
+	}
+
+	if c.Outbox.PollInterval <= 0 {
+		errs = append(errs, "OUTBOX_POLL_INTERVAL must be greater than 0")
+	}
+	if c.Outbox.BatchSize < 1 {
+		errs = append(errs, "OUTBOX_BATCH_SIZE must be greater than 0")
+	}
+	if c.Outbox.MaxBackoff < time.Second {
+		errs = append(errs, "OUTBOX_MAX_BACKOFF must be at least 1s")
+	}
+	if c.Outbox.ClaimTimeout <= 0 {
+		errs = append(errs, "OUTBOX_CLAIM_TIMEOUT must be greater than 0")
+	}
+	if c.Outbox.Retention <= 0 {
+		errs = append(errs, "OUTBOX_RETENTION must be greater than 0")
+	}
+
+	if c.Encoder.ResultsQueue != "" {
+		if c.Broker.Driver != "amqp" {
+			errs = append(errs, "ENCODER_RESULTS_QUEUE requires BROKER_DRIVER amqp")
+		}
+		if c.Encoder.MaxRetries < 0 {
+			errs = append(errs, "ENCODER_MAX_RETRIES must not be negative")
+		}
+		if c.Encoder.MaxBackoff < time.Second {
+			errs = append(errs, "ENCODER_MAX_BACKOFF must be at least 1s")
+		}
+	}
+
+	if c.Webhook.Timeout <= 0 {
+		errs = append(errs, "WEBHOOK_TIMEOUT must be greater than 0")
+	}
+	if c.Webhook.PollInterval <= 0 {
+		errs = append(errs, "WEBHOOK_POLL_INTERVAL must be greater than 0")
+	}
+	if c.Webhook.BatchSize < 1 {
+		errs = append(errs, "WEBHOOK_BATCH_SIZE must be greater than 0")
+	}
+	if c.Webhook.MaxAttempts < 1 {
+		errs = append(errs, "WEBHOOK_MAX_ATTEMPTS must be greater than 0")
+	}
+	if c.Webhook.MaxBackoff < time.Second {
+		errs = append(errs, "WEBHOOK_MAX_BACKOFF must be at least 1s")
+	}
+	if c.Webhook.DisableAfter < 1 {
+		errs = append(errs, "WEBHOOK_DISABLE_AFTER must be greater than 0")
+	}
+
+	if c.Changes.LogSize < 1 {
+		errs = append(errs, "CHANGES_LOG_SIZE must be greater than 0")
+	}
+	if c.Changes.PollInterval <= 0 {
+		errs = append(errs, "CHANGES_POLL_INTERVAL must be greater than 0")
+	}
+	if c.Changes.Heartbeat <= 0 {
+		errs = append(errs, "CHANGES_HEARTBEAT must be greater than 0")
+	}
+	if c.Changes.ClientBuffer < 1 {
+		errs = append(errs, "CHANGES_CLIENT_BUFFER must be greater than 0")
+	}
+
+	switch c.Search.Backend {
+	case "postgres":
+	case "embedded":
+		if c.Search.IndexPath == "" {
+			errs = append(errs, "SEARCH_INDEX_PATH is required when SEARCH_BACKEND is embedded")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("SEARCH_BACKEND must be postgres or embedded, got %q", c.Search.Backend))
+	}
+	if c.Search.SyncInterval <= 0 {
+		errs = append(errs, "SEARCH_SYNC_INTERVAL must be greater than 0")
+	}
+	if c.Search.FlushInterval <= 0 {
+		errs = append(errs, "SEARCH_FLUSH_INTERVAL must be greater than 0")
+	}
+	if c.Search.SuggestBelow < 0 {
+		errs = append(errs, "SEARCH_SUGGEST_BELOW must not be negative")
+	}
+	if c.Search.VocabularyInterval <= 0 {
+		errs = append(errs, "SEARCH_VOCABULARY_INTERVAL must be greater than 0")
+	}
+
+	if _, err := i18n.Parse(c.I18n.DefaultLocale, c.I18n.Locales, c.I18n.Fallbacks); err != nil {
+		errs = append(errs, fmt.Sprintf("I18N_DEFAULT_LOCALE, I18N_LOCALES and I18N_FALLBACKS: %v", err))
+	}
+
+	if _, err := rating.ParseEquivalences(c.Ratings.Equivalences); err != nil {
+		errs = append(errs, fmt.Sprintf("RATING_EQUIVALENCES: %v", err))
+	}
+
+	if c.Auth.JWKSURL != "" && c.Auth.JWKSFile != "" {
+		errs = append(errs, "AUTH_JWKS_URL and AUTH_JWKS_FILE are mutually exclusive")
+	}
+	if c.Auth.Secret == "" && c.Auth.JWKSURL == "" && c.Auth.JWKSFile == "" {
+		errs = append(errs, "API_SECRET is required unless AUTH_JWKS_URL or AUTH_JWKS_FILE is set")
+	} else if c.Auth.Secret != "" && len(c.Auth.Secret) < 8 {
+		errs = append(errs, "API_SECRET must have at least 8 characters")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func oneOf(value string, options ...string) bool {
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// setting binds one configuration value to its env var, yaml key and flag
+type setting struct {
+	env   string
+	yaml  string
+	flag  string
+	usage string
+	field func(c *Config) interface{}
+}
+
+var settings = []setting{
+	{"APP_ENV", "app_env", "app-env", "application environment", func(c *Config) interface{} { return &c.AppEnv }},
+
+	{"API_PORT", "http.port", "port", "http port", func(c *Config) interface{} { return &c.HTTP.Port }},
+	{"GIN_MODE", "http.gin_mode", "gin-mode", "gin mode: debug, release or test", func(c *Config) interface{} { return &c.HTTP.GinMode }},
+	{"HTTP_READ_TIMEOUT", "http.read_timeout", "http-read-timeout", "http read timeout", func(c *Config) interface{} { return &c.HTTP.ReadTimeout }},
+	{"HTTP_WRITE_TIMEOUT", "http.write_timeout", "http-write-timeout", "http write timeout", func(c *Config) interface{} { return &c.HTTP.WriteTimeout }},
+	{"HTTP_IDLE_TIMEOUT", "http.idle_timeout", "http-idle-timeout", "http keep-alive idle timeout", func(c *Config) interface{} { return &c.HTTP.IdleTimeout }},
+	{"HTTP_SHUTDOWN_TIMEOUT", "http.shutdown_timeout", "http-shutdown-timeout", "time to drain in-flight requests on shutdown", func(c *Config) interface{} { return &c.HTTP.ShutdownTimeout }},
+	{"WORKER_SHUTDOWN_TIMEOUT", "http.worker_shutdown_timeout", "worker-shutdown-timeout", "time for workers to stop on shutdown, once requests are drained", func(c *Config) interface{} { return &c.HTTP.WorkerShutdownTimeout }},
+	{"HEALTH_CHECK_TIMEOUT", "http.health_check_timeout", "health-check-timeout", "timeout of each readiness check", func(c *Config) interface{} { return &c.HTTP.HealthCheckTimeout }},
+
+	{"DB_DRIVER", "db.driver", "db-driver", "database driver", func(c *Config) interface{} { return &c.DB.Driver }},
+	{"DB_HOST", "db.host", "db-host", "database host", func(c *Config) interface{} { return &c.DB.Host }},
+	{"DB_PORT", "db.port", "db-port", "database port", func(c *Config) interface{} { return &c.DB.Port }},
+	{"DB_USER", "db.user", "db-user", "database user", func(c *Config) interface{} { return &c.DB.User }},
+	{"DB_PASSWORD", "db.password", "db-password", "database password", func(c *Config) interface{} { return &c.DB.Password }},
+	{"DB_NAME", "db.name", "db-name", "database name", func(c *Config) interface{} { return &c.DB.Name }},
+	{"DB_SSLMODE", "db.sslmode", "db-sslmode", "database ssl mode", func(c *Config) interface{} { return &c.DB.SSLMode }},
+	{"DB_DEBUG", "db.debug", "db-debug", "log sql statements: on or off", func(c *Config) interface{} { return &c.DB.Debug }},
+	{"DB_AUTO_MIGRATE", "db.auto_migrate", "db-auto-migrate", "migrate the schema on startup", func(c *Config) interface{} { return &c.DB.AutoMigrate }},
+	{"DB_MAX_OPEN_CONNS", "db.max_open_conns", "db-max-open-conns", "maximum open database connections", func(c *Config) interface{} { return &c.DB.MaxOpenConns }},
+	{"DB_MAX_IDLE_CONNS", "db.max_idle_conns", "db-max-idle-conns", "maximum idle database connections", func(c *Config) interface{} { return &c.DB.MaxIdleConns }},
+	{"DB_CONN_MAX_LIFETIME", "db.conn_max_lifetime", "db-conn-max-lifetime", "maximum database connection lifetime", func(c *Config) interface{} { return &c.DB.ConnMaxLifetime }},
+
+	{"STORAGE_DRIVER", "storage.driver", "storage-driver", "video file storage driver", func(c *Config) interface{} { return &c.Storage.Driver }},
+	{"STORAGE_LOCAL_PATH", "storage.local_path", "storage-local-path", "directory used by the local storage driver", func(c *Config) interface{} { return &c.Storage.LocalPath }},
+	{"STORAGE_BASE_URL", "storage.base_url", "storage-base-url", "public base url of stored files", func(c *Config) interface{} { return &c.Storage.BaseURL }},
+
+	{"API_SECRET", "auth.secret", "auth-secret", "HS256 token secret", func(c *Config) interface{} { return &c.Auth.Secret }},
+	{"AUTH_JWKS_URL", "auth.jwks_url", "auth-jwks-url", "RS256 JWKS url", func(c *Config) interface{} { return &c.Auth.JWKSURL }},
+	{"AUTH_JWKS_FILE", "auth.jwks_file", "auth-jwks-file", "RS256 JWKS file", func(c *Config) interface{} { return &c.Auth.JWKSFile }},
+	{"AUTH_AUDIENCE", "auth.audience", "auth-audience", "expected token audience", func(c *Config) interface{} { return &c.Auth.Audience }},
+	{"AUTH_ISSUER", "auth.issuer", "auth-issuer", "expected token issuer", func(c *Config) interface{} { return &c.Auth.Issuer }},
+
+	{"BROKER_DRIVER", "broker.driver", "broker-driver", "domain event broker: none, memory or amqp", func(c *Config) interface{} { return &c.Broker.Driver }},
+	{"AMQP_URL", "broker.url", "amqp-url", "AMQP broker url", func(c *Config) interface{} { return &c.Broker.URL }},
+	{"AMQP_EXCHANGE", "broker.exchange", "amqp-exchange", "topic exchange domain events are published to", func(c *Config) interface{} { return &c.Broker.Exchange }},
+	{"AMQP_CONFIRM_TIMEOUT", "broker.confirm_timeout", "amqp-confirm-timeout", "time to wait for a publisher confirm", func(c *Config) interface{} { return &c.Broker.ConfirmTimeout }},
+	{"OUTBOX_POLL_INTERVAL", "outbox.poll_interval", "outbox-poll-interval", "how often the relay looks for unpublished events", func(c *Config) interface{} { return &c.Outbox.PollInterval }},
+	{"OUTBOX_BATCH_SIZE", "outbox.batch_size", "outbox-batch-size", "events relayed per poll", func(c *Config) interface{} { return &c.Outbox.BatchSize }},
+	{"OUTBOX_MAX_BACKOFF", "outbox.max_backoff", "outbox-max-backoff", "longest wait between retries of an event", func(c *Config) interface{} { return &c.Outbox.MaxBackoff }},
+	{"OUTBOX_CLAIM_TIMEOUT", "outbox.claim_timeout", "outbox-claim-timeout", "how long events being published are kept from other relays", func(c *Config) interface{} { return &c.Outbox.ClaimTimeout }},
+	{"OUTBOX_RETENTION", "outbox.retention", "outbox-retention", "how long published events are kept", func(c *Config) interface{} { return &c.Outbox.Retention }},
+	{"ENCODER_RESULTS_QUEUE", "encoder.results_queue", "encoder-results-queue", "queue of encoder results, empty to disable the consumer", func(c *Config) interface{} { return &c.Encoder.ResultsQueue }},
+	{"ENCODER_MAX_RETRIES", "encoder.max_retries", "encoder-max-retries", "retries of a failed encoder result before dead lettering it", func(c *Config) interface{} { return &c.Encoder.MaxRetries }},
+	{"ENCODER_MAX_BACKOFF", "encoder.max_backoff", "encoder-max-backoff", "longest wait between retries of an encoder result", func(c *Config) interface{} { return &c.Encoder.MaxBackoff }},
+
+	{"WEBHOOK_TIMEOUT", "webhook.timeout", "webhook-timeout", "timeout of a webhook request", func(c *Config) interface{} { return &c.Webhook.Timeout }},
+	{"WEBHOOK_POLL_INTERVAL", "webhook.poll_interval", "webhook-poll-interval", "how often due webhook deliveries are sent", func(c *Config) interface{} { return &c.Webhook.PollInterval }},
+	{"WEBHOOK_BATCH_SIZE", "webhook.batch_size", "webhook-batch-size", "webhook deliveries sent per poll", func(c *Config) interface{} { return &c.Webhook.BatchSize }},
+	{"WEBHOOK_MAX_ATTEMPTS", "webhook.max_attempts", "webhook-max-attempts", "attempts before a webhook delivery fails", func(c *Config) interface{} { return &c.Webhook.MaxAttempts }},
+	{"WEBHOOK_MAX_BACKOFF", "webhook.max_backoff", "webhook-max-backoff", "longest wait between webhook retries", func(c *Config) interface{} { return &c.Webhook.MaxBackoff }},
+	{"WEBHOOK_DISABLE_AFTER", "webhook.disable_after", "webhook-disable-after", "consecutive failures disabling a webhook", func(c *Config) interface{} { return &c.Webhook.DisableAfter }},
+
+	{"CHANGES_LOG_SIZE", "changes.log_size", "changes-log-size", "events kept in memory for change feed clients to resume", func(c *Config) interface{} { return &c.Changes.LogSize }},
+	{"CHANGES_POLL_INTERVAL", "changes.poll_interval", "changes-poll-interval", "how often the change feed reads new events", func(c *Config) interface{} { return &c.Changes.PollInterval }},
+	{"CHANGES_HEARTBEAT", "changes.heartbeat", "changes-heartbeat", "interval of change feed heartbeats", func(c *Config) interface{} { return &c.Changes.Heartbeat }},
+	{"CHANGES_CLIENT_BUFFER", "changes.client_buffer", "changes-client-buffer", "events a change feed client may lag behind before it is dropped", func(c *Config) interface{} { return &c.Changes.ClientBuffer }},
+	{"SEARCH_BACKEND", "search.backend", "search-backend", "search backend: embedded / postgres", func(c *Config) interface{} { return &c.Search.Backend }},
+	{"SEARCH_INDEX_PATH", "search.index_path", "search-index-path", "directory of the embedded search index", func(c *Config) interface{} { return &c.Search.IndexPath }},
+	{"SEARCH_SYNC_INTERVAL", "search.sync_interval", "search-sync-interval", "how often the embedded index reads catalog changes", func(c *Config) interface{} { return &c.Search.SyncInterval }},
+	{"SEARCH_FLUSH_INTERVAL", "search.flush_interval", "search-flush-interval", "how often the embedded index is saved to disk", func(c *Config) interface{} { return &c.Search.FlushInterval }},
+	{"SEARCH_SUGGEST_BELOW", "search.suggest_below", "search-suggest-below", "hits under which searches get spelling suggestions, 0 disables them", func(c *Config) interface{} { return &c.Search.SuggestBelow }},
+	{"SEARCH_VOCABULARY_INTERVAL", "search.vocabulary_interval", "search-vocabulary-interval", "how often the spelling vocabulary checks for catalog changes", func(c *Config) interface{} { return &c.Search.VocabularyInterval }},
+
+	{"I18N_DEFAULT_LOCALE", "i18n.default_locale", "i18n-default-locale", "locale of the stored texts", func(c *Config) interface{} { return &c.I18n.DefaultLocale }},
+	{"I18N_LOCALES", "i18n.locales", "i18n-locales", "comma separated locales texts are translated to", func(c *Config) interface{} { return &c.I18n.Locales }},
+	{"I18N_FALLBACKS", "i18n.fallbacks", "i18n-fallbacks", "comma separated locale:fallback pairs used for missing translations", func(c *Config) interface{} { return &c.I18n.Fallbacks }},
+
+	{"RATING_EQUIVALENCES", "ratings.equivalences", "rating-equivalences", "comma separated system:level=classind pairs used by max rating filters", func(c *Config) interface{} { return &c.Ratings.Equivalences }},
+}
+
+// Loader reads the configuration from env vars, an optional yaml file and
+// flags. Each source overrides the values set by the previous one.
+type Loader struct {
+	fs         *flag.FlagSet
+	configFile *string
+	flags      map[string]*string
+}
+
+// NewLoader registers the configuration flags on fs
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{
+		fs:         fs,
+		configFile: fs.String("config", "", "yaml configuration file (env CONFIG_FILE)"),
+		flags:      map[string]*string{},
+	}
+	for _, s := range settings {
+		l.flags[s.flag] = fs.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	return l
+}
+
+// Load builds and validates the configuration. It must be called after the
+// flag set is parsed.
+func (l *Loader) Load() (*Config, error) {
+	cfg := Default()
+	var errs ValidationError
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := assign(s.field(cfg), value); err != nil {
+				errs = append(errs, fmt.Sprintf("%s from env: %v", s.env, err))
+			}
+		}
+	}
+
+	path := *l.configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		values, err := readYAML(path)
+		if err != nil {
+			return nil, ValidationError{err.Error()}
+		}
+		for _, s := range settings {
+			if value, ok := values[s.yaml]; ok {
+				if err := assign(s.field(cfg), value); err != nil {
+					errs = append(errs, fmt.Sprintf("%s from %s: %v", s.yaml, path, err))
+				}
+			}
+		}
+	}
+
+	l.fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name {
+				if err := assign(s.field(cfg), *l.flags[s.flag]); err != nil {
+					errs = append(errs, fmt.Sprintf("-%s: %v", s.flag, err))
+				}
+			}
+		}
+	})
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Load parses args as configuration flags and loads the configuration
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	loader := NewLoader(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return loader.Load()
+}
+
+func assign(field interface{}, value string) error {
+	value = strings.TrimSpace(value)
+	switch f := field.(type) {
+	case *string:
+		*f = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*f = n
+	case *int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*f = n
+	case *bool:
+		switch strings.ToLower(value) {
+		case "on", "true", "yes", "1":
+			*f = true
+		case "off", "false", "no", "0", "":
+			*f = false
+		default:
+			return fmt.Errorf("%q is not a boolean, use on or off", value)
+		}
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 5m", value)
+		}
+		*f = d
+	}
+	return nil
+}
+
+func readYAML(path string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file: %v", err)
+	}
+
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("cannot parse config file %s: %v", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", raw, values)
+	return values, nil
+}
+
+func flatten(prefix string, raw map[string]interface{}, values map[string]string) {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[interface{}]interface{}:
+			nested := map[string]interface{}{}
+			for k, nv := range v {
+				nested[fmt.Sprint(k)] = nv
+			}
+			flatten(key, nested, values)
+		case nil:
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
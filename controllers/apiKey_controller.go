@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"video-catalog/auth"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// apiKeyWithSecret is answered once, when a key is created or rotated
+type apiKeyWithSecret struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+// grantable refuses scopes beyond the permissions of the caller, writing
+// the refusal and returning false
+func grantable(c *gin.Context, scopes []string) bool {
+	principal := currentPrincipal(c)
+	if principal == nil {
+		return true
+	}
+	for _, scope := range scopes {
+		permission, err := auth.ParsePermission(scope)
+		if err != nil || !principal.CanGrant(permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Scope " + scope + " exceeds the permissions of the caller",
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// CreateAPIKey handles api key creation requests
+func (server *Server) CreateAPIKey(c *gin.Context) {
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	apiKey := models.APIKey{}
+	if err = json.Unmarshal(body, &apiKey); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	apiKey.ID = uuid.NewV4().String()
+	apiKey.CreatedBy = c.GetString(subjectKey)
+	apiKey.LastUsedAt = nil
+	apiKey.RevokedAt = nil
+
+	apiKey.Prepare()
+	if err := apiKey.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	for _, scope := range apiKey.Scopes {
+		if _, err := auth.ParsePermission(scope); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err,
+			})
+			return
+		}
+	}
+	if !grantable(c, apiKey.Scopes) {
+		return
+	}
+
+	key, err := apiKey.Generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	apiKeyCreated, err := apiKey.Create(server.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiKeyWithSecret{APIKey: apiKeyCreated, Key: key})
+}
+
+// GetAPIKeys handles api keys list request
+func (server *Server) GetAPIKeys(c *gin.Context) {
+	apiKey := models.APIKey{}
+
+	apiKeys, err := apiKey.FindAll(server.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+// RevokeAPIKey handles api key revoke requests
+func (server *Server) RevokeAPIKey(c *gin.Context) {
+	apiKeyID := c.Param("id")
+	if _, err := uuid.FromString(apiKeyID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	apiKey := models.APIKey{ID: apiKeyID}
+
+	if err := apiKey.Revoke(server.DB); err != nil {
+		if err.Error() == "Api key not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// RotateAPIKey handles api key rotation requests. The key is revoked and
+// replaced by a new one with the same name, scopes and expiration.
+func (server *Server) RotateAPIKey(c *gin.Context) {
+	apiKeyID := c.Param("id")
+	if _, err := uuid.FromString(apiKeyID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	current := models.APIKey{ID: apiKeyID}
+	if err := current.FindByID(server.DB); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err,
+		})
+		return
+	}
+	if current.RevokedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Api key not found",
+		})
+		return
+	}
+
+	if !grantable(c, current.Scopes) {
+		return
+	}
+
+	replacement := models.APIKey{
+		ID:        uuid.NewV4().String(),
+		Name:      current.Name,
+		Scopes:    current.Scopes,
+		ExpiresAt: current.ExpiresAt,
+		CreatedBy: c.GetString(subjectKey),
+	}
+	key, err := replacement.Generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	tx := server.DB.Begin()
+	if err := current.Revoke(tx); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err,
+		})
+		return
+	}
+	if _, err := replacement.Create(tx); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiKeyWithSecret{APIKey: &replacement, Key: key})
+}
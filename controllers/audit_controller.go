@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maximum audit entries answered by page
+const maxAuditLimit = 1000
+
+// GetAuditEntries handles audit log requests, filtered by entity_type,
+// entity_id, actor and the from/to RFC 3339 time range
+func (server *Server) GetAuditEntries(c *gin.Context) {
+	filter := models.AuditFilter{
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		Actor:      c.Query("actor"),
+		Limit:      100,
+	}
+
+	for _, param := range []struct {
+		name  string
+		value **time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	} {
+		if raw := c.Query(param.name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error": param.name + " must be an RFC 3339 time",
+				})
+				return
+			}
+			*param.value = &t
+		}
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "limit must be between 1 and 1000",
+			})
+			return
+		}
+		filter.Limit = limit
+	}
+	if raw := c.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "offset must be a positive number",
+			})
+			return
+		}
+		filter.Offset = offset
+	}
+
+	entry := models.AuditEntry{}
+	entries, err := entry.FindAll(server.DB, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
@@ -1,49 +1,256 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
-	"video-catalog/models"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"video-catalog/auth"
+	"video-catalog/changefeed"
+	"video-catalog/config"
+	"video-catalog/database"
+	"video-catalog/events"
+	"video-catalog/i18n"
+	"video-catalog/rating"
+	"video-catalog/search"
+	"video-catalog/storage"
+	"video-catalog/webhooks"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/postgres" // postgres driver
 )
 
 // Server models server structure
 type Server struct {
-	DB     *gorm.DB
-	Router *gin.Engine
+	DB        *gorm.DB
+	Router    *gin.Engine
+	Config    *config.Config
+	Storage   storage.Storage
+	Auth      *auth.Authenticator
+	Events    events.Publisher
+	Outbox    *events.Relay
+	Changes   *changefeed.Log
+	Search    search.Backend
+	Suggester search.Suggester
+	Speller   *search.Speller
+	Locales   *i18n.Locales
+	Ratings   *rating.Equivalences
+
+	workers      []namedWorker
+	healthChecks []namedHealthCheck
+	shuttingDown int32
+}
+
+// Worker models a background process tied to the server lifecycle. Run
+// must return once ctx is cancelled.
+type Worker interface {
+	Run(ctx context.Context) error
 }
 
-//Initialize inits db connection and system routes
-func (server *Server) Initialize(Dbdriver, DbUser, DbPassword, DbPort, DbHost, DbName, DbDebug string) {
+type namedWorker struct {
+	name   string
+	worker Worker
+}
+
+// Initialize inits db connection and system routes
+func (server *Server) Initialize(cfg *config.Config) {
 	var err error
-	DBURL := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s", DbHost, DbPort, DbUser, DbName, DbPassword)
-	server.DB, err = gorm.Open(Dbdriver, DBURL)
+	server.Config = cfg
+	server.DB, err = database.Open(cfg.DB)
 	if err != nil {
-		fmt.Printf("Cannot connect to %s database", Dbdriver)
 		log.Fatal("Error connecting to database: ", err)
-	} else {
-		fmt.Printf("Connected to %s database\n", Dbdriver)
 	}
+	fmt.Printf("Connected to %s database\n", cfg.DB.Driver)
 
-	if DbDebug == "on" {
-		server.DB.LogMode(true)
+	//database migration
+	if cfg.DB.AutoMigrate {
+		applied, err := database.Migrate(server.DB)
+		if err != nil {
+			log.Fatal("Error migrating database: ", err)
+		}
+		for _, id := range applied {
+			fmt.Printf("Applied migration %s\n", id)
+		}
 	}
 
-	//database migration
-	server.DB.Debug().AutoMigrate(
-		&models.Category{},
-		&models.Genre{},
-	)
+	postgresSearch := search.NewPostgres(server.DB)
+	server.Search = postgresSearch
+	server.Suggester = postgresSearch
+	if cfg.Search.Backend == "embedded" {
+		embedded := search.NewEmbedded(server.DB, cfg.Search.IndexPath, cfg.Search.SyncInterval, cfg.Search.FlushInterval)
+		if err := embedded.Load(); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error loading search index, it will be rebuilt: %v", err)
+		}
+		server.Search = embedded
+		server.AddWorker("search-index", embedded)
+	}
+	if cfg.Search.SuggestBelow > 0 {
+		server.Speller = search.NewSpeller(server.DB, cfg.Search.VocabularyInterval)
+		server.AddWorker("search-vocabulary", server.Speller)
+	}
 
+	server.Locales, err = i18n.Parse(cfg.I18n.DefaultLocale, cfg.I18n.Locales, cfg.I18n.Fallbacks)
+	if err != nil {
+		log.Fatal("Error initializing locales: ", err)
+	}
+	server.Ratings, err = rating.ParseEquivalences(cfg.Ratings.Equivalences)
+	if err != nil {
+		log.Fatal("Error initializing rating equivalences: ", err)
+	}
+
+	server.Storage, err = storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatal("Error initializing storage: ", err)
+	}
+	server.Events, err = events.New(cfg.Broker)
+	if err != nil {
+		log.Fatal("Error connecting to message broker: ", err)
+	}
+	server.Outbox = events.NewRelay(server.DB, server.Events, cfg.Outbox)
+	server.AddWorker("outbox", server.Outbox)
+	server.AddWorker("webhooks", webhooks.NewSender(server.DB, cfg.Webhook))
+	server.Changes = changefeed.NewLog(cfg.Changes.LogSize)
+	server.AddWorker("changes", changefeed.NewPoller(server.DB, server.Changes, cfg.Changes.PollInterval))
+	if cfg.Encoder.ResultsQueue != "" {
+		server.AddWorker("encoder-results", events.NewAMQPConsumer(cfg.Broker.URL, cfg.Encoder.ResultsQueue,
+			server.HandleEncoderResult, cfg.Encoder.MaxRetries, cfg.Encoder.MaxBackoff))
+	}
+	server.registerDefaultHealthChecks()
+
+	server.Auth, err = auth.NewAuthenticator(cfg.Auth)
+	if err != nil {
+		log.Fatal("Error initializing authentication: ", err)
+	}
+
+	gin.SetMode(cfg.HTTP.GinMode)
 	server.Router = gin.Default()
 	server.initializeRoutes()
 }
 
-// Run starts server
-func (server *Server) Run(addr string) {
-	log.Fatal(http.ListenAndServe(addr, server.Router))
+// AddWorker registers a background worker started by Run
+func (server *Server) AddWorker(name string, worker Worker) {
+	server.workers = append(server.workers, namedWorker{name: name, worker: worker})
+}
+
+// Run starts the http server and the workers, and blocks until SIGINT or
+// SIGTERM is received. The server is then stopped as Serve does, before
+// the broker and db are closed.
+func (server *Server) Run() error {
+	listener, err := net.Listen("tcp", server.Config.HTTP.Addr())
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	go func() {
+		select {
+		case sig := <-quit:
+			log.Printf("Received %s, shutting down", sig)
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+
+	runErr := server.Serve(ctx, listener)
+
+	if err := server.Events.Close(); err != nil {
+		log.Printf("Error closing message broker: %v", err)
+	}
+	if err := server.DB.Close(); err != nil {
+		log.Printf("Error closing database: %v", err)
+	}
+	log.Print("Server stopped")
+	return runErr
+}
+
+// Serve serves http on listener and runs the workers until ctx is
+// cancelled or the http server fails. In-flight requests are then drained
+// within the shutdown timeout; workers are stopped afterwards and have a
+// deadline of their own, so a slow drain doesn't cut their shutdown short.
+func (server *Server) Serve(ctx context.Context, listener net.Listener) error {
+	httpCfg := server.Config.HTTP
+	httpServer := &http.Server{
+		Handler:           server.Router,
+		ReadTimeout:       httpCfg.ReadTimeout,
+		ReadHeaderTimeout: httpCfg.ReadTimeout,
+		WriteTimeout:      httpCfg.WriteTimeout,
+		IdleTimeout:       httpCfg.IdleTimeout,
+	}
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	workersDone := server.startWorkers(workersCtx)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			serveErr <- err
+		}
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case runErr = <-serveErr:
+		log.Printf("Http server failed: %v", runErr)
+	}
+
+	// readiness fails from now on so the load balancer stops routing here
+	atomic.StoreInt32(&server.shuttingDown, 1)
+
+	// change feed streams never end by themselves
+	server.Changes.Close()
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), httpCfg.ShutdownTimeout)
+	defer drainCancel()
+	if err := httpServer.Shutdown(drainCtx); err != nil {
+		log.Printf("Http server did not drain in time: %v", err)
+		httpServer.Close()
+	}
+
+	stopWorkers()
+	timer := time.NewTimer(httpCfg.WorkerShutdownTimeout)
+	defer timer.Stop()
+	select {
+	case <-workersDone:
+	case <-timer.C:
+		log.Printf("Workers did not stop within %s", httpCfg.WorkerShutdownTimeout)
+	}
+	return runErr
+}
+
+// startWorkers runs every worker in its own goroutine. The returned channel
+// is closed once all of them returned.
+func (server *Server) startWorkers(ctx context.Context) <-chan struct{} {
+	var wg sync.WaitGroup
+	for _, w := range server.workers {
+		wg.Add(1)
+		go func(w namedWorker) {
+			defer wg.Done()
+			started := time.Now()
+			if err := w.worker.Run(ctx); err != nil && err != context.Canceled {
+				log.Printf("Worker %s stopped with error after %s: %v", w.name, time.Since(started), err)
+				return
+			}
+			log.Printf("Worker %s stopped", w.name)
+		}(w)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
 }
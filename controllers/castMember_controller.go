@@ -7,6 +7,7 @@ import (
 	"video-catalog/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -37,7 +38,19 @@ func (server *Server) CreateCastMember(c *gin.Context) {
 		return
 	}
 
-	castMemberCreated, err := castMember.Create(server.DB)
+	var castMemberCreated *models.CastMember
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if castMemberCreated, err = castMember.Create(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "cast_member",
+			entityID:   castMemberCreated.ID,
+			action:     models.AuditActionCreate,
+			after:      castMemberCreated,
+		})
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Error processing request",
@@ -60,6 +73,14 @@ func (server *Server) GetCastMembers(c *gin.Context) {
 		return
 	}
 
+	entities := []models.Translatable{}
+	for i := range *castMembers {
+		entities = append(entities, &(*castMembers)[i])
+	}
+	if !server.localize(c, "cast_member", entities...) {
+		return
+	}
+
 	c.JSON(http.StatusOK, castMembers)
 }
 
@@ -82,6 +103,10 @@ func (server *Server) GetCastMember(c *gin.Context) {
 		return
 	}
 
+	if !server.localize(c, "cast_member", &castMember) {
+		return
+	}
+
 	c.JSON(http.StatusOK, castMember)
 }
 
@@ -114,7 +139,25 @@ func (server *Server) UpdateCastMember(c *gin.Context) {
 		return
 	}
 
-	updatedCastMember, err := newCastMember.Update(server.DB)
+	var updatedCastMember *models.CastMember
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.CastMember{ID: castMemberID}
+		if err := before.FindByID(tx); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		var err error
+		if updatedCastMember, err = newCastMember.Update(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "cast_member",
+			entityID:   castMemberID,
+			action:     models.AuditActionUpdate,
+			before:     before,
+			after:      updatedCastMember,
+		})
+	})
 	if err != nil {
 		if err.Error() == "Internal server error" {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -142,7 +185,23 @@ func (server *Server) DeleteCastMember(c *gin.Context) {
 	}
 	castMember := models.CastMember{ID: castMemberID}
 
-	if err := castMember.Delete(server.DB); err != nil {
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.CastMember{ID: castMemberID}
+		if err := before.FindByID(tx); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if err := castMember.Delete(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "cast_member",
+			entityID:   castMemberID,
+			action:     models.AuditActionDelete,
+			before:     before,
+		})
+	})
+	if err != nil {
 		if err.Error() == "CastMember not found" {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": err,
@@ -158,3 +217,48 @@ func (server *Server) DeleteCastMember(c *gin.Context) {
 
 	c.JSON(http.StatusNoContent, gin.H{})
 }
+
+// RestoreCastMember handles cast member restore requests
+func (server *Server) RestoreCastMember(c *gin.Context) {
+	castMemberID := c.Param("id")
+	if _, err := uuid.FromString(castMemberID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	castMember := models.CastMember{ID: castMemberID}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.CastMember{ID: castMemberID}
+		if err := before.FindByID(tx.Unscoped()); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if err := castMember.Restore(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "cast_member",
+			entityID:   castMemberID,
+			action:     models.AuditActionRestore,
+			before:     before,
+			after:      castMember,
+		})
+	})
+	if err != nil {
+		if err.Error() == "CastMember not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, castMember)
+}
@@ -8,6 +8,7 @@ import (
 	"video-catalog/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -38,8 +39,35 @@ func (server *Server) CreateCategory(c *gin.Context) {
 		return
 	}
 
-	categoryCreated, err := category.Create(server.DB)
+	var categoryCreated *models.Category
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		if category.ParentID != nil {
+			if err := models.LockCategoryTree(tx); err != nil {
+				return err
+			}
+			if err := category.ValidateParent(tx, category.ParentID); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if categoryCreated, err = category.Create(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "category",
+			entityID:   categoryCreated.ID,
+			action:     models.AuditActionCreate,
+			after:      categoryCreated,
+		})
+	})
 	if err != nil {
+		if models.IsCategoryTreeError(err) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Error processing request",
 		})
@@ -61,6 +89,14 @@ func (server *Server) GetCategories(c *gin.Context) {
 		return
 	}
 
+	entities := []models.Translatable{}
+	for i := range *categories {
+		entities = append(entities, &(*categories)[i])
+	}
+	if !server.localize(c, "category", entities...) {
+		return
+	}
+
 	c.JSON(http.StatusOK, categories)
 }
 
@@ -84,6 +120,10 @@ func (server *Server) GetCategory(c *gin.Context) {
 		return
 	}
 
+	if !server.localize(c, "category", &category) {
+		return
+	}
+
 	c.JSON(http.StatusOK, category)
 }
 
@@ -116,8 +156,40 @@ func (server *Server) UpdateCategory(c *gin.Context) {
 		return
 	}
 
-	updatedCategory, err := newCategory.Update(server.DB)
+	var updatedCategory *models.Category
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Category{ID: categoryID}
+		if err := before.FindByID(tx); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+		if newCategory.ParentID != nil {
+			if err := models.LockCategoryTree(tx); err != nil {
+				return err
+			}
+			if err := newCategory.ValidateParent(tx, newCategory.ParentID); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if updatedCategory, err = newCategory.Update(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "category",
+			entityID:   categoryID,
+			action:     models.AuditActionUpdate,
+			before:     before,
+			after:      updatedCategory,
+		})
+	})
 	if err != nil {
+		if models.IsCategoryTreeError(err) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 		if err.Error() == "Internal server error" {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": err,
@@ -144,7 +216,37 @@ func (server *Server) DeleteCategory(c *gin.Context) {
 	}
 	category := models.Category{ID: categoryID}
 
-	if err := category.Delete(server.DB); err != nil {
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Category{ID: categoryID}
+		if err := before.FindByID(tx); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+		if err := models.LockCategoryTree(tx); err != nil {
+			return err
+		}
+		if hasChildren, err := category.HasChildren(tx); err != nil {
+			return err
+		} else if hasChildren {
+			return models.ErrCategoryHasChildren
+		}
+
+		if err := category.Delete(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "category",
+			entityID:   categoryID,
+			action:     models.AuditActionDelete,
+			before:     before,
+		})
+	})
+	if err != nil {
+		if err == models.ErrCategoryHasChildren {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 		if err.Error() == "Category not found" {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": err,
@@ -160,3 +262,65 @@ func (server *Server) DeleteCategory(c *gin.Context) {
 
 	c.JSON(http.StatusNoContent, gin.H{})
 }
+
+// RestoreCategory handles category restore requests
+func (server *Server) RestoreCategory(c *gin.Context) {
+	categoryID := c.Param("id")
+	if _, err := uuid.FromString(categoryID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	category := models.Category{ID: categoryID}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Category{ID: categoryID}
+		if err := before.FindByID(tx.Unscoped()); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+		if before.ParentID != nil {
+			if err := models.LockCategoryTree(tx); err != nil {
+				return err
+			}
+			parent := models.Category{ID: *before.ParentID}
+			if err := parent.FindByID(tx); gorm.IsRecordNotFoundError(err) {
+				return models.ErrParentDeleted
+			} else if err != nil {
+				return err
+			}
+		}
+
+		if err := category.Restore(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "category",
+			entityID:   categoryID,
+			action:     models.AuditActionRestore,
+			before:     before,
+			after:      category,
+		})
+	})
+	if err != nil {
+		if err == models.ErrParentDeleted {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "Category not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// moveInput models a category move request, a null parent_id moves the
+// category to the root
+type moveInput struct {
+	ParentID *string `json:"parent_id"`
+}
+
+// GetCategoryTree handles requests for the whole category tree
+func (server *Server) GetCategoryTree(c *gin.Context) {
+	category := models.Category{}
+
+	tree, err := category.FindTree(server.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+	if !server.localize(c, "category", treeCategories(tree...)...) {
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
+
+// treeCategories returns the categories of the trees rooted at nodes
+func treeCategories(nodes ...*models.CategoryNode) []models.Translatable {
+	categories := []models.Translatable{}
+	for _, node := range nodes {
+		categories = append(categories, &node.Category)
+		categories = append(categories, treeCategories(node.Children...)...)
+	}
+	return categories
+}
+
+// GetCategorySubtree handles requests for a category with its
+// subcategories
+func (server *Server) GetCategorySubtree(c *gin.Context) {
+	categoryID := c.Param("id")
+	if _, err := uuid.FromString(categoryID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	category := models.Category{ID: categoryID}
+
+	subtree, err := category.FindSubtree(server.DB)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Category not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+	if !server.localize(c, "category", treeCategories(subtree)...) {
+		return
+	}
+
+	c.JSON(http.StatusOK, subtree)
+}
+
+// GetCategoryAncestors handles breadcrumb requests, listing the ancestors
+// of a category from the root
+func (server *Server) GetCategoryAncestors(c *gin.Context) {
+	categoryID := c.Param("id")
+	if _, err := uuid.FromString(categoryID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	category := models.Category{ID: categoryID}
+
+	if err := category.FindByID(server.DB); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Category not found",
+		})
+		return
+	}
+	ancestors, err := category.Ancestors(server.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+	entities := []models.Translatable{}
+	for i := range ancestors {
+		entities = append(entities, &ancestors[i])
+	}
+	if !server.localize(c, "category", entities...) {
+		return
+	}
+
+	c.JSON(http.StatusOK, ancestors)
+}
+
+// MoveCategory handles requests placing a category under another one, or
+// at the root
+func (server *Server) MoveCategory(c *gin.Context) {
+	categoryID := c.Param("id")
+	if _, err := uuid.FromString(categoryID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	input := moveInput{}
+	if err = json.Unmarshal(body, &input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	if err := models.ValidateParentID(input.ParentID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	category := models.Category{ID: categoryID}
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.LockCategoryTree(tx); err != nil {
+			return err
+		}
+		before := models.Category{ID: categoryID}
+		if err := before.FindByID(tx); err != nil {
+			return err
+		}
+
+		if err := category.Move(tx, input.ParentID); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "category",
+			entityID:   categoryID,
+			action:     models.AuditActionUpdate,
+			before:     before,
+			after:      category,
+			eventType:  "category.moved",
+		})
+	})
+	if err != nil {
+		if models.IsCategoryTreeError(err) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if gorm.IsRecordNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Category not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"video-catalog/events"
+	"video-catalog/models"
+	"video-catalog/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// change models a catalog mutation. eventType, aggregateType and
+// aggregateID override the published event, which defaults to
+// entityType.action over the entity.
+type change struct {
+	entityType    string
+	entityID      string
+	action        string
+	before        interface{}
+	after         interface{}
+	eventType     string
+	aggregateType string
+	aggregateID   string
+}
+
+// eventPayload is the data of catalog events, version 1
+type eventPayload struct {
+	Entity  models.JSON                   `json:"entity"`
+	Changes map[string]models.FieldChange `json:"changes"`
+}
+
+// recordChange records a mutation made by the request in the same
+// transaction as the mutation itself: an audit entry, a revision and the
+// domain event, written to the outbox and queued for the webhooks
+// subscribed to it
+func (server *Server) recordChange(c *gin.Context, tx *gorm.DB, ch change) error {
+	return server.recordChangeBy(tx, c.GetString(subjectKey), c.GetString(requestIDKey), ch)
+}
+
+// recordChangeBy records a mutation made outside of a request, such as by
+// a consumer, on behalf of actor
+func (server *Server) recordChangeBy(tx *gorm.DB, actor, requestID string, ch change) error {
+	before, err := models.ToJSON(ch.before)
+	if err != nil {
+		return err
+	}
+	after, err := models.ToJSON(ch.after)
+	if err != nil {
+		return err
+	}
+	diff, err := models.Diff(before, after)
+	if err != nil {
+		return err
+	}
+	diffJSON, err := models.ToJSON(diff)
+	if err != nil {
+		return err
+	}
+
+	entry := models.AuditEntry{
+		ID:         uuid.NewV4().String(),
+		Actor:      actor,
+		EntityType: ch.entityType,
+		EntityID:   ch.entityID,
+		Action:     ch.action,
+		RequestID:  requestID,
+		Before:     before,
+		After:      after,
+		Diff:       diffJSON,
+		CreatedAt:  time.Now(),
+	}
+	if _, err = entry.Create(tx); err != nil {
+		return err
+	}
+
+	if _, ok := revisionedEntities[ch.entityType]; ok && after != nil {
+		revision := models.Revision{
+			ID:         uuid.NewV4().String(),
+			EntityType: ch.entityType,
+			EntityID:   ch.entityID,
+			Action:     ch.action,
+			Actor:      entry.Actor,
+			Snapshot:   after,
+			CreatedAt:  entry.CreatedAt,
+		}
+		if _, err = revision.Create(tx); err != nil {
+			return err
+		}
+	}
+
+	event, err := newEvent(entry, ch, diff)
+	if err != nil {
+		return err
+	}
+	message, err := events.NewOutboxMessage(event)
+	if err != nil {
+		return err
+	}
+	if _, err = message.Create(tx); err != nil {
+		return err
+	}
+	return webhooks.Queue(tx, event)
+}
+
+// RecordWrite records a catalog row written outside of the api, such as by
+// the seed and import commands, on behalf of actor. A nil before records a
+// creation, a nil after a deletion; an update leaving the row unchanged
+// records nothing.
+func RecordWrite(tx *gorm.DB, actor, entityType, entityID string, before, after interface{}) error {
+	action := models.AuditActionUpdate
+	switch {
+	case before == nil:
+		action = models.AuditActionCreate
+	case after == nil:
+		action = models.AuditActionDelete
+	default:
+		beforeJSON, err := models.ToJSON(before)
+		if err != nil {
+			return err
+		}
+		afterJSON, err := models.ToJSON(after)
+		if err != nil {
+			return err
+		}
+		diff, err := models.Diff(beforeJSON, afterJSON)
+		if err != nil || len(diff) == 0 {
+			return err
+		}
+	}
+
+	server := Server{}
+	return server.recordChangeBy(tx, actor, "", change{
+		entityType: entityType,
+		entityID:   entityID,
+		action:     action,
+		before:     before,
+		after:      after,
+	})
+}
+
+func newEvent(entry models.AuditEntry, ch change, diff map[string]models.FieldChange) (events.Event, error) {
+	entity := entry.After
+	if entity == nil {
+		entity = entry.Before
+	}
+	data, err := json.Marshal(eventPayload{Entity: entity, Changes: diff})
+	if err != nil {
+		return events.Event{}, err
+	}
+
+	eventType := ch.eventType
+	if eventType == "" {
+		eventType = events.EventType(ch.entityType, ch.action)
+	}
+	aggregateType := ch.aggregateType
+	if aggregateType == "" {
+		aggregateType = strings.SplitN(eventType, ".", 2)[0]
+	}
+	aggregateID := ch.aggregateID
+	if aggregateID == "" {
+		aggregateID = ch.entityID
+	}
+
+	return events.Event{
+		ID:            entry.ID,
+		Type:          eventType,
+		Version:       events.PayloadVersion,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		OccurredAt:    entry.CreatedAt,
+		Actor:         entry.Actor,
+		RequestID:     entry.RequestID,
+		Data:          data,
+	}, nil
+}
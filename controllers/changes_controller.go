@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"video-catalog/changefeed"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LastEventIDHeader is sent by SSE clients when they reconnect
+const LastEventIDHeader = "Last-Event-ID"
+
+// StreamChanges streams catalog change events as Server-Sent Events. The
+// types query filters entity types, every readable one by default. Clients
+// resume with the Last-Event-ID header, or last_event_id query; a reset
+// event tells them the events since were evicted and they must reload.
+// Streams end before the http write timeout, clients reconnect by
+// themselves.
+func (server *Server) StreamChanges(c *gin.Context) {
+	readable, status, err := readableTypes(c, catalogTypes)
+	if err != nil {
+		abortWithProblem(c, status, err.Error())
+		return
+	}
+	types := map[string]bool{}
+	for _, t := range readable {
+		types[t] = true
+	}
+
+	lastEventID := c.GetHeader(LastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var backlog []changefeed.Entry
+	var subscription *changefeed.Subscription
+	resumed := true
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			abortWithProblem(c, http.StatusUnprocessableEntity, "Last-Event-ID must be an event id")
+			return
+		}
+		backlog, subscription, resumed = server.Changes.Subscribe(id, server.Config.Changes.ClientBuffer)
+	} else {
+		subscription = server.Changes.Tail(server.Config.Changes.ClientBuffer)
+	}
+	defer server.Changes.Unsubscribe(subscription)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	stream := &sseWriter{c: c}
+	stream.write("retry: 3000\n\n")
+	if !resumed {
+		stream.write("event: reset\ndata: {}\n\n")
+	}
+	for _, entry := range backlog {
+		stream.entry(entry, types)
+	}
+	stream.flush()
+
+	var end <-chan time.Time
+	if timeout := server.Config.HTTP.WriteTimeout; timeout > 0 {
+		timer := time.NewTimer(timeout * 9 / 10)
+		defer timer.Stop()
+		end = timer.C
+	}
+	heartbeat := time.NewTicker(server.Config.Changes.Heartbeat)
+	defer heartbeat.Stop()
+
+	for stream.err == nil {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-end:
+			return
+		case <-heartbeat.C:
+			stream.write(": heartbeat\n\n")
+		case entry, ok := <-subscription.C:
+			if !ok {
+				// dropped clients resume from their last event
+				if subscription.Dropped() {
+					stream.write("event: dropped\ndata: {}\n\n")
+					stream.flush()
+				}
+				return
+			}
+			stream.entry(entry, types)
+		}
+		stream.flush()
+	}
+}
+
+// sseWriter writes events, keeping the first error
+type sseWriter struct {
+	c   *gin.Context
+	err error
+}
+
+func (w *sseWriter) write(s string) {
+	if w.err == nil {
+		_, w.err = w.c.Writer.WriteString(s)
+	}
+}
+
+func (w *sseWriter) entry(entry changefeed.Entry, types map[string]bool) {
+	if !types[entry.Event.AggregateType] {
+		return
+	}
+	data, err := json.Marshal(entry.Event)
+	if err != nil {
+		return
+	}
+	w.write(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", entry.ID, entry.Event.Type, data))
+}
+
+func (w *sseWriter) flush() {
+	if w.err == nil {
+		w.c.Writer.Flush()
+	}
+}
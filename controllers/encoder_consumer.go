@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"context"
+	"video-catalog/events"
+	"video-catalog/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// encoderActor is the audit actor of the changes made by the encoder
+const encoderActor = "video-encoder"
+
+// HandleEncoderResult applies an encoding finished or failed message to
+// its video file. Redelivered messages leave the file untouched.
+func (server *Server) HandleEncoderResult(ctx context.Context, message events.Message) error {
+	result, err := events.ParseEncoderResult(message.Body)
+	if err != nil {
+		return err
+	}
+
+	return server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.VideoFile{ID: result.VideoFileID}
+		if err := before.FindByID(tx); err != nil {
+			if err == models.ErrVideoFileNotFound {
+				return events.Permanent(err)
+			}
+			return err
+		}
+
+		videoFile := before
+		eventType := "video.file_encoded"
+		switch result.Event {
+		case events.EncodingFinished:
+			videoFile.Status = models.VideoFileStatusEncoded
+			videoFile.OutputPath = result.OutputPath
+			videoFile.Error = ""
+		case events.EncodingFailed:
+			videoFile.Status = models.VideoFileStatusFailed
+			videoFile.Error = result.Error
+			eventType = "video.file_encoding_failed"
+		}
+		if videoFile.Status == before.Status && videoFile.OutputPath == before.OutputPath && videoFile.Error == before.Error {
+			return nil
+		}
+
+		updated, err := videoFile.UpdateStatus(tx)
+		if err != nil {
+			return err
+		}
+		return server.recordChangeBy(tx, encoderActor, message.ID, change{
+			entityType:  "video_file",
+			entityID:    updated.ID,
+			action:      models.AuditActionUpdate,
+			before:      before,
+			after:       updated,
+			eventType:   eventType,
+			aggregateID: updated.VideoID,
+		})
+	})
+}
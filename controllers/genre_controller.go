@@ -7,6 +7,7 @@ import (
 	"video-catalog/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -37,7 +38,19 @@ func (server *Server) CreateGenre(c *gin.Context) {
 		return
 	}
 
-	genreCreated, err := genre.Create(server.DB)
+	var genreCreated *models.Genre
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if genreCreated, err = genre.Create(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "genre",
+			entityID:   genreCreated.ID,
+			action:     models.AuditActionCreate,
+			after:      genreCreated,
+		})
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Error processing request",
@@ -60,6 +73,14 @@ func (server *Server) GetGenres(c *gin.Context) {
 		return
 	}
 
+	entities := []models.Translatable{}
+	for i := range *genres {
+		entities = append(entities, &(*genres)[i])
+	}
+	if !server.localize(c, "genre", entities...) {
+		return
+	}
+
 	c.JSON(http.StatusOK, genres)
 }
 
@@ -82,6 +103,10 @@ func (server *Server) GetGenre(c *gin.Context) {
 		return
 	}
 
+	if !server.localize(c, "genre", &genre) {
+		return
+	}
+
 	c.JSON(http.StatusOK, genre)
 }
 
@@ -114,7 +139,25 @@ func (server *Server) UpdateGenre(c *gin.Context) {
 		return
 	}
 
-	updatedGenre, err := newGenre.Update(server.DB)
+	var updatedGenre *models.Genre
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Genre{ID: genreID}
+		if err := before.FindByID(tx); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		var err error
+		if updatedGenre, err = newGenre.Update(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "genre",
+			entityID:   genreID,
+			action:     models.AuditActionUpdate,
+			before:     before,
+			after:      updatedGenre,
+		})
+	})
 	if err != nil {
 		if err.Error() == "Internal server error" {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -142,7 +185,23 @@ func (server *Server) DeleteGenre(c *gin.Context) {
 	}
 	genre := models.Genre{ID: genreID}
 
-	if err := genre.Delete(server.DB); err != nil {
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Genre{ID: genreID}
+		if err := before.FindByID(tx); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if err := genre.Delete(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "genre",
+			entityID:   genreID,
+			action:     models.AuditActionDelete,
+			before:     before,
+		})
+	})
+	if err != nil {
 		if err.Error() == "Genre not found" {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": err,
@@ -158,3 +217,48 @@ func (server *Server) DeleteGenre(c *gin.Context) {
 
 	c.JSON(http.StatusNoContent, gin.H{})
 }
+
+// RestoreGenre handles genre restore requests
+func (server *Server) RestoreGenre(c *gin.Context) {
+	genreID := c.Param("id")
+	if _, err := uuid.FromString(genreID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	genre := models.Genre{ID: genreID}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Genre{ID: genreID}
+		if err := before.FindByID(tx.Unscoped()); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if err := genre.Restore(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "genre",
+			entityID:   genreID,
+			action:     models.AuditActionRestore,
+			before:     before,
+			after:      genre,
+		})
+	})
+	if err != nil {
+		if err.Error() == "Genre not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, genre)
+}
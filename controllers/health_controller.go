@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+	"video-catalog/database"
+	"video-catalog/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck reports whether a dependency is usable
+type HealthCheck func(ctx context.Context) error
+
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+type componentStatus struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// AddHealthCheck registers a readiness check
+func (server *Server) AddHealthCheck(name string, check HealthCheck) {
+	server.healthChecks = append(server.healthChecks, namedHealthCheck{name: name, check: check})
+}
+
+// registerDefaultHealthChecks registers the checks of the dependencies
+// every server has
+func (server *Server) registerDefaultHealthChecks() {
+	server.AddHealthCheck("database", func(ctx context.Context) error {
+		return server.DB.DB().PingContext(ctx)
+	})
+	server.AddHealthCheck("storage", func(ctx context.Context) error {
+		return server.Storage.Ping(ctx)
+	})
+	if pinger, ok := server.Events.(events.Pinger); ok {
+		server.AddHealthCheck("broker", pinger.Ping)
+	}
+	server.AddHealthCheck("migrations", func(ctx context.Context) error {
+		pending, err := database.Pending(server.DB)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s)", len(pending))
+		}
+		return nil
+	})
+}
+
+// Liveness handles liveness probes, answering while the process can serve
+func (server *Server) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
+
+// Readiness handles readiness probes, checking every dependency
+func (server *Server) Readiness(c *gin.Context) {
+	if atomic.LoadInt32(&server.shuttingDown) == 1 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "shutting_down",
+		})
+		return
+	}
+
+	components := map[string]componentStatus{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, hc := range server.healthChecks {
+		wg.Add(1)
+		go func(hc namedHealthCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.Request.Context(), server.Config.HTTP.HealthCheckTimeout)
+			defer cancel()
+
+			started := time.Now()
+			status := componentStatus{Status: "ok"}
+			if err := runHealthCheck(ctx, hc.check); err != nil {
+				status.Status = "error"
+				status.Error = err.Error()
+			}
+			status.DurationMs = time.Since(started).Milliseconds()
+
+			mu.Lock()
+			components[hc.name] = status
+			mu.Unlock()
+		}(hc)
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	overall := "ok"
+	for _, status := range components {
+		if status.Status != "ok" {
+			code = http.StatusServiceUnavailable
+			overall = "unavailable"
+		}
+	}
+
+	c.JSON(code, gin.H{
+		"status":     overall,
+		"components": components,
+	})
+}
+
+// runHealthCheck returns when the check finishes or ctx expires, whichever
+// comes first, so a stuck dependency can't hold the probe
+func runHealthCheck(ctx context.Context, check HealthCheck) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
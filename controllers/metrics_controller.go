@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metric models one sample of the text exposition format
+type metric struct {
+	name  string
+	kind  string
+	help  string
+	value float64
+}
+
+// Metrics handles Prometheus scrapes
+func (server *Server) Metrics(c *gin.Context) {
+	outbox := models.OutboxMessage{}
+	stats, err := outbox.Stats(server.DB)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "error reading outbox: %v\n", err)
+		return
+	}
+
+	lag := 0.0
+	if stats.OldestCreatedAt != nil {
+		lag = time.Since(*stats.OldestCreatedAt).Seconds()
+	}
+	metrics := []metric{
+		{"catalog_outbox_pending_messages", "gauge", "Events written to the outbox and not published yet.", float64(stats.Pending)},
+		{"catalog_outbox_lag_seconds", "gauge", "Age of the oldest unpublished event.", lag},
+	}
+	if server.Outbox != nil {
+		relay := server.Outbox.Metrics()
+		metrics = append(metrics,
+			metric{"catalog_outbox_published_total", "counter", "Events published by this instance.", float64(relay.Published)},
+			metric{"catalog_outbox_failed_attempts_total", "counter", "Failed attempts to publish an event.", float64(relay.FailedAttempts)},
+			metric{"catalog_outbox_last_publish_lag_seconds", "gauge", "Time between the change and the publication of the last event.", relay.LastLag.Seconds()},
+		)
+	}
+
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"video-catalog/auth"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// context keys set by the authentication middleware
+const (
+	subjectKey   = "subject"
+	principalKey = "principal"
+	requestIDKey = "request_id"
+)
+
+// RequestIDHeader carries the id correlating a request across services
+const RequestIDHeader = "X-Request-ID"
+
+// APIKeyHeader carries the api key of service-to-service calls
+const APIKeyHeader = "X-API-Key"
+
+// Authenticate middleware requires a valid bearer token or api key and puts
+// its subject on the gin context
+func (server *Server) Authenticate(c *gin.Context) {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		server.authenticateAPIKey(c, key)
+		return
+	}
+
+	header := c.GetHeader("Authorization")
+	if header == "" {
+		unauthorized(c, "Missing bearer token")
+		return
+	}
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || strings.TrimSpace(parts[1]) == "" {
+		unauthorized(c, "Authorization header must be Bearer <token>")
+		return
+	}
+
+	principal, err := server.Auth.Authenticate(strings.TrimSpace(parts[1]))
+	if err != nil {
+		unauthorized(c, err.Error())
+		return
+	}
+
+	c.Set(subjectKey, principal.Subject)
+	c.Set(principalKey, principal)
+	c.Next()
+}
+
+func (server *Server) authenticateAPIKey(c *gin.Context, key string) {
+	apiKey := models.APIKey{}
+	if err := apiKey.FindByKey(server.DB, key); err != nil {
+		unauthorized(c, "Invalid api key")
+		return
+	}
+	if err := apiKey.Touch(server.DB, time.Now()); err != nil {
+		c.Error(err)
+	}
+
+	principal := &auth.Principal{
+		Subject:  "api_key:" + apiKey.ID,
+		APIKeyID: apiKey.ID,
+		Scopes:   apiKey.Scopes,
+	}
+	c.Set(subjectKey, principal.Subject)
+	c.Set(principalKey, principal)
+	c.Next()
+}
+
+// currentPrincipal returns the authenticated caller, if any
+func currentPrincipal(c *gin.Context) *auth.Principal {
+	if p, ok := c.Get(principalKey); ok {
+		return p.(*auth.Principal)
+	}
+	return nil
+}
+
+func unauthorized(c *gin.Context, detail string) {
+	c.Header("WWW-Authenticate", `Bearer realm="catalog"`)
+	abortWithProblem(c, http.StatusUnauthorized, detail)
+}
+
+// Authorize middleware requires the authenticated caller to hold the
+// permission to run action over resource
+func (server *Server) Authorize(resource auth.Resource, action auth.Action) gin.HandlerFunc {
+	permission := auth.Permission{Resource: resource, Action: action}
+	return func(c *gin.Context) {
+		principal := currentPrincipal(c)
+		if principal == nil {
+			unauthorized(c, "Missing bearer token")
+			return
+		}
+		if !principal.Can(permission) {
+			p := newProblem(c, http.StatusForbidden, "Missing permission "+permission.String())
+			p.MissingPermission = permission.String()
+			writeProblem(c, p)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequestID middleware reuses the caller request id or generates one, and
+// echoes it in the response
+func (server *Server) RequestID(c *gin.Context) {
+	requestID := c.GetHeader(RequestIDHeader)
+	if requestID == "" || len(requestID) > 64 {
+		requestID = uuid.NewV4().String()
+	}
+	c.Set(requestIDKey, requestID)
+	c.Header(RequestIDHeader, requestID)
+	c.Next()
+}
+
+// ResolveSlug middleware lets the id parameter of the routes of entityType
+// be a slug. The current slug of an entity stands for its id; a slug it had
+// before a rename redirects permanently to the same path with the current
+// one.
+func (server *Server) ResolveSlug(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.Param("id")
+		if _, err := uuid.FromString(value); err == nil {
+			c.Next()
+			return
+		}
+
+		id, current, err := models.ResolveSlug(server.DB, entityType, value)
+		if err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				abortWithProblem(c, http.StatusNotFound, "Entity not found")
+				return
+			}
+			abortWithProblem(c, http.StatusInternalServerError, "Error processing request")
+			return
+		}
+		if current != value {
+			location := *c.Request.URL
+			segments := strings.Split(location.Path, "/")
+			for n, segment := range strings.Split(c.FullPath(), "/") {
+				if segment == ":id" && n < len(segments) {
+					segments[n] = current
+				}
+			}
+			location.Path = strings.Join(segments, "/")
+			location.RawPath = ""
+			c.Redirect(http.StatusMovedPermanently, location.RequestURI())
+			c.Abort()
+			return
+		}
+
+		for n := range c.Params {
+			if c.Params[n].Key == "id" {
+				c.Params[n].Value = id
+			}
+		}
+		c.Next()
+	}
+}
+
+// catalogTypes lists the entity types served by the feeds and searches
+// spanning the whole catalog
+var catalogTypes = []auth.Resource{auth.ResourceCategory, auth.ResourceGenre, auth.ResourceCastMember, auth.ResourceVideo}
+
+// suggestTypes lists the entity types with name suggestions
+var suggestTypes = []auth.Resource{auth.ResourceCategory, auth.ResourceGenre, auth.ResourceCastMember}
+
+// readableTypes returns the entity types of the types query among allowed,
+// every readable one by default. Requesting an unknown type or one the
+// caller can't read is refused, with the status of the refusal.
+func readableTypes(c *gin.Context, allowed []auth.Resource) ([]string, int, error) {
+	principal := currentPrincipal(c)
+	requested := allowed
+	if value := c.Query("types"); value != "" {
+		requested = nil
+		for _, t := range strings.Split(value, ",") {
+			requested = append(requested, auth.Resource(strings.TrimSpace(t)))
+		}
+	}
+
+	types := []string{}
+	for _, t := range requested {
+		known := false
+		for _, f := range allowed {
+			known = known || f == t
+		}
+		if !known {
+			return nil, http.StatusUnprocessableEntity, fmt.Errorf("Unknown entity type %q", t)
+		}
+		permission := auth.Permission{Resource: t, Action: auth.ActionRead}
+		if principal != nil && !principal.Can(permission) {
+			if c.Query("types") != "" {
+				return nil, http.StatusForbidden, fmt.Errorf("Missing permission %s", permission)
+			}
+			continue
+		}
+		types = append(types, string(t))
+	}
+	return types, http.StatusOK, nil
+}
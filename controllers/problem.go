@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// problem models an RFC 7807 problem details body
+type problem struct {
+	Type              string `json:"type"`
+	Title             string `json:"title"`
+	Status            int    `json:"status"`
+	Detail            string `json:"detail,omitempty"`
+	Instance          string `json:"instance,omitempty"`
+	MissingPermission string `json:"missing_permission,omitempty"`
+}
+
+func newProblem(c *gin.Context, status int, detail string) problem {
+	return problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+	}
+}
+
+// abortWithProblem stops the request answering an application/problem+json
+// body
+func abortWithProblem(c *gin.Context, status int, detail string) {
+	writeProblem(c, newProblem(c, status, detail))
+}
+
+func writeProblem(c *gin.Context, p problem) {
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(p.Status, p)
+}
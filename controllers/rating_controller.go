@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"video-catalog/config"
+	"video-catalog/rating"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ratingLevel models a level of a rating system and its ClassInd
+// equivalent
+type ratingLevel struct {
+	Level      string `json:"level"`
+	Equivalent string `json:"equivalent"`
+}
+
+// ratingEquivalences returns the configured rating equivalences, the
+// default ones when the server was built without configuration
+func (server *Server) ratingEquivalences() *rating.Equivalences {
+	if server.Ratings != nil {
+		return server.Ratings
+	}
+	equivalences, _ := rating.ParseEquivalences(config.Default().Ratings.Equivalences)
+	return equivalences
+}
+
+// maxRating reads the max_rating query, a system:level pair or a ClassInd
+// level, into the limit it sets. It returns nil without the query.
+func (server *Server) maxRating(c *gin.Context) (*rating.Ceiling, error) {
+	value := strings.TrimSpace(c.Query("max_rating"))
+	if value == "" {
+		return nil, nil
+	}
+	system, level := rating.ClassInd.Name, value
+	if parts := strings.SplitN(value, ":", 2); len(parts) == 2 {
+		system, level = strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+	}
+	ceiling, err := server.ratingEquivalences().Ceiling(system, level)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid max_rating %q: %v", value, err)
+	}
+	return &ceiling, nil
+}
+
+// GetRatingSystems lists the rating systems with their levels, each with
+// its ClassInd equivalent
+func (server *Server) GetRatingSystems(c *gin.Context) {
+	equivalences := server.ratingEquivalences()
+	systems := []gin.H{}
+	for _, system := range rating.Systems {
+		levels := []ratingLevel{}
+		for _, level := range system.Levels {
+			equivalent, _ := equivalences.Equivalent(system.Name, level)
+			levels = append(levels, ratingLevel{level, equivalent})
+		}
+		systems = append(systems, gin.H{
+			"name":    system.Name,
+			"label":   system.Label,
+			"country": system.Country,
+			"levels":  levels,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": systems,
+	})
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// reorderInput models a reorder request, ids listed first in that order
+type reorderInput struct {
+	IDs []string `json:"ids"`
+}
+
+// orderedEntity gives the reorder handler access to an entity type with a
+// manual order
+type orderedEntity struct {
+	reorder func(tx *gorm.DB, ids []string) ([]string, error)
+	findAll func(tx *gorm.DB) ([]string, interface{}, error)
+}
+
+// orderChange models the order of the entities of a type, as recorded in
+// the audit log and events
+type orderChange struct {
+	IDs []string `json:"ids"`
+}
+
+var orderedEntities = map[string]orderedEntity{
+	"category": {
+		reorder: func(tx *gorm.DB, ids []string) ([]string, error) {
+			category := models.Category{}
+			return category.Reorder(tx, ids)
+		},
+		findAll: func(tx *gorm.DB) ([]string, interface{}, error) {
+			category := models.Category{}
+			categories, err := category.FindAll(tx)
+			if err != nil {
+				return nil, nil, err
+			}
+			ids := []string{}
+			for _, c := range *categories {
+				ids = append(ids, c.ID)
+			}
+			return ids, categories, nil
+		},
+	},
+	"genre": {
+		reorder: func(tx *gorm.DB, ids []string) ([]string, error) {
+			genre := models.Genre{}
+			return genre.Reorder(tx, ids)
+		},
+		findAll: func(tx *gorm.DB) ([]string, interface{}, error) {
+			genre := models.Genre{}
+			genres, err := genre.FindAll(tx)
+			if err != nil {
+				return nil, nil, err
+			}
+			ids := []string{}
+			for _, g := range *genres {
+				ids = append(ids, g.ID)
+			}
+			return ids, genres, nil
+		},
+	},
+}
+
+// ReorderEntities returns a handler applying a manual order to the
+// entities of a type at once. The listed ids come first in the given order,
+// the others follow in their previous order. The reordered list is
+// returned. A single change is recorded for the whole order, its event
+// aggregate id being the entity type.
+func (server *Server) ReorderEntities(entityType string) gin.HandlerFunc {
+	entity := orderedEntities[entityType]
+	return func(c *gin.Context) {
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err,
+			})
+			return
+		}
+		input := reorderInput{}
+		if err = json.Unmarshal(body, &input); err != nil || len(input.IDs) == 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "ids must list the ids to order",
+			})
+			return
+		}
+
+		var list interface{}
+		err = server.DB.Transaction(func(tx *gorm.DB) error {
+			before, _, err := entity.findAll(tx)
+			if err != nil {
+				return err
+			}
+			changed, err := entity.reorder(tx, input.IDs)
+			if err != nil {
+				return err
+			}
+			var after []string
+			if after, list, err = entity.findAll(tx); err != nil {
+				return err
+			}
+			if len(changed) == 0 {
+				return nil
+			}
+
+			return server.recordChange(c, tx, change{
+				entityType:    entityType + "_order",
+				entityID:      entityType,
+				action:        models.AuditActionUpdate,
+				before:        orderChange{IDs: before},
+				after:         orderChange{IDs: after},
+				eventType:     entityType + ".reordered",
+				aggregateType: entityType,
+				aggregateID:   entityType,
+			})
+		})
+		if err != nil {
+			if err == models.ErrInvalidOrder {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error processing request",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// revisionedEntities maps the entity types keeping revisions to a
+// constructor of their model
+var revisionedEntities = map[string]func() interface{}{
+	"video":    func() interface{} { return &models.Video{} },
+	"category": func() interface{} { return &models.Category{} },
+	"genre":    func() interface{} { return &models.Genre{} },
+}
+
+// GetRevisions returns a handler listing the revisions of an entity
+func (server *Server) GetRevisions(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entityID := c.Param("id")
+		if _, err := uuid.FromString(entityID); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		revision := models.Revision{EntityType: entityType, EntityID: entityID}
+		revisions, err := revision.FindAll(server.DB)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error processing request",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, revisions)
+	}
+}
+
+// DiffRevisions returns a handler comparing two revisions of an entity. The
+// from and to query parameters default to the two latest revisions.
+func (server *Server) DiffRevisions(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entityID := c.Param("id")
+		if _, err := uuid.FromString(entityID); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		to := models.Revision{EntityType: entityType, EntityID: entityID}
+		if to.Version, _ = strconv.Atoi(c.Query("to")); to.Version < 0 {
+			to.Version = 0
+		}
+		if err := to.FindByVersion(server.DB); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		from := models.Revision{EntityType: entityType, EntityID: entityID, Version: to.Version - 1}
+		if raw := c.Query("from"); raw != "" {
+			version, err := strconv.Atoi(raw)
+			if err != nil || version < 1 {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error": "from must be a revision version",
+				})
+				return
+			}
+			from.Version = version
+		}
+		if from.Version > 0 {
+			if err := from.FindByVersion(server.DB); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+		}
+
+		changes, err := models.Diff(from.Snapshot, to.Snapshot)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error processing request",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"from":    from.Version,
+			"to":      to.Version,
+			"changes": changes,
+		})
+	}
+}
+
+// RollbackRevision returns a handler restoring an entity to the state of
+// one of its revisions. The rollback is itself recorded as a new revision.
+func (server *Server) RollbackRevision(entityType string) gin.HandlerFunc {
+	newModel := revisionedEntities[entityType]
+	return func(c *gin.Context) {
+		entityID := c.Param("id")
+		if _, err := uuid.FromString(entityID); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		version, err := strconv.Atoi(c.Param("version"))
+		if err != nil || version < 1 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "version must be a revision version",
+			})
+			return
+		}
+
+		revision := models.Revision{EntityType: entityType, EntityID: entityID, Version: version}
+		if err := revision.FindByVersion(server.DB); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		current := newModel()
+		var invalid error
+		err = server.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("id = ?", entityID).Take(current).Error; err != nil {
+				return err
+			}
+			if video, ok := current.(*models.Video); ok {
+				if err := video.LoadRelations(tx); err != nil {
+					return err
+				}
+			}
+
+			restored, err := snapshotToModel(revision.Snapshot, current, newModel())
+			if err != nil {
+				return err
+			}
+			// rules may have changed since the revision was taken
+			if invalid = validateSnapshot(tx, restored); invalid != nil {
+				return invalid
+			}
+			if err := validatePlacement(tx, restored); err != nil {
+				return err
+			}
+			if err := tx.Save(restored).Error; err != nil {
+				return models.NumberConflict(err)
+			}
+			if _, err := models.RefreshSlug(tx, entityType, entityID); err != nil {
+				return err
+			}
+			if err := tx.Where("id = ?", entityID).Take(restored).Error; err != nil {
+				return err
+			}
+			if video, ok := restored.(*models.Video); ok {
+				if err := video.RestoreRelations(tx); err != nil {
+					return err
+				}
+				if err := video.LoadRelations(tx); err != nil {
+					return err
+				}
+			}
+
+			err = server.recordChange(c, tx, change{
+				entityType: entityType,
+				entityID:   entityID,
+				action:     models.AuditActionRollback,
+				before:     current,
+				after:      restored,
+			})
+			current = restored
+			return err
+		})
+		if err != nil {
+			if invalid != nil {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error": invalid,
+				})
+				return
+			}
+			if models.IsCategoryTreeError(err) {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			if models.IsEpisodeError(err) {
+				c.JSON(episodeStatus(err), gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			if gorm.IsRecordNotFoundError(err) {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": "Entity not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error processing request",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, current)
+	}
+}
+
+// snapshotToModel decodes snapshot into target, keeping the bookkeeping
+// timestamps, the slug and the position of the current entity. The slug
+// then follows the restored name like on any update, positions only change
+// through a reorder.
+func snapshotToModel(snapshot models.JSON, current, target interface{}) (interface{}, error) {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(snapshot, &fields); err != nil {
+		return nil, err
+	}
+
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	currentFields := map[string]interface{}{}
+	if err := json.Unmarshal(currentJSON, &currentFields); err != nil {
+		return nil, err
+	}
+	fields["created_at"] = currentFields["created_at"]
+	fields["slug"] = currentFields["slug"]
+	if position, ok := currentFields["position"]; ok {
+		fields["position"] = position
+	}
+	delete(fields, "updated_at")
+	delete(fields, "deleted_at")
+
+	restored, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(restored, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// validateSnapshot checks an entity restored from a snapshot as its
+// creation would be checked
+func validateSnapshot(db *gorm.DB, entity interface{}) error {
+	switch e := entity.(type) {
+	case *models.Video:
+		if err := e.Validate("create"); err != nil {
+			return err
+		}
+		return e.ValidateRelations(db)
+	case *models.Category:
+		return e.Validate()
+	case *models.Genre:
+		return e.Validate()
+	}
+	return nil
+}
+
+// validatePlacement checks where a restored entity goes as a move would: a
+// category must fit in the tree, which is locked until the rollback
+// commits, and a video must take a free number of a live season
+func validatePlacement(db *gorm.DB, entity interface{}) error {
+	switch e := entity.(type) {
+	case *models.Video:
+		return e.ValidateEpisode(db)
+	case *models.Category:
+		if err := models.LockCategoryTree(db); err != nil {
+			return err
+		}
+		return e.ValidateParent(db, e.ParentID)
+	}
+	return nil
+}
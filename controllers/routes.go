@@ -1,27 +1,125 @@
 package controllers
 
+import "video-catalog/auth"
+
 func (s *Server) initializeRoutes() {
-	v1 := s.Router.Group("/api/v1")
+	s.Router.Use(s.RequestID)
+
+	//Health routes
+	s.Router.GET("/healthz", s.Liveness)
+	s.Router.GET("/readyz", s.Readiness)
+
+	//Metrics routes
+	s.Router.GET("/metrics", s.Metrics)
+
+	v1 := s.Router.Group("/api/v1", s.Authenticate)
 	{
 		//Category routes
-		v1.POST("/category", s.CreateCategory)
-		v1.GET("/categories", s.GetCategories)
-		v1.GET("/category/:id", s.GetCategory)
-		v1.PUT("/category/:id", s.UpdateCategory)
-		v1.DELETE("/category/:id", s.DeleteCategory)
+		v1.POST("/category", s.Authorize(auth.ResourceCategory, auth.ActionCreate), s.CreateCategory)
+		v1.GET("/categories", s.Authorize(auth.ResourceCategory, auth.ActionRead), s.GetCategories)
+		v1.GET("/category/:id", s.Authorize(auth.ResourceCategory, auth.ActionRead), s.ResolveSlug("category"), s.GetCategory)
+		v1.PUT("/categories/order", s.Authorize(auth.ResourceCategory, auth.ActionUpdate), s.ReorderEntities("category"))
+		v1.GET("/categories/tree", s.Authorize(auth.ResourceCategory, auth.ActionRead), s.GetCategoryTree)
+		v1.GET("/category/:id/tree", s.Authorize(auth.ResourceCategory, auth.ActionRead), s.ResolveSlug("category"), s.GetCategorySubtree)
+		v1.GET("/category/:id/ancestors", s.Authorize(auth.ResourceCategory, auth.ActionRead), s.ResolveSlug("category"), s.GetCategoryAncestors)
+		v1.POST("/category/:id/move", s.Authorize(auth.ResourceCategory, auth.ActionUpdate), s.MoveCategory)
+		v1.PUT("/category/:id", s.Authorize(auth.ResourceCategory, auth.ActionUpdate), s.UpdateCategory)
+		v1.DELETE("/category/:id", s.Authorize(auth.ResourceCategory, auth.ActionDelete), s.DeleteCategory)
+		v1.POST("/category/:id/restore", s.Authorize(auth.ResourceCategory, auth.ActionRestore), s.RestoreCategory)
+		v1.GET("/category/:id/translations", s.Authorize(auth.ResourceCategory, auth.ActionRead), s.ResolveSlug("category"), s.GetTranslations("category"))
+		v1.PUT("/category/:id/translations/:locale", s.Authorize(auth.ResourceCategory, auth.ActionUpdate), s.SetTranslation("category"))
+		v1.DELETE("/category/:id/translations/:locale", s.Authorize(auth.ResourceCategory, auth.ActionUpdate), s.DeleteTranslation("category"))
+		v1.GET("/category/:id/revisions", s.Authorize(auth.ResourceCategory, auth.ActionRead), s.ResolveSlug("category"), s.GetRevisions("category"))
+		v1.GET("/category/:id/revisions/diff", s.Authorize(auth.ResourceCategory, auth.ActionRead), s.ResolveSlug("category"), s.DiffRevisions("category"))
+		v1.POST("/category/:id/revisions/:version/rollback", s.Authorize(auth.ResourceCategory, auth.ActionUpdate), s.RollbackRevision("category"))
 
 		//Genre routes
-		v1.POST("/genre", s.CreateGenre)
-		v1.GET("/genres", s.GetGenres)
-		v1.GET("/genre/:id", s.GetGenre)
-		v1.PUT("/genre/:id", s.UpdateGenre)
-		v1.DELETE("/genre/:id", s.DeleteGenre)
+		v1.POST("/genre", s.Authorize(auth.ResourceGenre, auth.ActionCreate), s.CreateGenre)
+		v1.GET("/genres", s.Authorize(auth.ResourceGenre, auth.ActionRead), s.GetGenres)
+		v1.PUT("/genres/order", s.Authorize(auth.ResourceGenre, auth.ActionUpdate), s.ReorderEntities("genre"))
+		v1.GET("/genre/:id", s.Authorize(auth.ResourceGenre, auth.ActionRead), s.ResolveSlug("genre"), s.GetGenre)
+		v1.PUT("/genre/:id", s.Authorize(auth.ResourceGenre, auth.ActionUpdate), s.UpdateGenre)
+		v1.DELETE("/genre/:id", s.Authorize(auth.ResourceGenre, auth.ActionDelete), s.DeleteGenre)
+		v1.POST("/genre/:id/restore", s.Authorize(auth.ResourceGenre, auth.ActionRestore), s.RestoreGenre)
+		v1.GET("/genre/:id/translations", s.Authorize(auth.ResourceGenre, auth.ActionRead), s.ResolveSlug("genre"), s.GetTranslations("genre"))
+		v1.PUT("/genre/:id/translations/:locale", s.Authorize(auth.ResourceGenre, auth.ActionUpdate), s.SetTranslation("genre"))
+		v1.DELETE("/genre/:id/translations/:locale", s.Authorize(auth.ResourceGenre, auth.ActionUpdate), s.DeleteTranslation("genre"))
+		v1.GET("/genre/:id/revisions", s.Authorize(auth.ResourceGenre, auth.ActionRead), s.ResolveSlug("genre"), s.GetRevisions("genre"))
+		v1.GET("/genre/:id/revisions/diff", s.Authorize(auth.ResourceGenre, auth.ActionRead), s.ResolveSlug("genre"), s.DiffRevisions("genre"))
+		v1.POST("/genre/:id/revisions/:version/rollback", s.Authorize(auth.ResourceGenre, auth.ActionUpdate), s.RollbackRevision("genre"))
 
 		//CastMember routes
-		v1.POST("/cast_member", s.CreateCastMember)
-		v1.GET("/cast_members", s.GetCastMembers)
-		v1.GET("/cast_member/:id", s.GetCastMember)
-		v1.PUT("/cast_member/:id", s.UpdateCastMember)
-		v1.DELETE("/cast_member/:id", s.DeleteCastMember)
+		v1.POST("/cast_member", s.Authorize(auth.ResourceCastMember, auth.ActionCreate), s.CreateCastMember)
+		v1.GET("/cast_members", s.Authorize(auth.ResourceCastMember, auth.ActionRead), s.GetCastMembers)
+		v1.GET("/cast_member/:id", s.Authorize(auth.ResourceCastMember, auth.ActionRead), s.ResolveSlug("cast_member"), s.GetCastMember)
+		v1.PUT("/cast_member/:id", s.Authorize(auth.ResourceCastMember, auth.ActionUpdate), s.UpdateCastMember)
+		v1.DELETE("/cast_member/:id", s.Authorize(auth.ResourceCastMember, auth.ActionDelete), s.DeleteCastMember)
+		v1.POST("/cast_member/:id/restore", s.Authorize(auth.ResourceCastMember, auth.ActionRestore), s.RestoreCastMember)
+		v1.GET("/cast_member/:id/translations", s.Authorize(auth.ResourceCastMember, auth.ActionRead), s.ResolveSlug("cast_member"), s.GetTranslations("cast_member"))
+		v1.PUT("/cast_member/:id/translations/:locale", s.Authorize(auth.ResourceCastMember, auth.ActionUpdate), s.SetTranslation("cast_member"))
+		v1.DELETE("/cast_member/:id/translations/:locale", s.Authorize(auth.ResourceCastMember, auth.ActionUpdate), s.DeleteTranslation("cast_member"))
+
+		//Video routes
+		v1.POST("/video", s.Authorize(auth.ResourceVideo, auth.ActionCreate), s.CreateVideo)
+		v1.GET("/videos", s.Authorize(auth.ResourceVideo, auth.ActionRead), s.GetVideos)
+		v1.GET("/ratings", s.Authorize(auth.ResourceVideo, auth.ActionRead), s.GetRatingSystems)
+		v1.GET("/video/:id", s.Authorize(auth.ResourceVideo, auth.ActionRead), s.ResolveSlug("video"), s.GetVideo)
+		v1.PUT("/video/:id", s.Authorize(auth.ResourceVideo, auth.ActionUpdate), s.UpdateVideo)
+		v1.DELETE("/video/:id", s.Authorize(auth.ResourceVideo, auth.ActionDelete), s.DeleteVideo)
+		v1.POST("/video/:id/restore", s.Authorize(auth.ResourceVideo, auth.ActionRestore), s.RestoreVideo)
+		v1.GET("/video/:id/translations", s.Authorize(auth.ResourceVideo, auth.ActionRead), s.ResolveSlug("video"), s.GetTranslations("video"))
+		v1.PUT("/video/:id/translations/:locale", s.Authorize(auth.ResourceVideo, auth.ActionUpdate), s.SetTranslation("video"))
+		v1.DELETE("/video/:id/translations/:locale", s.Authorize(auth.ResourceVideo, auth.ActionUpdate), s.DeleteTranslation("video"))
+		v1.GET("/video/:id/revisions", s.Authorize(auth.ResourceVideo, auth.ActionRead), s.ResolveSlug("video"), s.GetRevisions("video"))
+		v1.GET("/video/:id/revisions/diff", s.Authorize(auth.ResourceVideo, auth.ActionRead), s.ResolveSlug("video"), s.DiffRevisions("video"))
+		v1.POST("/video/:id/revisions/:version/rollback", s.Authorize(auth.ResourceVideo, auth.ActionUpdate), s.RollbackRevision("video"))
+		v1.POST("/video/:id/files", s.Authorize(auth.ResourceVideo, auth.ActionUpdate), s.RegisterVideoFile)
+		v1.GET("/video/:id/next", s.Authorize(auth.ResourceVideo, auth.ActionRead), s.ResolveSlug("video"), s.GetNextEpisode)
+		v1.GET("/video/:id/previous", s.Authorize(auth.ResourceVideo, auth.ActionRead), s.ResolveSlug("video"), s.GetPreviousEpisode)
+
+		//Series routes
+		v1.POST("/series", s.Authorize(auth.ResourceSeries, auth.ActionCreate), s.CreateSeries)
+		v1.GET("/series", s.Authorize(auth.ResourceSeries, auth.ActionRead), s.GetAllSeries)
+		v1.GET("/series/:id", s.Authorize(auth.ResourceSeries, auth.ActionRead), s.GetSeries)
+		v1.PUT("/series/:id", s.Authorize(auth.ResourceSeries, auth.ActionUpdate), s.UpdateSeries)
+		v1.DELETE("/series/:id", s.Authorize(auth.ResourceSeries, auth.ActionDelete), s.DeleteSeries)
+		v1.POST("/series/:id/restore", s.Authorize(auth.ResourceSeries, auth.ActionRestore), s.RestoreSeries)
+		v1.POST("/series/:id/seasons", s.Authorize(auth.ResourceSeries, auth.ActionUpdate), s.CreateSeason)
+		v1.GET("/series/:id/seasons", s.Authorize(auth.ResourceSeries, auth.ActionRead), s.GetSeasons)
+		v1.GET("/series/:id/seasons/:number", s.Authorize(auth.ResourceSeries, auth.ActionRead), s.GetSeason)
+		v1.PUT("/series/:id/seasons/:number", s.Authorize(auth.ResourceSeries, auth.ActionUpdate), s.UpdateSeason)
+		v1.DELETE("/series/:id/seasons/:number", s.Authorize(auth.ResourceSeries, auth.ActionUpdate), s.DeleteSeason)
+		v1.POST("/series/:id/seasons/:number/episodes", s.Authorize(auth.ResourceSeries, auth.ActionUpdate), s.Authorize(auth.ResourceVideo, auth.ActionCreate), s.CreateEpisode)
+		v1.GET("/series/:id/seasons/:number/episodes", s.Authorize(auth.ResourceSeries, auth.ActionRead), s.Authorize(auth.ResourceVideo, auth.ActionRead), s.GetEpisodes)
+		v1.GET("/series/:id/seasons/:number/episodes/:episode", s.Authorize(auth.ResourceSeries, auth.ActionRead), s.Authorize(auth.ResourceVideo, auth.ActionRead), s.GetEpisode)
+		v1.DELETE("/series/:id/seasons/:number/episodes/:episode", s.Authorize(auth.ResourceSeries, auth.ActionUpdate), s.Authorize(auth.ResourceVideo, auth.ActionUpdate), s.RemoveEpisode)
+
+		//ApiKey routes
+		v1.POST("/api_key", s.Authorize(auth.ResourceAPIKey, auth.ActionCreate), s.CreateAPIKey)
+		v1.GET("/api_keys", s.Authorize(auth.ResourceAPIKey, auth.ActionRead), s.GetAPIKeys)
+		v1.DELETE("/api_key/:id", s.Authorize(auth.ResourceAPIKey, auth.ActionDelete), s.RevokeAPIKey)
+		v1.POST("/api_key/:id/rotate", s.Authorize(auth.ResourceAPIKey, auth.ActionUpdate), s.RotateAPIKey)
+
+		//Webhook routes
+		v1.POST("/webhook", s.Authorize(auth.ResourceWebhook, auth.ActionCreate), s.CreateWebhook)
+		v1.GET("/webhooks", s.Authorize(auth.ResourceWebhook, auth.ActionRead), s.GetWebhooks)
+		v1.GET("/webhook/:id", s.Authorize(auth.ResourceWebhook, auth.ActionRead), s.GetWebhook)
+		v1.PUT("/webhook/:id", s.Authorize(auth.ResourceWebhook, auth.ActionUpdate), s.UpdateWebhook)
+		v1.DELETE("/webhook/:id", s.Authorize(auth.ResourceWebhook, auth.ActionDelete), s.DeleteWebhook)
+		v1.GET("/webhook/:id/deliveries", s.Authorize(auth.ResourceWebhook, auth.ActionRead), s.GetWebhookDeliveries)
+		v1.POST("/webhook/:id/deliveries/:delivery_id/redeliver", s.Authorize(auth.ResourceWebhook, auth.ActionUpdate), s.RedeliverWebhook)
+
+		//Search routes
+		v1.GET("/search", s.SearchCatalog)
+		v1.GET("/suggest", s.Suggest)
+
+		//Translation routes
+		v1.GET("/translations/missing", s.GetMissingTranslations)
+
+		//Change feed routes
+		v1.GET("/changes", s.StreamChanges)
+
+		//Audit routes
+		v1.GET("/audit", s.Authorize(auth.ResourceAudit, auth.ActionRead), s.GetAuditEntries)
 	}
 }
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"video-catalog/models"
+	"video-catalog/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSearchQuery bounds the length of search queries, in characters
+const maxSearchQuery = 200
+
+// SearchCatalog handles full-text searches over videos, categories, genres
+// and cast members. Hits of every type are ranked together. Video filters
+// narrow the search to videos, the types searched are returned.
+func (server *Server) SearchCatalog(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" || utf8.RuneCountInString(text) > maxSearchQuery {
+		abortWithProblem(c, http.StatusUnprocessableEntity, "q must have between 1 and 200 characters")
+		return
+	}
+	types, status, err := readableTypes(c, catalogTypes)
+	if err != nil {
+		abortWithProblem(c, status, err.Error())
+		return
+	}
+	limit, offset, ok := pagination(c, 20, 100)
+	if !ok {
+		return
+	}
+	filter, err := server.videoFilter(c)
+	if err != nil {
+		abortWithProblem(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if len(types) == 0 {
+		c.JSON(http.StatusOK, search.Result{Types: []string{}, Hits: []search.Hit{}})
+		return
+	}
+
+	query := search.Query{
+		Text:   text,
+		Types:  types,
+		Limit:  limit,
+		Offset: offset,
+		Filter: filter,
+		Facets: c.Query("facets") == "true",
+	}
+	result, err := server.Search.Search(c.Request.Context(), query)
+	if err == nil && server.Speller != nil && result.Total < server.Config.Search.SuggestBelow {
+		result.DidYouMean, err = server.didYouMean(c, query)
+	}
+	if err != nil {
+		if err == search.ErrEmptyQuery {
+			abortWithProblem(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		log.Printf("Error searching %q: %v", text, err)
+		abortWithProblem(c, http.StatusInternalServerError, "Error processing request")
+		return
+	}
+	if !server.localizeHits(c, &result) {
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// localizeHits translates the titles of the hits and the facet labels of a
+// result. The highlights of a translated title, taken from the stored one,
+// are dropped.
+func (server *Server) localizeHits(c *gin.Context, result *search.Result) bool {
+	names := facetNames(result.Facets)
+	titles := make([]string, len(result.Hits))
+	for i := range result.Hits {
+		hit := &result.Hits[i]
+		titles[i] = hit.Title
+		names[hit.Type] = append(names[hit.Type], models.NameOf(hit.Type, hit.ID, &hit.Title))
+	}
+	if !server.localizeNames(c, names) {
+		return false
+	}
+	for i := range result.Hits {
+		hit := &result.Hits[i]
+		if hit.Title != titles[i] {
+			delete(hit.Highlights, "title")
+			delete(hit.Highlights, "name")
+		}
+	}
+	return true
+}
+
+// maxSpellings bounds the spelling suggestions of a search
+const maxSpellings = 3
+
+// didYouMean returns the spellings of the query text that find something
+func (server *Server) didYouMean(c *gin.Context, query search.Query) ([]string, error) {
+	spellings := []string{}
+	for _, spelling := range server.Speller.Suggest(query.Text, maxSpellings) {
+		probe := query
+		probe.Text, probe.Limit, probe.Offset, probe.Facets = spelling, 1, 0, false
+		result, err := server.Search.Search(c.Request.Context(), probe)
+		if err != nil {
+			return nil, err
+		}
+		if result.Total > 0 {
+			spellings = append(spellings, spelling)
+		}
+	}
+	return spellings, nil
+}
+
+// pagination reads the limit and offset queries, answering the request
+// when they are invalid
+func pagination(c *gin.Context, defaultLimit, maxLimit int) (int, int, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 || limit > maxLimit {
+		abortWithProblem(c, http.StatusUnprocessableEntity, "limit must be between 1 and "+strconv.Itoa(maxLimit))
+		return 0, 0, false
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		abortWithProblem(c, http.StatusUnprocessableEntity, "offset must be a positive integer")
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
@@ -0,0 +1,430 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// seasonFromPath finds the season addressed by the id and number
+// parameters, writing the refusal when there is none
+func (server *Server) seasonFromPath(c *gin.Context) (*models.SeasonSummary, bool) {
+	seriesID := c.Param("id")
+	if _, err := uuid.FromString(seriesID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": models.ErrSeasonNumber.Error(),
+		})
+		return nil, false
+	}
+
+	season := models.Season{}
+	found, err := season.FindByNumber(server.DB, seriesID, number)
+	if err != nil {
+		if err == models.ErrSeasonNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return nil, false
+	}
+	return found, true
+}
+
+// CreateSeason handles the creation of a season of a series
+func (server *Server) CreateSeason(c *gin.Context) {
+	seriesID := c.Param("id")
+	if _, err := uuid.FromString(seriesID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	season := models.Season{}
+	if err = json.Unmarshal(body, &season); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	season.ID = uuid.NewV4().String()
+	season.SeriesID = seriesID
+
+	season.Prepare()
+	if err := season.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var seasonCreated *models.Season
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if seasonCreated, err = season.Create(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "season",
+			entityID:   seasonCreated.ID,
+			action:     models.AuditActionCreate,
+			after:      seasonCreated,
+		})
+	})
+	if err != nil {
+		server.seasonError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, seasonCreated)
+}
+
+// GetSeasons handles the list of the seasons of a series, with the episode
+// count and total duration of each
+func (server *Server) GetSeasons(c *gin.Context) {
+	seriesID := c.Param("id")
+	if _, err := uuid.FromString(seriesID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	series := models.Series{ID: seriesID}
+	if err := series.FindByID(server.DB); err != nil {
+		if err == models.ErrSeriesNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	season := models.Season{}
+	seasons, err := season.FindBySeries(server.DB, seriesID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, seasons)
+}
+
+// GetSeason handles season search request
+func (server *Server) GetSeason(c *gin.Context) {
+	season, ok := server.seasonFromPath(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, season)
+}
+
+// UpdateSeason handles season update requests. A season may be renumbered
+// to a number free in its series.
+func (server *Server) UpdateSeason(c *gin.Context) {
+	current, ok := server.seasonFromPath(c)
+	if !ok {
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	newSeason := current.Season
+	if err = json.Unmarshal(body, &newSeason); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	newSeason.ID = current.ID
+	newSeason.SeriesID = current.SeriesID
+
+	newSeason.Prepare()
+	if err := newSeason.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var updatedSeason *models.Season
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if updatedSeason, err = newSeason.Update(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "season",
+			entityID:   current.ID,
+			action:     models.AuditActionUpdate,
+			before:     current.Season,
+			after:      updatedSeason,
+		})
+	})
+	if err != nil {
+		server.seasonError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedSeason)
+}
+
+// DeleteSeason handles season delete requests. Seasons with episodes are
+// refused.
+func (server *Server) DeleteSeason(c *gin.Context) {
+	current, ok := server.seasonFromPath(c)
+	if !ok {
+		return
+	}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		season := current.Season
+		if err := season.Delete(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "season",
+			entityID:   current.ID,
+			action:     models.AuditActionDelete,
+			before:     current.Season,
+		})
+	})
+	if err != nil {
+		server.seasonError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// seasonError writes the response refusing a season change
+func (server *Server) seasonError(c *gin.Context, err error) {
+	switch {
+	case err == models.ErrSeriesNotFound || err == models.ErrSeasonNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case models.IsEpisodeConflict(err):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+	}
+}
+
+// GetEpisodes handles the list of the episodes of a season, by number
+func (server *Server) GetEpisodes(c *gin.Context) {
+	season, ok := server.seasonFromPath(c)
+	if !ok {
+		return
+	}
+
+	video := models.Video{}
+	episodes, err := video.FindEpisodes(server.DB, season.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+	entities := []models.Translatable{}
+	for i := range *episodes {
+		entities = append(entities, &(*episodes)[i])
+	}
+	if !server.localize(c, "video", entities...) {
+		return
+	}
+
+	c.JSON(http.StatusOK, episodes)
+}
+
+// CreateEpisode handles the creation of a video as an episode of a season
+func (server *Server) CreateEpisode(c *gin.Context) {
+	season, ok := server.seasonFromPath(c)
+	if !ok {
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	video := models.Video{}
+	if err = json.Unmarshal(body, &video); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	if video.EpisodeNumber == nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": models.ErrEpisodeIncomplete.Error(),
+		})
+		return
+	}
+	video.SeasonID = &season.ID
+
+	server.createVideo(c, video)
+}
+
+// episodeFromPath finds the episode addressed by the id, number and
+// episode parameters, writing the refusal when there is none
+func (server *Server) episodeFromPath(c *gin.Context) (*models.Video, bool) {
+	season, ok := server.seasonFromPath(c)
+	if !ok {
+		return nil, false
+	}
+	number, err := strconv.Atoi(c.Param("episode"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": models.ErrEpisodeNumber.Error(),
+		})
+		return nil, false
+	}
+
+	video := models.Video{}
+	if err := video.FindEpisode(server.DB, season.ID, number); err != nil {
+		if err == models.ErrEpisodeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return nil, false
+	}
+	return &video, true
+}
+
+// GetEpisode handles episode search request
+func (server *Server) GetEpisode(c *gin.Context) {
+	video, ok := server.episodeFromPath(c)
+	if !ok {
+		return
+	}
+	if !server.localize(c, "video", video) {
+		return
+	}
+
+	c.JSON(http.StatusOK, video)
+}
+
+// RemoveEpisode handles the removal of an episode from its season. The
+// video stays in the catalog, delete it through the video endpoints.
+func (server *Server) RemoveEpisode(c *gin.Context) {
+	video, ok := server.episodeFromPath(c)
+	if !ok {
+		return
+	}
+
+	before := *video
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := video.RemoveFromSeason(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "video",
+			entityID:   video.ID,
+			action:     models.AuditActionUpdate,
+			before:     before,
+			after:      video,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, video)
+}
+
+// GetNextEpisode handles the lookup of the episode following a video
+func (server *Server) GetNextEpisode(c *gin.Context) {
+	server.adjacentEpisode(c, (*models.Video).NextEpisode)
+}
+
+// GetPreviousEpisode handles the lookup of the episode preceding a video
+func (server *Server) GetPreviousEpisode(c *gin.Context) {
+	server.adjacentEpisode(c, (*models.Video).PreviousEpisode)
+}
+
+func (server *Server) adjacentEpisode(c *gin.Context, find func(*models.Video, *gorm.DB) (*models.Video, error)) {
+	videoID := c.Param("id")
+	if _, err := uuid.FromString(videoID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	video := models.Video{ID: videoID}
+	if err := video.FindByID(server.DB); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Video not found",
+		})
+		return
+	}
+
+	episode, err := find(&video, server.DB)
+	if err != nil {
+		if err == models.ErrNotEpisode || err == models.ErrEpisodeNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+	if !server.localize(c, "video", episode) {
+		return
+	}
+
+	c.JSON(http.StatusOK, episode)
+}
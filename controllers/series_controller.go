@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// episodeStatus returns the status refusing a season or episode error
+func episodeStatus(err error) int {
+	if models.IsEpisodeConflict(err) {
+		return http.StatusConflict
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// CreateSeries controller handles series creation request
+func (server *Server) CreateSeries(c *gin.Context) {
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	series := models.Series{}
+	if err = json.Unmarshal(body, &series); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	series.ID = uuid.NewV4().String()
+
+	series.Prepare()
+	if err := series.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var seriesCreated *models.Series
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if seriesCreated, err = series.Create(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "series",
+			entityID:   seriesCreated.ID,
+			action:     models.AuditActionCreate,
+			after:      seriesCreated,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, seriesCreated)
+}
+
+// GetAllSeries handles series list request
+func (server *Server) GetAllSeries(c *gin.Context) {
+	series := models.Series{}
+
+	list, err := series.FindAll(server.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetSeries handles series search request. The series comes with its
+// seasons and their totals.
+func (server *Server) GetSeries(c *gin.Context) {
+	seriesID := c.Param("id")
+	if _, err := uuid.FromString(seriesID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	series := models.Series{ID: seriesID}
+
+	if err := series.FindByID(server.DB); err != nil {
+		if err == models.ErrSeriesNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+	season := models.Season{}
+	seasons, err := season.FindBySeries(server.DB, seriesID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	duration, episodes := 0, 0
+	for _, s := range seasons {
+		duration += s.Duration
+		episodes += s.EpisodeCount
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"series":        series,
+		"seasons":       seasons,
+		"episode_count": episodes,
+		"duration":      duration,
+	})
+}
+
+// UpdateSeries handles series update requests
+func (server *Server) UpdateSeries(c *gin.Context) {
+	seriesID := c.Param("id")
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	newSeries := models.Series{}
+	if err = json.Unmarshal(body, &newSeries); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	newSeries.ID = seriesID
+	newSeries.Prepare()
+	if err := newSeries.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var updatedSeries *models.Series
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Series{ID: seriesID}
+		if err := before.FindByID(tx); err != nil && err != models.ErrSeriesNotFound {
+			return err
+		}
+
+		var err error
+		if updatedSeries, err = newSeries.Update(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "series",
+			entityID:   seriesID,
+			action:     models.AuditActionUpdate,
+			before:     before,
+			after:      updatedSeries,
+		})
+	})
+	if err != nil {
+		if err.Error() == "Internal server error" {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedSeries)
+}
+
+// DeleteSeries handles series delete requests. Series with seasons are
+// refused.
+func (server *Server) DeleteSeries(c *gin.Context) {
+	seriesID := c.Param("id")
+	if _, err := uuid.FromString(seriesID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	series := models.Series{ID: seriesID}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Series{ID: seriesID}
+		if err := before.FindByID(tx); err != nil && err != models.ErrSeriesNotFound {
+			return err
+		}
+
+		if err := series.Delete(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "series",
+			entityID:   seriesID,
+			action:     models.AuditActionDelete,
+			before:     before,
+		})
+	})
+	if err != nil {
+		if err == models.ErrSeriesHasSeasons {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "Series not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// RestoreSeries handles series restore requests
+func (server *Server) RestoreSeries(c *gin.Context) {
+	seriesID := c.Param("id")
+	if _, err := uuid.FromString(seriesID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	series := models.Series{ID: seriesID}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Series{ID: seriesID}
+		if err := before.FindByID(tx.Unscoped()); err != nil && err != models.ErrSeriesNotFound {
+			return err
+		}
+
+		if err := series.Restore(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "series",
+			entityID:   seriesID,
+			action:     models.AuditActionRestore,
+			before:     before,
+			after:      series,
+		})
+	})
+	if err != nil {
+		if err.Error() == "Series not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
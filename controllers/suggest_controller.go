@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"video-catalog/models"
+	"video-catalog/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSuggestQuery bounds the length of suggestion queries, in characters
+const maxSuggestQuery = 100
+
+// Suggest handles typeahead requests, completing category, genre and cast
+// member names
+func (server *Server) Suggest(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" || utf8.RuneCountInString(text) > maxSuggestQuery {
+		abortWithProblem(c, http.StatusUnprocessableEntity, "q must have between 1 and "+strconv.Itoa(maxSuggestQuery)+" characters")
+		return
+	}
+	types, status, err := readableTypes(c, suggestTypes)
+	if err != nil {
+		abortWithProblem(c, status, err.Error())
+		return
+	}
+	limit, _, ok := pagination(c, 10, 50)
+	if !ok {
+		return
+	}
+	if len(types) == 0 {
+		c.JSON(http.StatusOK, []search.Suggestion{})
+		return
+	}
+
+	suggestions, err := server.Suggester.Suggest(c.Request.Context(), search.SuggestQuery{Text: text, Types: types, Limit: limit})
+	if err != nil {
+		if err == search.ErrEmptyQuery {
+			abortWithProblem(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		log.Printf("Error suggesting %q: %v", text, err)
+		abortWithProblem(c, http.StatusInternalServerError, "Error processing request")
+		return
+	}
+	names := map[string][]models.Translatable{}
+	for i := range suggestions {
+		s := &suggestions[i]
+		names[s.Type] = append(names[s.Type], models.NameOf(s.Type, s.ID, &s.Name))
+	}
+	if !server.localizeNames(c, names) {
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
@@ -0,0 +1,319 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"video-catalog/auth"
+	"video-catalog/config"
+	"video-catalog/i18n"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// translatedTypes lists the entity types with translations
+var translatedTypes = []auth.Resource{auth.ResourceCategory, auth.ResourceGenre, auth.ResourceCastMember, auth.ResourceVideo}
+
+// translationChange models the translations of an entity in a locale, as
+// recorded in the audit log and events
+type translationChange struct {
+	EntityType   string              `json:"entity_type"`
+	EntityID     string              `json:"entity_id"`
+	Locale       string              `json:"locale"`
+	Translations models.Translations `json:"translations"`
+}
+
+// locales returns the configured locales, the default ones when the server
+// was built without configuration
+func (server *Server) locales() *i18n.Locales {
+	if server.Locales != nil {
+		return server.Locales
+	}
+	cfg := config.Default().I18n
+	locales, _ := i18n.Parse(cfg.DefaultLocale, cfg.Locales, cfg.Fallbacks)
+	return locales
+}
+
+// localize translates entities to the locale of the request, chosen by the
+// locale query or the Accept-Language header, and announces it with
+// Content-Language. It writes the refusal and returns false when the
+// requested locale is not served.
+func (server *Server) localize(c *gin.Context, entityType string, entities ...models.Translatable) bool {
+	locales := server.locales()
+	locale, err := locales.Negotiate(c.Query("locale"), c.GetHeader("Accept-Language"))
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   err.Error(),
+			"locales": locales.Supported(),
+		})
+		return false
+	}
+	c.Header("Content-Language", locale)
+	c.Header("Vary", "Accept-Language")
+
+	if err := models.Localize(server.DB, entityType, locales.Chain(locale), entities...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return false
+	}
+	return true
+}
+
+// localizeNames localizes entity names held outside of their model, by
+// entity type, see models.NameOf
+func (server *Server) localizeNames(c *gin.Context, names map[string][]models.Translatable) bool {
+	for _, t := range []string{"category", "genre", "cast_member", "video"} {
+		if !server.localize(c, t, names[t]...) {
+			return false
+		}
+	}
+	return true
+}
+
+// facetNames returns the labels of the category and genre facets, named
+// after entities
+func facetNames(facets models.VideoFacets) map[string][]models.Translatable {
+	names := map[string][]models.Translatable{}
+	for facet, entityType := range map[string]string{models.FacetCategory: "category", models.FacetGenre: "genre"} {
+		counts := facets[facet]
+		for i := range counts {
+			names[entityType] = append(names[entityType], models.NameOf(entityType, counts[i].Value, &counts[i].Label))
+		}
+	}
+	return names
+}
+
+// translationLocale reads the locale parameter, which must be a served
+// locale other than the default one
+func (server *Server) translationLocale(c *gin.Context) (string, bool) {
+	locales := server.locales()
+	locale := i18n.Canonical(c.Param("locale"))
+	if !locales.Supports(locale) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   i18n.ErrUnsupportedLocale.Error(),
+			"locales": locales.Translated(),
+		})
+		return "", false
+	}
+	if locale == locales.Default {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": models.ErrTranslationNotUsed.Error(),
+		})
+		return "", false
+	}
+	return locale, true
+}
+
+// GetTranslations returns a handler listing the translations of an entity
+// by locale
+func (server *Server) GetTranslations(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entityID := c.Param("id")
+		if _, err := uuid.FromString(entityID); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		translations, err := models.FindTranslations(server.DB, entityType, entityID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error processing request",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, translations)
+	}
+}
+
+// SetTranslation returns a handler storing the translations of an entity
+// in a locale. Only the fields given change; each must satisfy the rules
+// of the field it translates.
+func (server *Server) SetTranslation(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entityID := c.Param("id")
+		if _, err := uuid.FromString(entityID); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		locale, ok := server.translationLocale(c)
+		if !ok {
+			return
+		}
+
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err,
+			})
+			return
+		}
+		values := models.Translations{}
+		if err = json.Unmarshal(body, &values); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		values.Prepare()
+		if err := values.Validate(entityType); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		var after models.Translations
+		err = server.DB.Transaction(func(tx *gorm.DB) error {
+			current, err := models.FindTranslations(tx, entityType, entityID)
+			if err != nil {
+				return err
+			}
+			if err := models.SetTranslations(tx, entityType, entityID, locale, values); err != nil {
+				return err
+			}
+			updated, err := models.FindTranslations(tx, entityType, entityID)
+			if err != nil {
+				return err
+			}
+			after = updated[locale]
+
+			ch := change{
+				entityType:    "translation",
+				entityID:      entityID,
+				action:        models.AuditActionUpdate,
+				after:         translationChange{entityType, entityID, locale, after},
+				eventType:     entityType + ".translated",
+				aggregateType: entityType,
+				aggregateID:   entityID,
+			}
+			if before, ok := current[locale]; ok {
+				ch.before = translationChange{entityType, entityID, locale, before}
+			} else {
+				ch.action = models.AuditActionCreate
+			}
+			return server.recordChange(c, tx, ch)
+		})
+		if err != nil {
+			if err == models.ErrEntityNotFound {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error processing request",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, after)
+	}
+}
+
+// DeleteTranslation returns a handler removing the translations of an
+// entity in a locale
+func (server *Server) DeleteTranslation(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entityID := c.Param("id")
+		if _, err := uuid.FromString(entityID); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		locale, ok := server.translationLocale(c)
+		if !ok {
+			return
+		}
+
+		err := server.DB.Transaction(func(tx *gorm.DB) error {
+			current, err := models.FindTranslations(tx, entityType, entityID)
+			if err != nil {
+				return err
+			}
+			before, ok := current[locale]
+			if !ok {
+				return gorm.ErrRecordNotFound
+			}
+			if _, err := models.DeleteTranslations(tx, entityType, entityID, locale); err != nil {
+				return err
+			}
+			return server.recordChange(c, tx, change{
+				entityType:    "translation",
+				entityID:      entityID,
+				action:        models.AuditActionDelete,
+				before:        translationChange{entityType, entityID, locale, before},
+				eventType:     entityType + ".translation_deleted",
+				aggregateType: entityType,
+				aggregateID:   entityID,
+			})
+		})
+		if err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": "Translation not found",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error processing request",
+			})
+			return
+		}
+
+		c.JSON(http.StatusNoContent, gin.H{})
+	}
+}
+
+// GetMissingTranslations lists the entities missing translations, by type
+// and locale. The types and locale queries narrow the listing, which
+// defaults to every readable type and every translated locale.
+func (server *Server) GetMissingTranslations(c *gin.Context) {
+	types, status, err := readableTypes(c, translatedTypes)
+	if err != nil {
+		c.JSON(status, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	locales := server.locales()
+	selected := locales.Translated()
+	if value := c.Query("locale"); value != "" {
+		selected = []string{}
+		for _, tag := range strings.Split(value, ",") {
+			locale := i18n.Canonical(tag)
+			if !locales.Supports(locale) || locale == locales.Default {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error":   i18n.ErrUnsupportedLocale.Error(),
+					"locales": locales.Translated(),
+				})
+				return
+			}
+			selected = append(selected, locale)
+		}
+	}
+
+	missing, err := models.FindMissingTranslations(server.DB, types, selected)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  missing,
+		"total": len(missing),
+	})
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"video-catalog/models"
+	"video-catalog/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// videoFileInput models a file registration request, the file being
+// already stored under key
+type videoFileInput struct {
+	Type        string `json:"type"`
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+}
+
+// RegisterVideoFile handles the registration of a stored video, trailer,
+// thumbnail or banner file. The file then waits for the encoder.
+func (server *Server) RegisterVideoFile(c *gin.Context) {
+	videoID := c.Param("id")
+	if _, err := uuid.FromString(videoID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	input := videoFileInput{}
+	if err = json.Unmarshal(body, &input); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	videoFile := models.VideoFile{
+		ID:          uuid.NewV4().String(),
+		VideoID:     videoID,
+		Type:        input.Type,
+		Key:         strings.TrimSpace(input.Key),
+		ContentType: strings.TrimSpace(input.ContentType),
+		Status:      models.VideoFileStatusUploaded,
+	}
+	if err := videoFile.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	videoFile.Size, err = server.Storage.Size(c.Request.Context(), videoFile.Key)
+	if err == storage.ErrNotFound || err == storage.ErrInvalidKey {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+	videoFile.URL = server.Storage.URL(videoFile.Key)
+
+	var created *models.VideoFile
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		video := models.Video{ID: videoID}
+		if err := video.FindByID(tx); err != nil {
+			return err
+		}
+
+		var err error
+		if created, err = videoFile.Create(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType:  "video_file",
+			entityID:    created.ID,
+			action:      models.AuditActionCreate,
+			after:       created,
+			eventType:   "video.file_uploaded",
+			aggregateID: videoID,
+		})
+	})
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Video not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
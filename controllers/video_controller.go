@@ -2,11 +2,15 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 	"video-catalog/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -27,6 +31,11 @@ func (server *Server) CreateVideo(c *gin.Context) {
 		})
 		return
 	}
+	server.createVideo(c, video)
+}
+
+// createVideo validates and creates a video decoded from a request body
+func (server *Server) createVideo(c *gin.Context, video models.Video) {
 	video.ID = uuid.NewV4().String()
 
 	video.Prepare()
@@ -36,126 +45,343 @@ func (server *Server) CreateVideo(c *gin.Context) {
 		})
 		return
 	}
+	if err := video.ValidateRelations(server.DB); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var videoCreated *models.Video
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := video.ValidateEpisode(tx); err != nil {
+			return err
+		}
+
+		var err error
+		if videoCreated, err = video.Create(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "video",
+			entityID:   videoCreated.ID,
+			action:     models.AuditActionCreate,
+			after:      videoCreated,
+		})
+	})
+	if err != nil {
+		if models.IsEpisodeError(err) {
+			c.JSON(episodeStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, videoCreated)
+}
+
+// GetVideos handles videos list request. The list can be filtered by
+// category, genre, rating, decade, duration and opened; facets=true wraps
+// it with the facet counts. include_subcategories=true extends category
+// filters to their subcategories. max_rating keeps the videos rated at
+// most a level, as system:level or a ClassInd level.
+func (server *Server) GetVideos(c *gin.Context) {
+	filter, err := server.videoFilter(c)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	video := models.Video{}
+
+	videos, err := video.FindFiltered(server.DB, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err,
+		})
+		return
+	}
+	entities := []models.Translatable{}
+	for i := range *videos {
+		entities = append(entities, &(*videos)[i])
+	}
+	if !server.localize(c, "video", entities...) {
+		return
+	}
 
-	// videoCreated, err := video.Create(server.DB)
+	if c.Query("facets") != "true" {
+		c.JSON(http.StatusOK, videos)
+		return
+	}
+	facets, err := models.CountVideoFacets(server.DB, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Error processing request",
 		})
 		return
 	}
+	if !server.localizeNames(c, facetNames(facets)) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":   videos,
+		"facets": facets,
+	})
+}
+
+// GetVideo handles video search request
+func (server *Server) GetVideo(c *gin.Context) {
+	videoID := c.Param("id")
+	if _, err := uuid.FromString(videoID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	video := models.Video{ID: videoID}
+
+	err := video.FindByID(server.DB)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if !server.localize(c, "video", &video) {
+		return
+	}
+
+	c.JSON(http.StatusOK, video)
+}
+
+// UpdateVideo handles video update requests
+func (server *Server) UpdateVideo(c *gin.Context) {
+	videoID := c.Param("id")
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	newVideo := models.Video{}
+	if err = json.Unmarshal(body, &newVideo); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	newVideo.ID = videoID
+	newVideo.Prepare()
+	if err := newVideo.Validate("update"); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	if err := newVideo.ValidateRelations(server.DB); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var updatedVideo *models.Video
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Video{ID: videoID}
+		if err := before.FindByID(tx); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if err := newVideo.ValidateEpisode(tx); err != nil {
+			return err
+		}
+
+		var err error
+		if updatedVideo, err = newVideo.Update(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "video",
+			entityID:   videoID,
+			action:     models.AuditActionUpdate,
+			before:     before,
+			after:      updatedVideo,
+		})
+	})
+	if err != nil {
+		if models.IsEpisodeError(err) {
+			c.JSON(episodeStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "Internal server error" {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err,
+			})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedVideo)
+}
+
+// DeleteVideo handles video delete requests
+func (server *Server) DeleteVideo(c *gin.Context) {
+	videoID := c.Param("id")
+	if _, err := uuid.FromString(videoID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	video := models.Video{ID: videoID}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Video{ID: videoID}
+		if err := before.FindByID(tx); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if err := video.Delete(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "video",
+			entityID:   videoID,
+			action:     models.AuditActionDelete,
+			before:     before,
+		})
+	})
+	if err != nil {
+		if err.Error() == "Video not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// RestoreVideo handles video restore requests
+func (server *Server) RestoreVideo(c *gin.Context) {
+	videoID := c.Param("id")
+	if _, err := uuid.FromString(videoID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err,
+		})
+		return
+	}
+	video := models.Video{ID: videoID}
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		before := models.Video{ID: videoID}
+		if err := before.FindByID(tx.Unscoped()); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if err := video.Restore(tx); err != nil {
+			return err
+		}
+		return server.recordChange(c, tx, change{
+			entityType: "video",
+			entityID:   videoID,
+			action:     models.AuditActionRestore,
+			before:     before,
+			after:      video,
+		})
+	})
+	if err != nil {
+		if err == models.ErrEpisodeTaken {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "Video not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err,
+		})
+		return
+	}
 
-	// c.JSON(http.StatusCreated, videoCreated)
-	c.JSON(http.StatusCreated, video)
+	c.JSON(http.StatusOK, video)
 }
 
-// // GetCastMembers handles cast members list request
-// func (server *Server) GetCastMembers(c *gin.Context) {
-// 	castMember := models.CastMember{}
-
-// 	castMembers, err := castMember.FindAll(server.DB)
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{
-// 			"error": err,
-// 		})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, castMembers)
-// }
-
-// // GetCastMember handles cast member search request
-// func (server *Server) GetCastMember(c *gin.Context) {
-// 	castMemberID := c.Param("id")
-// 	if _, err := uuid.FromString(castMemberID); err != nil {
-// 		c.JSON(http.StatusUnprocessableEntity, gin.H{
-// 			"error": err,
-// 		})
-// 		return
-// 	}
-// 	castMember := models.CastMember{ID: castMemberID}
-
-// 	err := castMember.FindByID(server.DB)
-// 	if err != nil {
-// 		c.JSON(http.StatusNotFound, gin.H{
-// 			"error": err,
-// 		})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, castMember)
-// }
-
-// // UpdateCastMember handles cast member update requests
-// func (server *Server) UpdateCastMember(c *gin.Context) {
-// 	castMemberID := c.Param("id")
-
-// 	body, err := ioutil.ReadAll(c.Request.Body)
-// 	if err != nil {
-// 		c.JSON(http.StatusUnprocessableEntity, gin.H{
-// 			"error": err,
-// 		})
-// 		return
-// 	}
-
-// 	newCastMember := models.CastMember{}
-// 	if err = json.Unmarshal(body, &newCastMember); err != nil {
-// 		c.JSON(http.StatusUnprocessableEntity, gin.H{
-// 			"error": err,
-// 		})
-// 		return
-// 	}
-
-// 	newCastMember.ID = castMemberID
-// 	newCastMember.Prepare()
-// 	if err := newCastMember.Validate("update"); err != nil {
-// 		c.JSON(http.StatusUnprocessableEntity, gin.H{
-// 			"error": err,
-// 		})
-// 		return
-// 	}
-
-// 	updatedCastMember, err := newCastMember.Update(server.DB)
-// 	if err != nil {
-// 		if err.Error() == "Internal server error" {
-// 			c.JSON(http.StatusInternalServerError, gin.H{
-// 				"error": err,
-// 			})
-// 			return
-// 		}
-// 		c.JSON(http.StatusNotFound, gin.H{
-// 			"error": err,
-// 		})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, updatedCastMember)
-// }
-
-// // DeleteCastMember handles cast member delete requests
-// func (server *Server) DeleteCastMember(c *gin.Context) {
-// 	castMemberID := c.Param("id")
-// 	if _, err := uuid.FromString(castMemberID); err != nil {
-// 		c.JSON(http.StatusUnprocessableEntity, gin.H{
-// 			"error": err,
-// 		})
-// 		return
-// 	}
-// 	castMember := models.CastMember{ID: castMemberID}
-
-// 	if err := castMember.Delete(server.DB); err != nil {
-// 		if err.Error() == "CastMember not found" {
-// 			c.JSON(http.StatusNotFound, gin.H{
-// 				"error": err,
-// 			})
-// 			return
-// 		}
-
-// 		c.JSON(http.StatusInternalServerError, gin.H{
-// 			"error": err,
-// 		})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusNoContent, gin.H{})
-// }
+// videoFilter reads the video facet filters of the request. A filter may
+// be repeated or hold comma separated values.
+func (server *Server) videoFilter(c *gin.Context) (models.VideoFilter, error) {
+	filter := models.VideoFilter{
+		Categories:        queryList(c, "category"),
+		WithSubcategories: c.Query("include_subcategories") == "true",
+		Genres:            queryList(c, "genre"),
+		Ratings:           queryList(c, "rating"),
+		Durations:         queryList(c, "duration"),
+	}
+	for _, id := range append(append([]string{}, filter.Categories...), filter.Genres...) {
+		if _, err := uuid.FromString(id); err != nil {
+			return filter, fmt.Errorf("Invalid id %q", id)
+		}
+	}
+	for _, value := range queryList(c, "decade") {
+		decade, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid decade %q", value)
+		}
+		filter.Decades = append(filter.Decades, decade)
+	}
+	maxRating, err := server.maxRating(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.MaxRating = maxRating
+	if value, ok := c.GetQuery("opened"); ok {
+		opened, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid opened %q", value)
+		}
+		filter.Opened = &opened
+	}
+	return filter, filter.Validate()
+}
+
+// queryList returns the values of a repeated or comma separated query
+func queryList(c *gin.Context, name string) []string {
+	values := []string{}
+	for _, query := range c.QueryArray(name) {
+		for _, value := range strings.Split(query, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
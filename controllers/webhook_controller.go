@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// webhookInput models the writable fields of a webhook. Update keeps the
+// fields left out.
+type webhookInput struct {
+	URL        *string   `json:"url"`
+	EventTypes *[]string `json:"event_types"`
+	Secret     *string   `json:"secret"`
+	Active     *bool     `json:"active"`
+}
+
+func (in webhookInput) apply(webhook *models.Webhook) {
+	if in.URL != nil {
+		webhook.URL = *in.URL
+	}
+	if in.EventTypes != nil {
+		webhook.EventTypes = models.StringList(*in.EventTypes)
+	}
+	if in.Secret != nil {
+		webhook.Secret = *in.Secret
+	}
+	if in.Active != nil {
+		webhook.Active = *in.Active
+	}
+}
+
+func readWebhookInput(c *gin.Context) (webhookInput, bool) {
+	in := webhookInput{}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &in)
+	}
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return in, false
+	}
+	return in, true
+}
+
+// CreateWebhook handles webhook subscription requests
+func (server *Server) CreateWebhook(c *gin.Context) {
+	in, ok := readWebhookInput(c)
+	if !ok {
+		return
+	}
+
+	webhook := models.Webhook{}
+	in.apply(&webhook)
+	webhook.ID = uuid.NewV4().String()
+	webhook.Active = true
+	webhook.CreatedBy = c.GetString(subjectKey)
+
+	webhook.Prepare()
+	if err := webhook.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	webhookCreated, err := webhook.Create(server.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhookCreated)
+}
+
+// GetWebhooks handles webhooks list request
+func (server *Server) GetWebhooks(c *gin.Context) {
+	webhook := models.Webhook{}
+
+	webhooks, err := webhook.FindAll(server.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhook handles webhook search request
+func (server *Server) GetWebhook(c *gin.Context) {
+	webhook, ok := server.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook handles webhook update requests. Setting active to true
+// enables a webhook disabled after repeated failures.
+func (server *Server) UpdateWebhook(c *gin.Context) {
+	webhook, ok := server.findWebhook(c)
+	if !ok {
+		return
+	}
+	in, ok := readWebhookInput(c)
+	if !ok {
+		return
+	}
+
+	in.apply(webhook)
+	webhook.Prepare()
+	if err := webhook.Validate(); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	updated, err := webhook.Update(server.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteWebhook handles webhook delete requests
+func (server *Server) DeleteWebhook(c *gin.Context) {
+	webhook, ok := server.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := webhook.Delete(server.DB); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// GetWebhookDeliveries handles the delivery log of a webhook, newest first
+func (server *Server) GetWebhookDeliveries(c *gin.Context) {
+	webhook, ok := server.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "limit must be between 1 and 1000",
+		})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "offset must be a positive integer",
+		})
+		return
+	}
+
+	delivery := models.WebhookDelivery{WebhookID: webhook.ID}
+	deliveries, err := delivery.FindAll(server.DB, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// RedeliverWebhook handles requests to send a delivery again, whatever its
+// status. It is queued for the next send.
+func (server *Server) RedeliverWebhook(c *gin.Context) {
+	webhook, ok := server.findWebhook(c)
+	if !ok {
+		return
+	}
+	deliveryID := c.Param("delivery_id")
+	if _, err := uuid.FromString(deliveryID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	delivery := models.WebhookDelivery{ID: deliveryID, WebhookID: webhook.ID}
+	if err := delivery.FindByID(server.DB); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.NextAttemptAt = time.Now()
+	delivery.Attempts = 0
+	if err := delivery.Save(server.DB); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error processing request",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// findWebhook loads the webhook of the id param, answering the request
+// when it is invalid or unknown
+func (server *Server) findWebhook(c *gin.Context) (*models.Webhook, bool) {
+	webhookID := c.Param("id")
+	if _, err := uuid.FromString(webhookID); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+
+	webhook := models.Webhook{ID: webhookID}
+	if err := webhook.FindByID(server.DB); err != nil {
+		status := http.StatusInternalServerError
+		if err == models.ErrWebhookNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	return &webhook, true
+}
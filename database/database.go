@@ -0,0 +1,27 @@
+package database
+
+import (
+	"fmt"
+	"video-catalog/config"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres" // postgres driver
+)
+
+// Open connects to the configured database and applies pool settings
+func Open(cfg config.DBConfig) (*gorm.DB, error) {
+	db, err := gorm.Open(cfg.Driver, cfg.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to %s database: %v", cfg.Driver, err)
+	}
+
+	db.DB().SetMaxOpenConns(cfg.MaxOpenConns)
+	db.DB().SetMaxIdleConns(cfg.MaxIdleConns)
+	db.DB().SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if cfg.Debug {
+		db.LogMode(true)
+	}
+
+	return db, nil
+}
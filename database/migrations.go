@@ -0,0 +1,366 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"video-catalog/slug"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Migration models a versioned schema change
+type Migration struct {
+	ID      string
+	Migrate func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	ID        string    `gorm:"primary_key"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Migrations lists every schema change in the order it must be applied.
+// Each one spells out its own DDL rather than migrating the models, so it
+// keeps building the schema it was written for as the models move on.
+var Migrations = []Migration{
+	{
+		ID: "202010010001_create_categories",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS categories (id uuid, name text UNIQUE, description text, is_active boolean DEFAULT true,
+					created_at timestamp with time zone, updated_at timestamp with time zone, deleted_at timestamp with time zone, PRIMARY KEY (id))`,
+			)
+		},
+	},
+	{
+		ID: "202010010002_create_genres",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS genres (id uuid, name text UNIQUE, is_active boolean DEFAULT true,
+					created_at timestamp with time zone, updated_at timestamp with time zone, deleted_at timestamp with time zone, PRIMARY KEY (id))`,
+			)
+		},
+	},
+	{
+		ID: "202010010003_create_cast_members",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS cast_members (id uuid, name text UNIQUE, type integer,
+					created_at timestamp with time zone, updated_at timestamp with time zone, deleted_at timestamp with time zone, PRIMARY KEY (id))`,
+			)
+		},
+	},
+	{
+		ID: "202010010004_create_videos",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS videos (id uuid, title varchar(255), description text, year_launched integer,
+					opened boolean DEFAULT false, rating text, duration integer,
+					created_at timestamp with time zone, updated_at timestamp with time zone, deleted_at timestamp with time zone, PRIMARY KEY (id))`,
+			)
+		},
+	},
+	{
+		ID: "202010200001_create_api_keys",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS api_keys (id uuid, name varchar(255) NOT NULL, prefix varchar(16) NOT NULL, hash varchar(64) NOT NULL,
+					scopes text, created_by varchar(255), expires_at timestamp with time zone, last_used_at timestamp with time zone,
+					revoked_at timestamp with time zone, created_at timestamp with time zone, updated_at timestamp with time zone, PRIMARY KEY (id))`,
+				"CREATE UNIQUE INDEX IF NOT EXISTS uix_api_keys_hash ON api_keys (hash)",
+			)
+		},
+	},
+	{
+		ID: "202010220001_create_audit_entries",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS audit_entries (id uuid, actor varchar(255), entity_type varchar(64), entity_id varchar(64),
+					action varchar(32), request_id varchar(64), before jsonb, after jsonb, diff jsonb,
+					created_at timestamp with time zone, PRIMARY KEY (id))`,
+				"CREATE INDEX IF NOT EXISTS idx_audit_entries_actor ON audit_entries (actor)",
+				"CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit_entries (entity_type, entity_id)",
+				"CREATE INDEX IF NOT EXISTS idx_audit_entries_created_at ON audit_entries (created_at)",
+			)
+		},
+	},
+	{
+		ID: "202010230001_create_revisions",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS revisions (id uuid, entity_type varchar(64), entity_id varchar(64), version integer,
+					action varchar(32), actor varchar(255), snapshot jsonb, created_at timestamp with time zone, PRIMARY KEY (id))`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS idx_revision_version ON revisions (entity_type, entity_id, "version")`,
+			)
+		},
+	},
+	{
+		ID: "202010260001_create_video_files",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS video_files (id uuid, video_id uuid NOT NULL, type varchar(32) NOT NULL, key varchar(255) NOT NULL,
+					url varchar(1024), size bigint, content_type varchar(255), status varchar(32) NOT NULL, output_path varchar(1024), error text,
+					created_at timestamp with time zone, updated_at timestamp with time zone, PRIMARY KEY (id))`,
+				"CREATE INDEX IF NOT EXISTS idx_video_files_video_id ON video_files (video_id)",
+			)
+		},
+	},
+	{
+		ID: "202010270001_create_outbox_messages",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS outbox_messages (sequence bigserial, event_id uuid NOT NULL, event_type varchar(128) NOT NULL,
+					aggregate_type varchar(64) NOT NULL, aggregate_id varchar(64) NOT NULL, payload jsonb NOT NULL,
+					attempts integer NOT NULL DEFAULT 0, last_error text, next_attempt_at timestamp with time zone NOT NULL,
+					created_at timestamp with time zone NOT NULL, published_at timestamp with time zone, PRIMARY KEY (sequence))`,
+				"CREATE INDEX IF NOT EXISTS idx_outbox_messages_aggregate_id ON outbox_messages (aggregate_id)",
+				"CREATE INDEX IF NOT EXISTS idx_outbox_messages_published_at ON outbox_messages (published_at)",
+				"CREATE UNIQUE INDEX IF NOT EXISTS uix_outbox_messages_event_id ON outbox_messages (event_id)",
+			)
+		},
+	},
+	{
+		ID: "202010280001_create_webhooks",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS webhooks (id uuid, url varchar(2048) NOT NULL, event_types text NOT NULL, secret varchar(255) NOT NULL,
+					active boolean NOT NULL DEFAULT true, consecutive_failures integer NOT NULL DEFAULT 0, disabled_at timestamp with time zone,
+					created_by varchar(255), created_at timestamp with time zone, updated_at timestamp with time zone,
+					deleted_at timestamp with time zone, PRIMARY KEY (id))`,
+				"CREATE INDEX IF NOT EXISTS idx_webhooks_deleted_at ON webhooks (deleted_at)",
+				`CREATE TABLE IF NOT EXISTS webhook_deliveries (id uuid, webhook_id uuid NOT NULL, event_id uuid NOT NULL,
+					event_type varchar(128) NOT NULL, payload jsonb NOT NULL, status varchar(32) NOT NULL, attempts integer NOT NULL DEFAULT 0,
+					next_attempt_at timestamp with time zone NOT NULL, response_status integer, last_error text,
+					delivered_at timestamp with time zone, created_at timestamp with time zone, updated_at timestamp with time zone, PRIMARY KEY (id))`,
+				"CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status)",
+				"CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries (created_at)",
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_delivery_event ON webhook_deliveries (webhook_id, event_id)",
+			)
+		},
+	},
+	{
+		ID:      "202010300001_create_search_indexes",
+		Migrate: createSearchIndexes,
+	},
+	{
+		ID: "202010310001_create_video_relations",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE IF NOT EXISTS category_video (video_id uuid, category_id uuid, PRIMARY KEY (video_id, category_id))",
+				"CREATE INDEX IF NOT EXISTS idx_category_video_category_id ON category_video (category_id)",
+				"CREATE TABLE IF NOT EXISTS genre_video (video_id uuid, genre_id uuid, PRIMARY KEY (video_id, genre_id))",
+				"CREATE INDEX IF NOT EXISTS idx_genre_video_genre_id ON genre_video (genre_id)",
+			)
+		},
+	},
+	{
+		ID:      "202011010001_create_suggest_indexes",
+		Migrate: createSuggestIndexes,
+	},
+	{
+		ID: "202011020001_add_category_parent",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id uuid",
+				"CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id)",
+			)
+		},
+	},
+	{
+		ID:      "202011030001_add_positions",
+		Migrate: addPositions,
+	},
+	{
+		ID:      "202011040001_add_slugs",
+		Migrate: addSlugs,
+	},
+	{
+		ID:      "202011050001_create_series",
+		Migrate: createSeries,
+	},
+	{
+		ID: "202011060001_create_translations",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS translations (entity_type varchar(32), entity_id uuid, locale varchar(35), field varchar(32),
+					value text NOT NULL, updated_at timestamp with time zone, PRIMARY KEY (entity_type, entity_id, locale, field))`,
+				"CREATE INDEX IF NOT EXISTS idx_translations_locale ON translations (entity_type, locale, field)",
+			)
+		},
+	},
+	{
+		ID: "202011070001_create_video_ratings",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE IF NOT EXISTS video_ratings (video_id uuid, system varchar(20), level varchar(10) NOT NULL, PRIMARY KEY (video_id, system))",
+				"CREATE INDEX IF NOT EXISTS idx_video_ratings_level ON video_ratings (system, level)",
+			)
+		},
+	},
+}
+
+// execAll runs statements in order, stopping at the first that fails
+func execAll(tx *gorm.DB, statements ...string) error {
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addPositions adds the manual order of categories and genres, numbering
+// the existing rows by creation
+func addPositions(tx *gorm.DB) error {
+	for _, table := range []string{"categories", "genres"} {
+		err := execAll(tx,
+			fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS "position" integer NOT NULL DEFAULT 0`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%[1]s_position ON %[1]s ("position")`, table),
+			fmt.Sprintf(`UPDATE %[1]s SET position = o.n
+				FROM (SELECT id, row_number() OVER (ORDER BY created_at, id) AS n FROM %[1]s) o
+				WHERE %[1]s.id = o.id`, table),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// slugSuffixRoom is kept free at the end of a backfilled slug for its
+// numeric suffix
+const slugSuffixRoom = 8
+
+// slugBackfills lists the tables given slugs by addSlugs, with the entity
+// type and the column each slug was made of when they were added
+var slugBackfills = []struct{ entityType, table, column string }{
+	{"category", "categories", "name"},
+	{"genre", "genres", "name"},
+	{"cast_member", "cast_members", "name"},
+	{"video", "videos", "title"},
+}
+
+// addSlugs adds the slugs of categories, genres, cast members and videos,
+// assigning them by creation so the oldest entity keeps the plain slug
+func addSlugs(tx *gorm.DB) error {
+	err := execAll(tx,
+		`CREATE TABLE IF NOT EXISTS slug_redirects (entity_type varchar(32), slug varchar(255), entity_id uuid NOT NULL,
+			created_at timestamp with time zone, PRIMARY KEY (entity_type, slug))`,
+		"CREATE INDEX IF NOT EXISTS idx_slug_redirects_entity_id ON slug_redirects (entity_id)",
+	)
+	if err != nil {
+		return err
+	}
+	for _, b := range slugBackfills {
+		err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS slug varchar(255) NOT NULL DEFAULT ''", b.table)).Error
+		if err != nil {
+			return err
+		}
+
+		rows := []struct{ ID, Text string }{}
+		err = tx.Raw(fmt.Sprintf("SELECT id, %s AS text FROM %s ORDER BY created_at, id", b.column, b.table)).Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+		used := map[string]bool{}
+		for _, row := range rows {
+			base := slug.Make(row.Text)
+			if base == "" {
+				base = strings.Replace(b.entityType, "_", "-", -1)
+			}
+			candidate := base
+			for n := 2; used[candidate]; n++ {
+				candidate = fmt.Sprintf("%s-%d", slug.Truncate(base, slug.MaxLength-slugSuffixRoom), n)
+			}
+			used[candidate] = true
+			if err := tx.Exec(fmt.Sprintf("UPDATE %s SET slug = ? WHERE id = ?", b.table), candidate, row.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		err = tx.Exec(fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS idx_%[1]s_slug ON %[1]s (slug) WHERE slug <> ''", b.table)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSeries adds series, their seasons and the season and episode
+// numbers of videos. Numbers are unique among live rows only, so deleted
+// seasons and episodes don't hold them.
+func createSeries(tx *gorm.DB) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS series (id uuid, title varchar(255), description text, year_launched integer,
+			created_at timestamp with time zone, updated_at timestamp with time zone, deleted_at timestamp with time zone, PRIMARY KEY (id))`,
+		`CREATE TABLE IF NOT EXISTS seasons (id uuid, series_id uuid NOT NULL, number integer NOT NULL, title varchar(255),
+			created_at timestamp with time zone, updated_at timestamp with time zone, deleted_at timestamp with time zone, PRIMARY KEY (id))`,
+		"CREATE INDEX IF NOT EXISTS idx_seasons_series_id ON seasons (series_id)",
+		"ALTER TABLE videos ADD COLUMN IF NOT EXISTS season_id uuid",
+		"ALTER TABLE videos ADD COLUMN IF NOT EXISTS episode_number integer",
+		"CREATE INDEX IF NOT EXISTS idx_videos_season_id ON videos (season_id)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_seasons_number ON seasons (series_id, number) WHERE deleted_at IS NULL",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_videos_episode ON videos (season_id, episode_number) WHERE deleted_at IS NULL AND season_id IS NOT NULL",
+	)
+}
+
+// Pending returns the ids of migrations not applied yet
+func Pending(db *gorm.DB) ([]string, error) {
+	applied := []SchemaMigration{}
+	if db.HasTable(&SchemaMigration{}) {
+		if err := db.Find(&applied).Error; err != nil {
+			return nil, err
+		}
+	}
+	done := map[string]bool{}
+	for _, m := range applied {
+		done[m.ID] = true
+	}
+
+	pending := []string{}
+	for _, m := range Migrations {
+		if !done[m.ID] {
+			pending = append(pending, m.ID)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies every pending migration, each one in its own transaction
+func Migrate(db *gorm.DB) ([]string, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}).Error; err != nil {
+		return nil, err
+	}
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+	isPending := map[string]bool{}
+	for _, id := range pending {
+		isPending[id] = true
+	}
+
+	applied := []string{}
+	for _, m := range Migrations {
+		if !isPending[m.ID] {
+			continue
+		}
+
+		tx := db.Begin()
+		if err := m.Migrate(tx); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("migration %s failed: %v", m.ID, err)
+		}
+		if err := tx.Create(&SchemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error; err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("migration %s failed: %v", m.ID, err)
+		}
+		if err := tx.Commit().Error; err != nil {
+			return applied, fmt.Errorf("migration %s failed: %v", m.ID, err)
+		}
+		applied = append(applied, m.ID)
+	}
+	return applied, nil
+}
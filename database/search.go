@@ -0,0 +1,93 @@
+package database
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// SearchConfig is the text search configuration of the catalog: Portuguese
+// stemming over unaccented words, so acao matches Ação
+const SearchConfig = "pt_unaccent"
+
+// SearchDocuments maps each searchable table to its weighted tsvector.
+// Queries must use the same expressions for the indexes to be used.
+var SearchDocuments = map[string]string{
+	"videos":       "setweight(to_tsvector('pt_unaccent', coalesce(title, '')), 'A') || setweight(to_tsvector('pt_unaccent', coalesce(description, '')), 'B')",
+	"categories":   "setweight(to_tsvector('pt_unaccent', coalesce(name, '')), 'A') || setweight(to_tsvector('pt_unaccent', coalesce(description, '')), 'B')",
+	"genres":       "setweight(to_tsvector('pt_unaccent', coalesce(name, '')), 'A')",
+	"cast_members": "setweight(to_tsvector('pt_unaccent', coalesce(name, '')), 'A')",
+}
+
+// searchIndexes orders SearchDocuments so the migration is deterministic
+var searchIndexes = []string{"videos", "categories", "genres", "cast_members"}
+
+func createSearchIndexes(tx *gorm.DB) error {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS unaccent",
+		`DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'pt_unaccent') THEN
+		CREATE TEXT SEARCH CONFIGURATION pt_unaccent (COPY = portuguese);
+		ALTER TEXT SEARCH CONFIGURATION pt_unaccent
+			ALTER MAPPING FOR hword, hword_part, word WITH unaccent, portuguese_stem;
+	END IF;
+END
+$$`,
+	}
+	for _, table := range searchIndexes {
+		statements = append(statements, "CREATE INDEX idx_"+table+"_search ON "+table+" USING gin (("+SearchDocuments[table]+"))")
+	}
+
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NormalizeFunction folds accents of its text argument. Unlike unaccent it
+// is immutable, so expression indexes may use it.
+const NormalizeFunction = "catalog_unaccent"
+
+// SuggestTables maps each table searched by suggestions to its suggested
+// column
+var SuggestTables = map[string]string{
+	"categories":   "name",
+	"genres":       "name",
+	"cast_members": "name",
+}
+
+// suggestIndexes orders SuggestTables so the migration is deterministic
+var suggestIndexes = []string{"categories", "genres", "cast_members"}
+
+// SuggestKey returns the normalized expression suggestions match column
+// against. Queries must use the same expression for the indexes to be used.
+func SuggestKey(column string) string {
+	return "lower(" + NormalizeFunction + "(" + column + "))"
+}
+
+// createSuggestIndexes indexes the suggested columns twice: a pattern
+// index answers prefix matches, a trigram index answers similarity ones
+func createSuggestIndexes(tx *gorm.DB) error {
+	statements := []string{
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE EXTENSION IF NOT EXISTS unaccent",
+		"CREATE OR REPLACE FUNCTION " + NormalizeFunction + "(text) RETURNS text " +
+			"AS $$ SELECT public.unaccent('public.unaccent'::regdictionary, $1) $$ " +
+			"LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT",
+	}
+	for _, table := range suggestIndexes {
+		key := SuggestKey(SuggestTables[table])
+		statements = append(statements,
+			"CREATE INDEX idx_"+table+"_suggest_prefix ON "+table+" ("+key+" text_pattern_ops)",
+			"CREATE INDEX idx_"+table+"_suggest_trgm ON "+table+" USING gin ("+key+" gin_trgm_ops)",
+		)
+	}
+
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
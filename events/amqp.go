@@ -0,0 +1,149 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ErrClosed is returned when publishing to a closed publisher
+var ErrClosed = errors.New("Publisher is closed")
+
+// AMQPPublisher publishes events to a RabbitMQ topic exchange and waits for
+// the broker to confirm each of them
+type AMQPPublisher struct {
+	url            string
+	exchange       string
+	confirmTimeout time.Duration
+
+	mu       sync.Mutex
+	conn     *amqp.Connection
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+	closed   bool
+}
+
+// NewAMQPPublisher connects to url and declares exchange
+func NewAMQPPublisher(url, exchange string, confirmTimeout time.Duration) (*AMQPPublisher, error) {
+	p := &AMQPPublisher{url: url, exchange: exchange, confirmTimeout: confirmTimeout}
+	if err := p.connect(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// connect opens the connection and a confirming channel. The caller must
+// hold p.mu unless p is not shared yet.
+func (p *AMQPPublisher) connect() error {
+	conn, err := amqp.Dial(p.url)
+	if err != nil {
+		return fmt.Errorf("cannot connect to broker: %v", err)
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("cannot open broker channel: %v", err)
+	}
+	if err := channel.ExchangeDeclare(p.exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return fmt.Errorf("cannot declare exchange %s: %v", p.exchange, err)
+	}
+	if err := channel.Confirm(false); err != nil {
+		conn.Close()
+		return fmt.Errorf("broker does not support publisher confirms: %v", err)
+	}
+
+	p.conn = conn
+	p.channel = channel
+	p.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	return nil
+}
+
+// Publish sends event as a persistent message and blocks until the broker
+// acknowledges it. Publishes are serialized so each confirm matches its
+// message.
+func (p *AMQPPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	if p.conn == nil || p.conn.IsClosed() {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+
+	err = p.channel.Publish(p.exchange, event.RoutingKey(), false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    event.ID,
+		Timestamp:    event.OccurredAt,
+		Type:         event.Type,
+		Headers: amqp.Table{
+			"version":        int32(event.Version),
+			"aggregate_type": event.AggregateType,
+			"aggregate_id":   event.AggregateID,
+		},
+		Body: body,
+	})
+	if err != nil {
+		p.conn.Close()
+		return fmt.Errorf("cannot publish %s: %v", event.Type, err)
+	}
+
+	timer := time.NewTimer(p.confirmTimeout)
+	defer timer.Stop()
+	select {
+	case confirm, ok := <-p.confirms:
+		if !ok {
+			return fmt.Errorf("broker channel closed before confirming %s", event.ID)
+		}
+		if !confirm.Ack {
+			return fmt.Errorf("broker refused event %s", event.ID)
+		}
+		return nil
+	case <-timer.C:
+		// the confirm may still arrive; drop the channel so it can't be
+		// matched to the next message
+		p.conn.Close()
+		return fmt.Errorf("broker did not confirm event %s within %s", event.ID, p.confirmTimeout)
+	case <-ctx.Done():
+		p.conn.Close()
+		return ctx.Err()
+	}
+}
+
+// Ping reports whether the broker connection is open
+func (p *AMQPPublisher) Ping(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	if p.conn == nil || p.conn.IsClosed() {
+		return p.connect()
+	}
+	return nil
+}
+
+// Close closes the broker connection
+func (p *AMQPPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.conn != nil && !p.conn.IsClosed() {
+		return p.conn.Close()
+	}
+	return nil
+}
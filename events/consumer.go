@@ -0,0 +1,219 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// retriesHeader counts the failed attempts of a consumed message
+const retriesHeader = "x-retries"
+
+// Message models a consumed message
+type Message struct {
+	ID      string
+	Body    []byte
+	Retries int
+}
+
+// Handler processes a consumed message. Errors wrapped with Permanent send
+// the message straight to the dead letter queue, other errors are retried.
+type Handler func(ctx context.Context, message Message) error
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, e.g. a malformed message
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent tells whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// Disposition is what happens to a message once handled
+type Disposition int
+
+// message dispositions
+const (
+	Acknowledge Disposition = iota
+	Retry
+	DeadLetter
+)
+
+// Dispose decides the disposition of a message handled with err after
+// retries failed attempts
+func Dispose(err error, retries, maxRetries int) Disposition {
+	switch {
+	case err == nil:
+		return Acknowledge
+	case IsPermanent(err) || retries >= maxRetries:
+		return DeadLetter
+	}
+	return Retry
+}
+
+// RetryDelays returns the distinct waits before the retries of a message,
+// shortest first: the backoff of each retry up to maxRetries
+func RetryDelays(maxRetries int, maxBackoff time.Duration) []time.Duration {
+	delays := []time.Duration{}
+	for retry := 1; retry <= maxRetries; retry++ {
+		wait := Backoff(retry, maxBackoff)
+		if len(delays) > 0 && delays[len(delays)-1] == wait {
+			continue
+		}
+		delays = append(delays, wait)
+	}
+	return delays
+}
+
+// RetryQueue returns the name of the retry queue of queue holding messages
+// for wait
+func RetryQueue(queue string, wait time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queue, wait)
+}
+
+// AMQPConsumer consumes a queue one message at a time. Failed messages wait
+// in the retry queue of their delay, whose messages all expire after it
+// and return to the main queue, and end up in a dead letter queue once
+// retries are exhausted. A queue per delay keeps a long wait from holding
+// back shorter ones, as RabbitMQ only expires messages at the queue head.
+type AMQPConsumer struct {
+	url        string
+	queue      string
+	handler    Handler
+	maxRetries int
+	maxBackoff time.Duration
+}
+
+// NewAMQPConsumer returns a consumer of queue
+func NewAMQPConsumer(url, queue string, handler Handler, maxRetries int, maxBackoff time.Duration) *AMQPConsumer {
+	return &AMQPConsumer{url: url, queue: queue, handler: handler, maxRetries: maxRetries, maxBackoff: maxBackoff}
+}
+
+// Run consumes until ctx is cancelled, reconnecting when the connection
+// drops. The message being handled when ctx is cancelled is finished first.
+func (c *AMQPConsumer) Run(ctx context.Context) error {
+	attempts := 0
+	for {
+		err := c.consume(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		attempts++
+		wait := Backoff(attempts, c.maxBackoff)
+		log.Printf("Consumer of %s disconnected, reconnecting in %s: %v", c.queue, wait, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *AMQPConsumer) consume(ctx context.Context) error {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return fmt.Errorf("cannot connect to broker: %v", err)
+	}
+	defer conn.Close()
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("cannot open broker channel: %v", err)
+	}
+	if err := c.declare(channel); err != nil {
+		return err
+	}
+	if err := channel.Qos(1, 0, false); err != nil {
+		return err
+	}
+	deliveries, err := channel.Consume(c.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("cannot consume %s: %v", c.queue, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return errors.New("delivery channel closed")
+			}
+			if err := c.handle(channel, delivery); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// declare declares the queue with its retry and dead letter queues
+func (c *AMQPConsumer) declare(channel *amqp.Channel) error {
+	type queue struct {
+		name string
+		args amqp.Table
+	}
+	queues := []queue{{name: c.queue}, {name: c.queue + ".dlq"}}
+	for _, wait := range RetryDelays(c.maxRetries, c.maxBackoff) {
+		queues = append(queues, queue{name: RetryQueue(c.queue, wait), args: amqp.Table{
+			"x-message-ttl":             wait.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": c.queue,
+		}})
+	}
+	for _, q := range queues {
+		if _, err := channel.QueueDeclare(q.name, true, false, false, false, q.args); err != nil {
+			return fmt.Errorf("cannot declare queue %s: %v", q.name, err)
+		}
+	}
+	return nil
+}
+
+func (c *AMQPConsumer) handle(channel *amqp.Channel, delivery amqp.Delivery) error {
+	retries := 0
+	if n, ok := delivery.Headers[retriesHeader].(int32); ok {
+		retries = int(n)
+	}
+
+	// the handler gets a context of its own, so stopping the consumer lets
+	// the current message finish
+	err := c.handler(context.Background(), Message{ID: delivery.MessageId, Body: delivery.Body, Retries: retries})
+
+	publishing := amqp.Publishing{
+		ContentType:  delivery.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    delivery.MessageId,
+		Type:         delivery.Type,
+		Headers:      amqp.Table{retriesHeader: int32(retries + 1)},
+		Body:         delivery.Body,
+	}
+	switch Dispose(err, retries, c.maxRetries) {
+	case Retry:
+		wait := Backoff(retries+1, c.maxBackoff)
+		log.Printf("Error handling message %s from %s, retrying in %s: %v", delivery.MessageId, c.queue, wait, err)
+		if err := channel.Publish("", RetryQueue(c.queue, wait), false, false, publishing); err != nil {
+			return err
+		}
+	case DeadLetter:
+		log.Printf("Moving message %s from %s to the dead letter queue: %v", delivery.MessageId, c.queue, err)
+		publishing.Headers["x-error"] = err.Error()
+		if err := channel.Publish("", c.queue+".dlq", false, false, publishing); err != nil {
+			return err
+		}
+	}
+	return delivery.Ack(false)
+}
@@ -0,0 +1,49 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// events emitted by the video encoder
+const (
+	EncodingFinished = "encoding.finished"
+	EncodingFailed   = "encoding.failed"
+)
+
+// EncoderResult models an encoding finished or failed message
+type EncoderResult struct {
+	Event       string `json:"event"`
+	VideoFileID string `json:"video_file_id"`
+	OutputPath  string `json:"output_path"`
+	Error       string `json:"error"`
+}
+
+// ParseEncoderResult decodes and validates an encoder message. Every
+// error is permanent, retrying a malformed message can't fix it.
+func ParseEncoderResult(body []byte) (EncoderResult, error) {
+	result := EncoderResult{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return result, Permanent(fmt.Errorf("malformed encoder message: %v", err))
+	}
+	if _, err := uuid.FromString(result.VideoFileID); err != nil {
+		return result, Permanent(errors.New("malformed encoder message: video_file_id must be an uuid"))
+	}
+
+	switch result.Event {
+	case EncodingFinished:
+		if result.OutputPath == "" {
+			return result, Permanent(errors.New("malformed encoder message: output_path is required"))
+		}
+	case EncodingFailed:
+		if result.Error == "" {
+			result.Error = "encoding failed"
+		}
+	default:
+		return result, Permanent(fmt.Errorf("malformed encoder message: unknown event %q", result.Event))
+	}
+	return result, nil
+}
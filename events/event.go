@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// PayloadVersion is the schema version of the event payloads published by
+// this service. It changes whenever Data changes in a backward incompatible
+// way.
+const PayloadVersion = 1
+
+// Event models a catalog domain event such as category.created
+type Event struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Actor         string          `json:"actor,omitempty"`
+	RequestID     string          `json:"request_id,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// RoutingKey returns the key the event is routed by, entity.action
+func (e Event) RoutingKey() string {
+	return e.Type
+}
+
+// Publisher models a destination of domain events
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
+
+// Pinger is implemented by publishers able to report their connection
+// health
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// EventType returns the event type of an action over an entity, e.g.
+// ("category", "create") gives category.created
+func EventType(entityType, action string) string {
+	past := map[string]string{
+		"create":   "created",
+		"update":   "updated",
+		"delete":   "deleted",
+		"restore":  "restored",
+		"rollback": "updated",
+	}
+	if p, ok := past[action]; ok {
+		action = p
+	}
+	return entityType + "." + action
+}
+
+// MatchRoutingKey tells whether key matches an AMQP topic pattern, where *
+// matches one word and # matches zero or more words
+func MatchRoutingKey(pattern, key string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(key, "."))
+}
+
+func matchWords(pattern, key []string) bool {
+	if len(pattern) == 0 {
+		return len(key) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(key); i++ {
+			if matchWords(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(key) > 0 && matchWords(pattern[1:], key[1:])
+	}
+	return len(key) > 0 && pattern[0] == key[0] && matchWords(pattern[1:], key[1:])
+}
@@ -0,0 +1,29 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"video-catalog/config"
+)
+
+// NopPublisher discards every event
+type NopPublisher struct{}
+
+// Publish discards event
+func (NopPublisher) Publish(ctx context.Context, event Event) error { return nil }
+
+// Close does nothing
+func (NopPublisher) Close() error { return nil }
+
+// New returns the publisher selected by the configuration
+func New(cfg config.BrokerConfig) (Publisher, error) {
+	switch cfg.Driver {
+	case "none":
+		return NopPublisher{}, nil
+	case "memory":
+		return NewInMemoryBroker(), nil
+	case "amqp":
+		return NewAMQPPublisher(cfg.URL, cfg.Exchange, cfg.ConfirmTimeout)
+	}
+	return nil, fmt.Errorf("unknown broker driver %q", cfg.Driver)
+}
@@ -0,0 +1,88 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// InMemoryBroker is an in-process stand-in for the message broker, used by
+// tests and local runs without RabbitMQ. It routes events to subscribers
+// with the same topic patterns as the AMQP exchange.
+type InMemoryBroker struct {
+	mu          sync.Mutex
+	published   []Event
+	subscribers []*subscription
+	closed      bool
+}
+
+type subscription struct {
+	pattern string
+	ch      chan Event
+}
+
+// NewInMemoryBroker returns an empty broker
+func NewInMemoryBroker() *InMemoryBroker {
+	return &InMemoryBroker{}
+}
+
+// Publish records event and delivers it to the matching subscribers. A full
+// subscriber buffer drops the event for that subscriber.
+func (b *InMemoryBroker) Publish(ctx context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	b.published = append(b.published, event)
+	for _, s := range b.subscribers {
+		if MatchRoutingKey(s.pattern, event.RoutingKey()) {
+			select {
+			case s.ch <- event:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving the events whose routing key
+// matches pattern
+func (b *InMemoryBroker) Subscribe(pattern string, buffer int) <-chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := &subscription{pattern: pattern, ch: make(chan Event, buffer)}
+	b.subscribers = append(b.subscribers, s)
+	return s.ch
+}
+
+// Published returns every event published so far
+func (b *InMemoryBroker) Published() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Event{}, b.published...)
+}
+
+// Ping reports whether the broker is open
+func (b *InMemoryBroker) Ping(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Close closes every subscription
+func (b *InMemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		for _, s := range b.subscribers {
+			close(s.ch)
+		}
+	}
+	return nil
+}
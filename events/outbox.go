@@ -0,0 +1,229 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+	"video-catalog/config"
+	"video-catalog/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// relayLockID is the postgres advisory lock held while claiming a batch,
+// so replicas claim one after the other and per aggregate order is kept
+const relayLockID = 4242001
+
+// NewOutboxMessage returns the outbox row storing event
+func NewOutboxMessage(event Event) (models.OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return models.OutboxMessage{}, err
+	}
+	return models.OutboxMessage{
+		EventID:       event.ID,
+		EventType:     event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Payload:       models.JSON(payload),
+		NextAttemptAt: event.OccurredAt,
+		CreatedAt:     event.OccurredAt,
+	}, nil
+}
+
+// Backoff returns the wait before retrying a message that failed attempts
+// times: 1s, 2s, 4s... up to max
+func Backoff(attempts int, max time.Duration) time.Duration {
+	wait := time.Second
+	for i := 1; i < attempts && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		return max
+	}
+	return wait
+}
+
+// RelayMetrics counts the work done by a relay since it started
+type RelayMetrics struct {
+	Published      uint64
+	FailedAttempts uint64
+	LastLag        time.Duration
+}
+
+// Relay publishes outbox messages. Messages of an aggregate are published
+// in the order they were written: a failed message holds back the later
+// ones of its aggregate until it is retried. Delivery is at least once, a
+// message published right before a crash is published again.
+type Relay struct {
+	db        *gorm.DB
+	publisher Publisher
+	cfg       config.OutboxConfig
+
+	mu      sync.Mutex
+	metrics RelayMetrics
+}
+
+// NewRelay returns a relay from db to publisher
+func NewRelay(db *gorm.DB, publisher Publisher, cfg config.OutboxConfig) *Relay {
+	return &Relay{db: db, publisher: publisher, cfg: cfg}
+}
+
+// Run relays messages every poll interval until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Dispatch(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error relaying outbox: %v", err)
+		}
+		outbox := models.OutboxMessage{}
+		if _, err := outbox.DeletePublished(r.db, time.Now().Add(-r.cfg.Retention)); err != nil {
+			log.Printf("Error pruning outbox: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch publishes one batch of due messages and returns how many were
+// published. The batch is claimed in a short transaction and published
+// outside of it; the claim keeps other relays off the batch until
+// ClaimTimeout, after which unacknowledged messages are due again.
+func (r *Relay) Dispatch(ctx context.Context) (int, error) {
+	messages, err := r.claim()
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	blocked := map[string]bool{}
+	for i := range *messages {
+		message := &(*messages)[i]
+		aggregate := message.AggregateType + ":" + message.AggregateID
+		if blocked[aggregate] || ctx.Err() != nil {
+			if err := message.Release(r.db, time.Now()); err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		if err := r.publish(ctx, message); err != nil {
+			log.Printf("Error publishing outbox event %s %s (attempt %d): %v", message.EventType, message.EventID, message.Attempts+1, err)
+			blocked[aggregate] = true
+			next := time.Now().Add(Backoff(message.Attempts+1, r.cfg.MaxBackoff))
+			if err := message.MarkFailed(r.db, err, next); err != nil {
+				return published, err
+			}
+			r.record(false, 0)
+			continue
+		}
+
+		now := time.Now()
+		if err := message.MarkPublished(r.db, now); err != nil {
+			return published, err
+		}
+		r.record(true, now.Sub(message.CreatedAt))
+		published++
+	}
+
+	return published, nil
+}
+
+// claim takes the next batch of due messages, nothing while another relay
+// is claiming
+func (r *Relay) claim() (*[]models.OutboxMessage, error) {
+	messages := &[]models.OutboxMessage{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if r.db.Dialect().GetName() == "postgres" {
+			lock := struct{ Locked bool }{}
+			if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?) AS locked", relayLockID).Scan(&lock).Error; err != nil {
+				return err
+			}
+			if !lock.Locked {
+				return nil
+			}
+		}
+
+		now := time.Now()
+		outbox := models.OutboxMessage{}
+		claimed, err := outbox.ClaimDue(tx, r.cfg.BatchSize, now, now.Add(r.cfg.ClaimTimeout))
+		if err != nil {
+			return err
+		}
+		messages = claimed
+		return nil
+	})
+	return messages, err
+}
+
+func (r *Relay) publish(ctx context.Context, message *models.OutboxMessage) error {
+	event := Event{}
+	if err := json.Unmarshal(message.Payload, &event); err != nil {
+		return err
+	}
+	return r.publisher.Publish(ctx, event)
+}
+
+func (r *Relay) record(published bool, lag time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if published {
+		r.metrics.Published++
+		r.metrics.LastLag = lag
+	} else {
+		r.metrics.FailedAttempts++
+	}
+}
+
+// Metrics returns the relay counters
+func (r *Relay) Metrics() RelayMetrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.metrics
+}
+
+// gapTimeout is how long a hole in the outbox sequence is waited for.
+// Sequences are taken when a row is inserted, so a lower one may still be
+// committed by a running transaction; a rolled back one never is.
+const gapTimeout = 5 * time.Second
+
+// OutboxTail reads the outbox in sequence order, following Last. A hole in
+// the sequence stops the reading until it is filled or gapTimeout passed.
+type OutboxTail struct {
+	Last     uint64
+	gapSince time.Time
+}
+
+// Next returns up to limit messages following Last and moves Last past
+// them
+func (t *OutboxTail) Next(db *gorm.DB, limit int) ([]models.OutboxMessage, error) {
+	outbox := models.OutboxMessage{}
+	messages, err := outbox.FindAfter(db, t.Last, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ready := []models.OutboxMessage{}
+	for _, m := range *messages {
+		if t.Last != 0 && m.Sequence != t.Last+1 {
+			if t.gapSince.IsZero() {
+				t.gapSince = time.Now()
+			}
+			if time.Since(t.gapSince) < gapTimeout {
+				break
+			}
+		}
+		t.gapSince = time.Time{}
+		ready = append(ready, m)
+		t.Last = m.Sequence
+	}
+	return ready, nil
+}
@@ -0,0 +1,212 @@
+// Package i18n negotiates the locale of responses among the locales the
+// catalog is translated to
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedLocale is returned when a requested locale isn't served
+var ErrUnsupportedLocale = errors.New("Locale is not supported")
+
+// Locales holds the locales served by the catalog. Stored texts are in the
+// default locale; the others are translations, each falling back to
+// another locale, and ultimately to the default one.
+type Locales struct {
+	Default   string
+	supported []string
+	fallbacks map[string]string
+}
+
+// Parse builds the locales from their configuration: the default locale, a
+// comma separated list of the others and comma separated locale:fallback
+// pairs
+func Parse(defaultLocale, locales, fallbacks string) (*Locales, error) {
+	l := &Locales{Default: Canonical(defaultLocale), fallbacks: map[string]string{}}
+	if !valid(l.Default) {
+		return nil, fmt.Errorf("invalid default locale %q", defaultLocale)
+	}
+	l.supported = []string{l.Default}
+	for _, value := range strings.Split(locales, ",") {
+		locale := Canonical(value)
+		if locale == "" || l.Supports(locale) {
+			continue
+		}
+		if !valid(locale) {
+			return nil, fmt.Errorf("invalid locale %q", value)
+		}
+		l.supported = append(l.supported, locale)
+	}
+
+	for _, pair := range strings.Split(fallbacks, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("fallback %q must be locale:fallback", pair)
+		}
+		from, to := Canonical(parts[0]), Canonical(parts[1])
+		if !l.Supports(from) || !l.Supports(to) {
+			return nil, fmt.Errorf("fallback %q must join supported locales", pair)
+		}
+		if from == l.Default {
+			return nil, fmt.Errorf("the default locale %s can't fall back", from)
+		}
+		l.fallbacks[from] = to
+	}
+	for _, locale := range l.supported {
+		seen := map[string]bool{}
+		for next, ok := locale, true; ok; next, ok = l.fallbacks[next] {
+			if seen[next] {
+				return nil, fmt.Errorf("fallbacks of %s form a cycle", locale)
+			}
+			seen[next] = true
+		}
+	}
+	return l, nil
+}
+
+// Supported returns the served locales, the default one first
+func (l *Locales) Supported() []string {
+	return append([]string{}, l.supported...)
+}
+
+// Translated returns the served locales other than the default one
+func (l *Locales) Translated() []string {
+	return append([]string{}, l.supported[1:]...)
+}
+
+// Supports tells whether locale is served
+func (l *Locales) Supports(locale string) bool {
+	for _, s := range l.supported {
+		if s == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Chain returns the translations to look for when localizing to locale, in
+// order: the locale itself, then its fallbacks, up to the default locale,
+// which is the stored text and is not listed
+func (l *Locales) Chain(locale string) []string {
+	chain := []string{}
+	seen := map[string]bool{}
+	for locale != l.Default && !seen[locale] {
+		seen[locale] = true
+		chain = append(chain, locale)
+		next, ok := l.fallbacks[locale]
+		if !ok {
+			break
+		}
+		locale = next
+	}
+	return chain
+}
+
+// Match returns the served locale closest to tag: the same one, else one
+// of the same language
+func (l *Locales) Match(tag string) (string, bool) {
+	tag = Canonical(tag)
+	if l.Supports(tag) {
+		return tag, true
+	}
+	language := strings.SplitN(tag, "-", 2)[0]
+	for _, s := range l.supported {
+		if strings.SplitN(s, "-", 2)[0] == language {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// Negotiate returns the locale of a response. An explicit locale must be
+// served; otherwise the preferred served language of the Accept-Language
+// header is picked, or the default locale.
+func (l *Locales) Negotiate(explicit, acceptLanguage string) (string, error) {
+	if strings.TrimSpace(explicit) != "" {
+		if locale := Canonical(explicit); l.Supports(locale) {
+			return locale, nil
+		}
+		return "", ErrUnsupportedLocale
+	}
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if locale, ok := l.Match(tag); ok {
+			return locale, nil
+		}
+	}
+	return l.Default, nil
+}
+
+// parseAcceptLanguage returns the tags of an Accept-Language header by
+// decreasing weight, dropping the refused ones and the wildcard
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag    string
+		weight float64
+	}
+	tags := []weighted{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					weight = q
+				}
+			}
+		}
+		if weight > 0 {
+			tags = append(tags, weighted{tag, weight})
+		}
+	}
+	sort.SliceStable(tags, func(a, b int) bool { return tags[a].weight > tags[b].weight })
+
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		result = append(result, t.tag)
+	}
+	return result
+}
+
+// Canonical writes a locale tag as language-REGION, e.g. pt-br as pt-BR
+func Canonical(tag string) string {
+	parts := strings.Split(strings.Replace(strings.TrimSpace(tag), "_", "-", -1), "-")
+	parts[0] = strings.ToLower(parts[0])
+	for n := 1; n < len(parts); n++ {
+		if len(parts[n]) == 2 {
+			parts[n] = strings.ToUpper(parts[n])
+		} else {
+			parts[n] = strings.ToLower(parts[n])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// valid tells whether tag looks like a language tag
+func valid(tag string) bool {
+	if tag == "" || len(tag) > 35 {
+		return false
+	}
+	for n, part := range strings.Split(tag, "-") {
+		if part == "" || len(part) > 8 || (n == 0 && (len(part) < 2 || len(part) > 3)) {
+			return false
+		}
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -1,7 +1,10 @@
 package main
 
-import "video-catalog/api"
+import (
+	"os"
+	"video-catalog/cmd"
+)
 
 func main() {
-	api.Run()
+	os.Exit(cmd.Execute(os.Args[1:]))
 }
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// APIKeyPrefix starts every generated api key
+const APIKeyPrefix = "cfk_"
+
+// APIKey models a service-to-service credential. Only the hash of the key
+// is stored.
+type APIKey struct {
+	ID         string     `json:"id" gorm:"type:uuid;primary_key"`
+	Name       string     `json:"name" gorm:"type:varchar(255);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16);not null"`
+	Hash       string     `json:"-" gorm:"type:varchar(64);unique_index;not null"`
+	Scopes     StringList `json:"scopes" gorm:"type:text"`
+	CreatedBy  string     `json:"created_by" gorm:"type:varchar(255)"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  *time.Time `json:"created_at,omitempty" gorm:"autoCreateTime"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty" gorm:"autoUpdateTime"`
+}
+
+// Prepare prepares values
+func (k *APIKey) Prepare() {
+	k.Name = html.EscapeString(strings.TrimSpace(k.Name))
+}
+
+// Validate validates basic struct
+func (k *APIKey) Validate() error {
+	if len(k.Name) < 3 || len(k.Name) > 255 {
+		return errors.New("Api key name must be between 3 and 255 characters")
+	}
+	if len(k.Scopes) == 0 {
+		return errors.New("Api key must have at least one scope")
+	}
+	if k.ExpiresAt != nil && k.ExpiresAt.Before(time.Now()) {
+		return errors.New("Api key expiration must be in the future")
+	}
+	return nil
+}
+
+// Generate sets a new random key on k and returns it in plain text. The
+// plain text key can't be recovered afterwards.
+func (k *APIKey) Generate() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	k.Prefix = key[:12]
+	k.Hash = HashAPIKey(key)
+	return key, nil
+}
+
+// HashAPIKey returns the stored form of a plain text key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsActive tells whether the key can be used at t
+func (k *APIKey) IsActive(t time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(t))
+}
+
+// Create creates a new api key
+func (k *APIKey) Create(db *gorm.DB) (*APIKey, error) {
+	if err := db.Create(&k).Error; err != nil {
+		return &APIKey{}, err
+	}
+
+	return k, nil
+}
+
+// FindAll returns all api keys in db
+func (k *APIKey) FindAll(db *gorm.DB) (*[]APIKey, error) {
+	keys := []APIKey{}
+
+	if err := db.Model(&APIKey{}).Order("created_at").Find(&keys).Error; err != nil {
+		return &[]APIKey{}, err
+	}
+
+	return &keys, nil
+}
+
+// FindByID searchs an api key by id
+func (k *APIKey) FindByID(db *gorm.DB) error {
+	err := db.Take(&k).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return errors.New("Api key not found")
+	}
+	return err
+}
+
+// FindByKey searchs an active api key by its plain text value
+func (k *APIKey) FindByKey(db *gorm.DB, key string) error {
+	err := db.Where("hash = ?", HashAPIKey(key)).Take(&k).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return errors.New("Api key not found")
+	}
+	if err != nil {
+		return err
+	}
+	if !k.IsActive(time.Now()) {
+		return errors.New("Api key is revoked or expired")
+	}
+	return nil
+}
+
+// Revoke revokes an api key by id
+func (k *APIKey) Revoke(db *gorm.DB) error {
+	now := time.Now()
+	req := db.Model(&k).Where("revoked_at IS NULL").Update("revoked_at", now)
+	if req.Error != nil {
+		return errors.New("Internal server error")
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("Api key not found")
+	}
+	k.RevokedAt = &now
+	return nil
+}
+
+// Touch records the key was used at t. Writes are throttled to one per
+// minute per key.
+func (k *APIKey) Touch(db *gorm.DB, t time.Time) error {
+	if k.LastUsedAt != nil && t.Sub(*k.LastUsedAt) < time.Minute {
+		return nil
+	}
+	k.LastUsedAt = &t
+	return db.Model(&APIKey{}).Where("id = ?", k.ID).UpdateColumn("last_used_at", t).Error
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// audit actions
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+// AuditEntry models a recorded catalog mutation
+type AuditEntry struct {
+	ID         string    `json:"id" gorm:"type:uuid;primary_key"`
+	Actor      string    `json:"actor" gorm:"type:varchar(255);index"`
+	EntityType string    `json:"entity_type" gorm:"type:varchar(64);index:idx_audit_entity"`
+	EntityID   string    `json:"entity_id" gorm:"type:varchar(64);index:idx_audit_entity"`
+	Action     string    `json:"action" gorm:"type:varchar(32)"`
+	RequestID  string    `json:"request_id" gorm:"type:varchar(64)"`
+	Before     JSON      `json:"before" gorm:"type:jsonb"`
+	After      JSON      `json:"after" gorm:"type:jsonb"`
+	Diff       JSON      `json:"diff" gorm:"type:jsonb"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// AuditFilter models the audit log search parameters. Empty fields don't
+// filter.
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	Actor      string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+// Create creates a new audit entry
+func (a *AuditEntry) Create(db *gorm.DB) (*AuditEntry, error) {
+	if err := db.Create(&a).Error; err != nil {
+		return &AuditEntry{}, err
+	}
+
+	return a, nil
+}
+
+// FindAll returns the audit entries matching filter, newest first
+func (a *AuditEntry) FindAll(db *gorm.DB, filter AuditFilter) (*[]AuditEntry, error) {
+	entries := []AuditEntry{}
+
+	query := db.Model(&AuditEntry{})
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	err := query.Order("created_at desc").Limit(filter.Limit).Offset(filter.Offset).Find(&entries).Error
+	if err != nil {
+		return &[]AuditEntry{}, err
+	}
+
+	return &entries, nil
+}
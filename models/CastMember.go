@@ -21,6 +21,7 @@ const TypeActor int = 2
 type CastMember struct {
 	ID        string     `json:"id" valid:"uuid" gorm:"type:uuid;primary_key"`
 	Name      string     `json:"name" valid:"type(string),required~CastMember name is required,stringlength(3|255)~Category name must be between 3 and 255 characters" gorm:"varchar(255);unique"`
+	Slug      string     `json:"slug" valid:"-" gorm:"type:varchar(255);not null;default:''"`
 	Type      int        `json:"type" valid:"type(int),required~CastMember type is required,range(1|2)~Value must be 1 or 2"`
 	CreatedAt *time.Time `json:"created_at,omitempty" valid:"-" gorm:"autoCreateTime"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty" valid:"-" gorm:"autoUpdateTime"`
@@ -66,6 +67,10 @@ func (c *CastMember) Prepare() {
 
 // Create creates a new cast member
 func (c *CastMember) Create(db *gorm.DB) (*CastMember, error) {
+	var err error
+	if c.Slug, err = NewSlug(db, "cast_member", c.ID, c.Name); err != nil {
+		return &CastMember{}, err
+	}
 	if err := db.Create(&c).Error; err != nil {
 		return &CastMember{}, err
 	}
@@ -100,8 +105,9 @@ func (c *CastMember) FindByID(db *gorm.DB) error {
 	return err
 }
 
-// Update updates a CastMember by id
+// Update updates a CastMember by id, its slug following the name
 func (c *CastMember) Update(db *gorm.DB) (*CastMember, error) {
+	c.Slug = ""
 	req := db.Model(&c).Updates(&c).Find(&c)
 	if req.Error != nil {
 		return &CastMember{}, errors.New("Internal server error")
@@ -109,6 +115,11 @@ func (c *CastMember) Update(db *gorm.DB) (*CastMember, error) {
 	if req.RowsAffected == 0 {
 		return &CastMember{}, errors.New("CastMember not found")
 	}
+	slug, err := RefreshSlug(db, "cast_member", c.ID)
+	if err != nil {
+		return &CastMember{}, err
+	}
+	c.Slug = slug
 
 	return c, nil
 }
@@ -125,3 +136,16 @@ func (c *CastMember) Delete(db *gorm.DB) error {
 	}
 	return nil
 }
+
+// Restore restores a soft deleted cast member by id
+func (c *CastMember) Restore(db *gorm.DB) error {
+	req := db.Unscoped().Model(&c).Where("deleted_at IS NOT NULL").Update("deleted_at", nil)
+	if req.Error != nil {
+		return errors.New("Internal server error")
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("CastMember not found")
+	}
+
+	return db.Take(&c).Error
+}
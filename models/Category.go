@@ -14,8 +14,11 @@ import (
 type Category struct {
 	ID          string     `json:"id" valid:"uuid" gorm:"type:uuid;primary_key"`
 	Name        string     `json:"name" valid:"type(string),required~Category name is required,stringlength(3|255)~Category name must be between 3 and 255 characters" gorm:"varchar(255);unique"`
+	Slug        string     `json:"slug" valid:"-" gorm:"type:varchar(255);not null;default:''"`
 	Description string     `json:"description" valid:"type(string),stringlength(3|255)~Category name must be between 3 and 255 characters,optional" gorm:"varchar(255)" gorm:"varchar(255)"`
 	IsActive    *bool      `json:"is_active" valid:"-" gorm:"bool;default:true"`
+	Position    int        `json:"position" valid:"-" gorm:"not null;default:0;index"`
+	ParentID    *string    `json:"parent_id" valid:"-" gorm:"type:uuid;index"`
 	CreatedAt   *time.Time `json:"created_at,omitempty" valid:"-" gorm:"autoCreateTime"`
 	UpdatedAt   *time.Time `json:"updated_at,omitempty" valid:"-" gorm:"autoUpdateTime"`
 	DeletedAt   *time.Time `json:"deleted_at,omitempty" valid:"-" gorm:"autoDeleteTime"`
@@ -30,7 +33,7 @@ func (c *Category) Validate() error {
 	if _, err := govalidator.ValidateStruct(c); err != nil {
 		return err
 	}
-	return nil
+	return ValidateParentID(c.ParentID)
 }
 
 // Prepare prepares values
@@ -39,9 +42,21 @@ func (c *Category) Prepare() {
 	c.Description = html.EscapeString(strings.TrimSpace(c.Description))
 }
 
-// Create creates a new category
+// Create creates a new category, placed after the others. Its position is
+// taken in the transaction inserting it.
 func (c *Category) Create(db *gorm.DB) (*Category, error) {
-	if err := db.Create(&c).Error; err != nil {
+	err := inTransaction(db, func(tx *gorm.DB) error {
+		position, err := nextPosition(tx, "categories")
+		if err != nil {
+			return err
+		}
+		c.Position = position
+		if c.Slug, err = NewSlug(tx, "category", c.ID, c.Name); err != nil {
+			return err
+		}
+		return tx.Create(&c).Error
+	})
+	if err != nil {
 		return &Category{}, err
 	}
 
@@ -52,7 +67,7 @@ func (c *Category) Create(db *gorm.DB) (*Category, error) {
 func (c *Category) FindAll(db *gorm.DB) (*[]Category, error) {
 	categories := []Category{}
 
-	if err := db.Model(&Category{}).Find(&categories).Error; err != nil {
+	if err := db.Model(&Category{}).Order("position, created_at").Find(&categories).Error; err != nil {
 		return &[]Category{}, err
 	}
 
@@ -75,8 +90,11 @@ func (c *Category) FindByID(db *gorm.DB) error {
 	return err
 }
 
-// Update updates a category by id
+// Update updates a category by id. Positions only change through Reorder,
+// slugs follow the name.
 func (c *Category) Update(db *gorm.DB) (*Category, error) {
+	c.Position = 0
+	c.Slug = ""
 	req := db.Model(&c).Updates(&c).Find(&c)
 	if req.Error != nil {
 		return &Category{}, errors.New("Internal server error")
@@ -84,6 +102,11 @@ func (c *Category) Update(db *gorm.DB) (*Category, error) {
 	if req.RowsAffected == 0 {
 		return &Category{}, errors.New("Category not found")
 	}
+	slug, err := RefreshSlug(db, "category", c.ID)
+	if err != nil {
+		return &Category{}, err
+	}
+	c.Slug = slug
 
 	return c, nil
 }
@@ -100,3 +123,22 @@ func (c *Category) Delete(db *gorm.DB) error {
 	}
 	return nil
 }
+
+// Restore restores a soft deleted category by id
+func (c *Category) Restore(db *gorm.DB) error {
+	req := db.Unscoped().Model(&c).Where("deleted_at IS NOT NULL").Update("deleted_at", nil)
+	if req.Error != nil {
+		return errors.New("Internal server error")
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("Category not found")
+	}
+
+	return db.Take(&c).Error
+}
+
+// Reorder places the categories listed by ids first, in that order, and returns
+// the ids of the ones that moved
+func (c *Category) Reorder(db *gorm.DB, ids []string) ([]string, error) {
+	return reorder(db, "categories", ids)
+}
@@ -0,0 +1,230 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/jinzhu/gorm"
+)
+
+// MaxCategoryDepth bounds the levels of the category tree, roots included
+const MaxCategoryDepth = 5
+
+// categoryTreeLockID serializes the changes of the category tree, so two
+// concurrent moves can't build a cycle together
+const categoryTreeLockID = 4242003
+
+// maxTreeWalk bounds the recursive queries, should a cycle exist anyway
+const maxTreeWalk = 64
+
+// category tree errors
+var (
+	ErrParentID            = errors.New("parent_id must be a category id or null")
+	ErrParentNotFound      = errors.New("Parent category not found")
+	ErrCategoryCycle       = errors.New("A category can't be moved under itself or its subcategories")
+	ErrCategoryTooDeep     = fmt.Errorf("Categories can't be nested more than %d levels deep", MaxCategoryDepth)
+	ErrCategoryHasChildren = errors.New("Category has subcategories, move or delete them first")
+	ErrParentDeleted       = errors.New("Parent category is deleted, restore it first")
+)
+
+// IsCategoryTreeError tells whether err refuses a change of the tree
+func IsCategoryTreeError(err error) bool {
+	switch err {
+	case ErrParentNotFound, ErrCategoryCycle, ErrCategoryTooDeep, ErrCategoryHasChildren, ErrParentDeleted:
+		return true
+	}
+	return false
+}
+
+// ValidateParentID refuses a parent id that isn't a category id, before it
+// reaches the uuid column. A nil parentID is a root.
+func ValidateParentID(parentID *string) error {
+	if parentID != nil && !govalidator.IsUUID(*parentID) {
+		return ErrParentID
+	}
+	return nil
+}
+
+// CategoryNode models a category with its subcategories
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// LockCategoryTree holds the tree lock until the transaction db ends
+func LockCategoryTree(db *gorm.DB) error {
+	return db.Exec("SELECT pg_advisory_xact_lock(?)", categoryTreeLockID).Error
+}
+
+// Ancestors returns the live ancestors of the category, root first
+func (c *Category) Ancestors(db *gorm.DB) ([]Category, error) {
+	ancestors := []Category{}
+	err := db.Raw(`WITH RECURSIVE ancestors AS (
+		SELECT id, parent_id, 0 AS level FROM categories WHERE id = ?
+		UNION ALL
+		SELECT c.id, c.parent_id, a.level + 1 FROM categories c JOIN ancestors a ON c.id = a.parent_id WHERE a.level < ?
+	)
+	SELECT categories.* FROM categories JOIN ancestors ON categories.id = ancestors.id
+	WHERE ancestors.level > 0 AND categories.deleted_at IS NULL
+	ORDER BY ancestors.level DESC`, c.ID, maxTreeWalk).Scan(&ancestors).Error
+	return ancestors, err
+}
+
+// Descendants returns the live subcategories of the category at every
+// level, with their depth below it
+func (c *Category) Descendants(db *gorm.DB) ([]Category, map[string]int, error) {
+	rows := []struct {
+		Category
+		Level int
+	}{}
+	err := db.Raw(`WITH RECURSIVE tree AS (
+		SELECT id, 0 AS level FROM categories WHERE id = ? AND deleted_at IS NULL
+		UNION ALL
+		SELECT c.id, t.level + 1 FROM categories c JOIN tree t ON c.parent_id = t.id WHERE c.deleted_at IS NULL AND t.level < ?
+	)
+	SELECT categories.*, tree.level FROM categories JOIN tree ON categories.id = tree.id
+	WHERE tree.level > 0
+	ORDER BY tree.level, categories.position, categories.name`, c.ID, maxTreeWalk).Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	descendants := []Category{}
+	levels := map[string]int{}
+	for _, row := range rows {
+		descendants = append(descendants, row.Category)
+		levels[row.ID] = row.Level
+	}
+	return descendants, levels, nil
+}
+
+// CategoryDescendantsSQL selects the ids of the live categories given as
+// its argument and of all their subcategories
+var CategoryDescendantsSQL = fmt.Sprintf(`WITH RECURSIVE tree AS (
+	SELECT id, 0 AS level FROM categories WHERE id IN (?) AND deleted_at IS NULL
+	UNION ALL
+	SELECT c.id, t.level + 1 FROM categories c JOIN tree t ON c.parent_id = t.id WHERE c.deleted_at IS NULL AND t.level < %d
+) SELECT id FROM tree`, maxTreeWalk)
+
+// ValidateParent checks the category may be placed under parentID: the
+// parent exists, is not the category or one of its subcategories, and the
+// tree stays within MaxCategoryDepth. A nil parentID makes it a root.
+func (c *Category) ValidateParent(db *gorm.DB, parentID *string) error {
+	height := 1
+	if c.ID != "" {
+		_, levels, err := c.Descendants(db)
+		if err != nil {
+			return err
+		}
+		for _, level := range levels {
+			if level+1 > height {
+				height = level + 1
+			}
+		}
+	}
+	if parentID == nil {
+		if height > MaxCategoryDepth {
+			return ErrCategoryTooDeep
+		}
+		return nil
+	}
+
+	parent := Category{}
+	if err := db.Where("id = ?", *parentID).Take(&parent).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrParentNotFound
+		}
+		return err
+	}
+	if parent.ID == c.ID {
+		return ErrCategoryCycle
+	}
+	ancestors, err := parent.Ancestors(db)
+	if err != nil {
+		return err
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.ID == c.ID {
+			return ErrCategoryCycle
+		}
+	}
+	if len(ancestors)+1+height > MaxCategoryDepth {
+		return ErrCategoryTooDeep
+	}
+	return nil
+}
+
+// Move places the category under parentID, or at the root when nil
+func (c *Category) Move(db *gorm.DB, parentID *string) error {
+	if err := c.ValidateParent(db, parentID); err != nil {
+		return err
+	}
+	req := db.Model(&Category{}).Where("id = ?", c.ID).Update("parent_id", parentID)
+	if req.Error != nil {
+		return req.Error
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("Category not found")
+	}
+	return db.Take(c).Error
+}
+
+// HasChildren tells whether live subcategories are placed under the
+// category
+func (c *Category) HasChildren(db *gorm.DB) (bool, error) {
+	count := 0
+	err := db.Model(&Category{}).Where("parent_id = ?", c.ID).Count(&count).Error
+	return count > 0, err
+}
+
+// FindTree returns every live category arranged as a forest, siblings by
+// position
+func (c *Category) FindTree(db *gorm.DB) ([]*CategoryNode, error) {
+	categories := []Category{}
+	if err := db.Order("position, name").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return BuildCategoryTree(categories), nil
+}
+
+// FindSubtree returns the category with its subcategories
+func (c *Category) FindSubtree(db *gorm.DB) (*CategoryNode, error) {
+	if err := db.Take(c).Error; err != nil {
+		return nil, err
+	}
+	descendants, _, err := c.Descendants(db)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := append([]Category{*c}, descendants...)
+	for _, node := range BuildCategoryTree(categories) {
+		if node.ID == c.ID {
+			return node, nil
+		}
+	}
+	return &CategoryNode{Category: *c, Children: []*CategoryNode{}}, nil
+}
+
+// BuildCategoryTree arranges categories by parent, keeping their order
+// among siblings. Categories whose parent is missing are roots.
+func BuildCategoryTree(categories []Category) []*CategoryNode {
+	nodes := map[string]*CategoryNode{}
+	for i := range categories {
+		nodes[categories[i].ID] = &CategoryNode{Category: categories[i], Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	for i := range categories {
+		node := nodes[categories[i].ID]
+		if node.ParentID != nil {
+			if parent, ok := nodes[*node.ParentID]; ok && parent != node {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
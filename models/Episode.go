@@ -0,0 +1,178 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// episode errors
+var (
+	ErrEpisodeIncomplete = errors.New("Episodes need both a season_id and an episode_number")
+	ErrEpisodeNumber     = errors.New("Episode number must be between 1 and 9999")
+	ErrEpisodeTaken      = errors.New("Season already has an episode with this number")
+	ErrEpisodeNotFound   = errors.New("Episode not found")
+	ErrNotEpisode        = errors.New("Video is not an episode")
+)
+
+// IsEpisodeConflict tells whether err refuses a season or episode number
+// already taken
+func IsEpisodeConflict(err error) bool {
+	return err == ErrEpisodeTaken || err == ErrSeasonExists || err == ErrSeasonHasEpisodes || err == ErrSeriesHasSeasons
+}
+
+// uniqueViolation is the postgres error code of a unique index violation
+const uniqueViolation = "23505"
+
+// NumberConflict maps a violation of the season or episode number indexes
+// to the error of the matching check. Concurrent writes may both pass the
+// checks, the index then refuses the last one.
+func NumberConflict(err error) error {
+	pqErr, ok := err.(*pq.Error)
+	if !ok || pqErr.Code != uniqueViolation {
+		return err
+	}
+	switch pqErr.Constraint {
+	case "idx_seasons_number":
+		return ErrSeasonExists
+	case "idx_videos_episode":
+		return ErrEpisodeTaken
+	}
+	return err
+}
+
+// IsEpisodeError tells whether err refuses the season or episode number of
+// a video
+func IsEpisodeError(err error) bool {
+	switch err {
+	case ErrEpisodeIncomplete, ErrEpisodeNumber, ErrEpisodeTaken, ErrSeasonNotFound:
+		return true
+	}
+	return false
+}
+
+// ValidateEpisode checks the season and episode number of the video. On
+// updates a missing one is taken from the stored video. The season must be
+// live and no other live video of it may have the number.
+func (v *Video) ValidateEpisode(db *gorm.DB) error {
+	if v.SeasonID == nil && v.EpisodeNumber == nil {
+		return nil
+	}
+	seasonID, number := v.SeasonID, v.EpisodeNumber
+	if seasonID == nil || number == nil {
+		stored := Video{}
+		err := db.Select("season_id, episode_number").Where("id = ?", v.ID).Take(&stored).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+		if seasonID == nil {
+			seasonID = stored.SeasonID
+		}
+		if number == nil {
+			number = stored.EpisodeNumber
+		}
+	}
+	if seasonID == nil || number == nil {
+		return ErrEpisodeIncomplete
+	}
+	if *number < 1 || *number > MaxSeasonNumber {
+		return ErrEpisodeNumber
+	}
+
+	count := 0
+	if err := db.Model(&Season{}).Where("id = ?", *seasonID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSeasonNotFound
+	}
+	return checkEpisodeNumber(db, v.ID, *seasonID, *number)
+}
+
+// checkEpisodeNumber refuses a number taken by another live video of the
+// season
+func checkEpisodeNumber(db *gorm.DB, videoID, seasonID string, number int) error {
+	count := 0
+	err := db.Model(&Video{}).Where("season_id = ? AND episode_number = ? AND id <> ?", seasonID, number, videoID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEpisodeTaken
+	}
+	return nil
+}
+
+// FindEpisodes returns the live episodes of a season by number
+func (v *Video) FindEpisodes(db *gorm.DB, seasonID string) (*[]Video, error) {
+	videos := []Video{}
+	if err := db.Where("season_id = ?", seasonID).Order("episode_number").Find(&videos).Error; err != nil {
+		return &[]Video{}, err
+	}
+	if err := LoadVideoRelations(db, videos); err != nil {
+		return &[]Video{}, err
+	}
+	return &videos, nil
+}
+
+// FindEpisode searchs the live episode of a season by number
+func (v *Video) FindEpisode(db *gorm.DB, seasonID string, number int) error {
+	err := db.Where("season_id = ? AND episode_number = ?", seasonID, number).Take(v).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrEpisodeNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return v.LoadRelations(db)
+}
+
+// RemoveFromSeason turns the episode back into a standalone video
+func (v *Video) RemoveFromSeason(db *gorm.DB) error {
+	err := db.Model(&Video{}).Where("id = ?", v.ID).Updates(map[string]interface{}{"season_id": nil, "episode_number": nil}).Error
+	if err != nil {
+		return err
+	}
+	return v.FindByID(db)
+}
+
+// NextEpisode returns the episode following the video in its series: the
+// next number of its season, else the first episode of a later season
+func (v *Video) NextEpisode(db *gorm.DB) (*Video, error) {
+	return v.adjacentEpisode(db, ">", "ASC")
+}
+
+// PreviousEpisode returns the episode preceding the video in its series
+func (v *Video) PreviousEpisode(db *gorm.DB) (*Video, error) {
+	return v.adjacentEpisode(db, "<", "DESC")
+}
+
+func (v *Video) adjacentEpisode(db *gorm.DB, comparison, direction string) (*Video, error) {
+	if v.SeasonID == nil || v.EpisodeNumber == nil {
+		return nil, ErrNotEpisode
+	}
+	season := Season{}
+	if err := db.Where("id = ?", *v.SeasonID).Take(&season).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrNotEpisode
+		}
+		return nil, err
+	}
+
+	adjacent := Video{}
+	err := db.Select("videos.*").Joins("JOIN seasons ON seasons.id = videos.season_id AND seasons.deleted_at IS NULL").
+		Where("seasons.series_id = ? AND (seasons.number, videos.episode_number) "+comparison+" (?, ?)", season.SeriesID, season.Number, *v.EpisodeNumber).
+		Order("seasons.number " + direction + ", videos.episode_number " + direction).
+		Take(&adjacent).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, ErrEpisodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := adjacent.LoadRelations(db); err != nil {
+		return nil, err
+	}
+	return &adjacent, nil
+}
@@ -14,7 +14,9 @@ import (
 type Genre struct {
 	ID        string     `json:"id" valid:"uuid" gorm:"type:uuid;primary_key"`
 	Name      string     `json:"name" valid:"type(string),required~Genre name is required,stringlength(3|255)~Genre name must be between 3 and 255 characters" gorm:"varchar(255);unique"`
+	Slug      string     `json:"slug" valid:"-" gorm:"type:varchar(255);not null;default:''"`
 	IsActive  *bool      `json:"is_active" valid:"-" gorm:"bool;default:true"`
+	Position  int        `json:"position" valid:"-" gorm:"not null;default:0;index"`
 	CreatedAt *time.Time `json:"created_at,omitempty" valid:"-" gorm:"autoCreateTime"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty" valid:"-" gorm:"autoUpdateTime"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty" valid:"-" gorm:"autoDeleteTime"`
@@ -37,9 +39,21 @@ func (g *Genre) Prepare() {
 	g.Name = html.EscapeString(strings.TrimSpace(g.Name))
 }
 
-// Create creates a new genre
+// Create creates a new genre, placed after the others. Its position is
+// taken in the transaction inserting it.
 func (g *Genre) Create(db *gorm.DB) (*Genre, error) {
-	if err := db.Create(&g).Error; err != nil {
+	err := inTransaction(db, func(tx *gorm.DB) error {
+		position, err := nextPosition(tx, "genres")
+		if err != nil {
+			return err
+		}
+		g.Position = position
+		if g.Slug, err = NewSlug(tx, "genre", g.ID, g.Name); err != nil {
+			return err
+		}
+		return tx.Create(&g).Error
+	})
+	if err != nil {
 		return &Genre{}, err
 	}
 
@@ -50,7 +64,7 @@ func (g *Genre) Create(db *gorm.DB) (*Genre, error) {
 func (g *Genre) FindAll(db *gorm.DB) (*[]Genre, error) {
 	genres := []Genre{}
 
-	if err := db.Model(&Genre{}).Find(&genres).Error; err != nil {
+	if err := db.Model(&Genre{}).Order("position, created_at").Find(&genres).Error; err != nil {
 		return &[]Genre{}, err
 	}
 
@@ -73,8 +87,11 @@ func (g *Genre) FindByID(db *gorm.DB) error {
 	return err
 }
 
-// Update updates a genre by id
+// Update updates a genre by id. Positions only change through Reorder,
+// slugs follow the name.
 func (g *Genre) Update(db *gorm.DB) (*Genre, error) {
+	g.Position = 0
+	g.Slug = ""
 	req := db.Model(&g).Updates(&g).Find(&g)
 	if req.Error != nil {
 		return &Genre{}, errors.New("Internal server error")
@@ -82,6 +99,11 @@ func (g *Genre) Update(db *gorm.DB) (*Genre, error) {
 	if req.RowsAffected == 0 {
 		return &Genre{}, errors.New("Genre not found")
 	}
+	slug, err := RefreshSlug(db, "genre", g.ID)
+	if err != nil {
+		return &Genre{}, err
+	}
+	g.Slug = slug
 
 	return g, nil
 }
@@ -98,3 +120,22 @@ func (g *Genre) Delete(db *gorm.DB) error {
 	}
 	return nil
 }
+
+// Restore restores a soft deleted genre by id
+func (g *Genre) Restore(db *gorm.DB) error {
+	req := db.Unscoped().Model(&g).Where("deleted_at IS NOT NULL").Update("deleted_at", nil)
+	if req.Error != nil {
+		return errors.New("Internal server error")
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("Genre not found")
+	}
+
+	return db.Take(&g).Error
+}
+
+// Reorder places the genres listed by ids first, in that order, and returns
+// the ids of the ones that moved
+func (g *Genre) Reorder(db *gorm.DB, ids []string) ([]string, error) {
+	return reorder(db, "genres", ids)
+}
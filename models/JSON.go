@@ -0,0 +1,99 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// JSON models a json document stored in a jsonb column
+type JSON json.RawMessage
+
+// ToJSON marshals v, returning nil for a nil value
+func ToJSON(v interface{}) (JSON, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return JSON(b), nil
+}
+
+// Value implements driver.Valuer
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+// Scan implements sql.Scanner
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append(JSON{}, v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return errors.New("Invalid json value")
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	*j = append(JSON{}, data...)
+	return nil
+}
+
+// FieldChange models the values of a field before and after a change
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// ignoredDiffFields are bookkeeping fields left out of diffs
+var ignoredDiffFields = map[string]bool{"updated_at": true}
+
+// Diff compares two json objects field by field and returns the changed
+// fields. A missing document is treated as an empty object.
+func Diff(before, after JSON) (map[string]FieldChange, error) {
+	b := map[string]interface{}{}
+	a := map[string]interface{}{}
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &b); err != nil {
+			return nil, err
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &a); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := map[string]FieldChange{}
+	for key, value := range b {
+		if !ignoredDiffFields[key] && !reflect.DeepEqual(value, a[key]) {
+			changes[key] = FieldChange{Before: value, After: a[key]}
+		}
+	}
+	for key, value := range a {
+		if _, ok := b[key]; !ok && !ignoredDiffFields[key] && value != nil {
+			changes[key] = FieldChange{Before: nil, After: value}
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// OutboxMessage models an event waiting to be published. It is written in
+// the same transaction as the change it describes, so an event exists if
+// and only if its change was committed.
+type OutboxMessage struct {
+	Sequence      uint64     `json:"sequence" gorm:"primary_key;AUTO_INCREMENT"`
+	EventID       string     `json:"event_id" gorm:"type:uuid;unique_index;not null"`
+	EventType     string     `json:"event_type" gorm:"type:varchar(128);not null"`
+	AggregateType string     `json:"aggregate_type" gorm:"type:varchar(64);not null"`
+	AggregateID   string     `json:"aggregate_id" gorm:"type:varchar(64);index;not null"`
+	Payload       JSON       `json:"payload" gorm:"type:jsonb;not null"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0"`
+	LastError     string     `json:"last_error,omitempty" gorm:"type:text"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"not null"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"not null"`
+	PublishedAt   *time.Time `json:"published_at,omitempty" gorm:"index"`
+}
+
+// OutboxStats summarizes the messages not published yet
+type OutboxStats struct {
+	Pending         int
+	OldestCreatedAt *time.Time
+}
+
+// Create creates a new outbox message
+func (m *OutboxMessage) Create(db *gorm.DB) (*OutboxMessage, error) {
+	if err := db.Create(&m).Error; err != nil {
+		return &OutboxMessage{}, err
+	}
+
+	return m, nil
+}
+
+// FindUnpublished returns up to limit unpublished messages in the order
+// they were written
+func (m *OutboxMessage) FindUnpublished(db *gorm.DB, limit int) (*[]OutboxMessage, error) {
+	messages := []OutboxMessage{}
+
+	err := db.Model(&OutboxMessage{}).Where("published_at IS NULL").Order("sequence").Limit(limit).Find(&messages).Error
+	if err != nil {
+		return &[]OutboxMessage{}, err
+	}
+
+	return &messages, nil
+}
+
+// ClaimDue claims up to limit due messages in the order they were written,
+// pushing their next attempt to until so no other relay takes them while
+// they are published. A message is due when its next attempt has come and
+// no earlier message of its aggregate is waiting for a later one. db must
+// be a transaction.
+func (m *OutboxMessage) ClaimDue(db *gorm.DB, limit int, now, until time.Time) (*[]OutboxMessage, error) {
+	messages := []OutboxMessage{}
+
+	err := db.Raw(`SELECT * FROM outbox_messages m
+		WHERE m.published_at IS NULL AND m.next_attempt_at <= ?
+		AND NOT EXISTS (SELECT 1 FROM outbox_messages e
+			WHERE e.aggregate_type = m.aggregate_type AND e.aggregate_id = m.aggregate_id
+			AND e.published_at IS NULL AND e.sequence < m.sequence AND e.next_attempt_at > ?)
+		ORDER BY m.sequence LIMIT ? FOR UPDATE SKIP LOCKED`, now, now, limit).Scan(&messages).Error
+	if err != nil {
+		return &[]OutboxMessage{}, err
+	}
+	if len(messages) == 0 {
+		return &messages, nil
+	}
+
+	sequences := []uint64{}
+	for i := range messages {
+		sequences = append(sequences, messages[i].Sequence)
+		messages[i].NextAttemptAt = until
+	}
+	err = db.Model(&OutboxMessage{}).Where("sequence IN (?)", sequences).
+		Update("next_attempt_at", until).Error
+	if err != nil {
+		return &[]OutboxMessage{}, err
+	}
+
+	return &messages, nil
+}
+
+// Release gives up the claim on the message, making it due again
+func (m *OutboxMessage) Release(db *gorm.DB, at time.Time) error {
+	m.NextAttemptAt = at
+	return db.Model(&OutboxMessage{}).Where("sequence = ? AND published_at IS NULL", m.Sequence).
+		Update("next_attempt_at", at).Error
+}
+
+// FindAfter returns up to limit messages written after sequence, published
+// or not, in the order they were written
+func (m *OutboxMessage) FindAfter(db *gorm.DB, sequence uint64, limit int) (*[]OutboxMessage, error) {
+	messages := []OutboxMessage{}
+
+	err := db.Model(&OutboxMessage{}).Where("sequence > ?", sequence).Order("sequence").Limit(limit).Find(&messages).Error
+	if err != nil {
+		return &[]OutboxMessage{}, err
+	}
+
+	return &messages, nil
+}
+
+// FindLatest returns the last limit messages in the order they were written
+func (m *OutboxMessage) FindLatest(db *gorm.DB, limit int) (*[]OutboxMessage, error) {
+	messages := []OutboxMessage{}
+
+	err := db.Model(&OutboxMessage{}).Order("sequence desc").Limit(limit).Find(&messages).Error
+	if err != nil {
+		return &[]OutboxMessage{}, err
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+
+	return &messages, nil
+}
+
+// MarkPublished records the message as delivered
+func (m *OutboxMessage) MarkPublished(db *gorm.DB, at time.Time) error {
+	m.PublishedAt = &at
+	return db.Model(&OutboxMessage{}).Where("sequence = ?", m.Sequence).
+		Updates(map[string]interface{}{"published_at": at, "attempts": m.Attempts + 1, "last_error": ""}).Error
+}
+
+// MarkFailed records a failed attempt and when to try again
+func (m *OutboxMessage) MarkFailed(db *gorm.DB, cause error, next time.Time) error {
+	m.Attempts++
+	m.LastError = cause.Error()
+	m.NextAttemptAt = next
+	return db.Model(&OutboxMessage{}).Where("sequence = ?", m.Sequence).
+		Updates(map[string]interface{}{"attempts": m.Attempts, "last_error": m.LastError, "next_attempt_at": next}).Error
+}
+
+// Stats counts the unpublished messages and finds the oldest one
+func (m *OutboxMessage) Stats(db *gorm.DB) (OutboxStats, error) {
+	stats := OutboxStats{}
+	if err := db.Model(&OutboxMessage{}).Where("published_at IS NULL").Count(&stats.Pending).Error; err != nil {
+		return stats, err
+	}
+	if stats.Pending == 0 {
+		return stats, nil
+	}
+
+	oldest := OutboxMessage{}
+	if err := db.Where("published_at IS NULL").Order("sequence").Take(&oldest).Error; err != nil {
+		return stats, err
+	}
+	stats.OldestCreatedAt = &oldest.CreatedAt
+	return stats, nil
+}
+
+// DeletePublished removes the messages published before the given time
+func (m *OutboxMessage) DeletePublished(db *gorm.DB, before time.Time) (int64, error) {
+	result := db.Where("published_at < ?", before).Delete(&OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
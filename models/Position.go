@@ -0,0 +1,99 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// positionLockID serializes the position changes of manually ordered
+// entities, so appends and reorders don't interleave
+const positionLockID = 4242004
+
+// ErrInvalidOrder is returned when a reorder lists unknown or repeated ids
+var ErrInvalidOrder = errors.New("Order must list existing ids, each once")
+
+// errNoTransaction is returned when a position is taken outside of a
+// transaction, whose lock would be released before the row is inserted
+var errNoTransaction = errors.New("positions must be taken within a transaction")
+
+// inTransaction runs fn in the transaction db is, or in a new one
+func inTransaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if _, ok := db.CommonDB().(*sql.Tx); ok {
+		return fn(db)
+	}
+	return db.Transaction(fn)
+}
+
+// nextPosition returns the position placing a new row of table last. db
+// must be the transaction inserting the row, which holds the lock until
+// the row is committed.
+func nextPosition(db *gorm.DB, table string) (int, error) {
+	if _, ok := db.CommonDB().(*sql.Tx); !ok {
+		return 0, errNoTransaction
+	}
+	if err := db.Exec("SELECT pg_advisory_xact_lock(?)", positionLockID).Error; err != nil {
+		return 0, err
+	}
+	var last struct{ Position int }
+	err := db.Raw(fmt.Sprintf("SELECT COALESCE(MAX(position), 0) AS position FROM %s WHERE deleted_at IS NULL", table)).Scan(&last).Error
+	return last.Position + 1, err
+}
+
+// reorder places the rows of table listed by ids first, in that order,
+// followed by the other rows in their previous order. It returns the ids
+// whose position changed.
+func reorder(db *gorm.DB, table string, ids []string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if _, err := uuid.FromString(id); err != nil || seen[id] {
+			return nil, ErrInvalidOrder
+		}
+		seen[id] = true
+	}
+
+	if err := db.Exec("SELECT pg_advisory_xact_lock(?)", positionLockID).Error; err != nil {
+		return nil, err
+	}
+	rows := []struct {
+		ID       string
+		Position int
+	}{}
+	err := db.Raw(fmt.Sprintf("SELECT id, position FROM %s WHERE deleted_at IS NULL ORDER BY position, created_at, id", table)).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	current := map[string]int{}
+	for _, row := range rows {
+		current[row.ID] = row.Position
+	}
+	order := []string{}
+	for _, id := range ids {
+		if _, ok := current[id]; !ok {
+			return nil, ErrInvalidOrder
+		}
+		order = append(order, id)
+	}
+	for _, row := range rows {
+		if !seen[row.ID] {
+			order = append(order, row.ID)
+		}
+	}
+
+	changed := []string{}
+	for i, id := range order {
+		if current[id] == i+1 {
+			continue
+		}
+		err := db.Exec(fmt.Sprintf("UPDATE %s SET position = ?, updated_at = now() WHERE id = ?", table), i+1, id).Error
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, id)
+	}
+	return changed, nil
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AuditActionRollback marks a change restoring a previous revision
+const AuditActionRollback = "rollback"
+
+// Revision models a full snapshot of an entity taken after a change
+type Revision struct {
+	ID         string    `json:"id" gorm:"type:uuid;primary_key"`
+	EntityType string    `json:"entity_type" gorm:"type:varchar(64);unique_index:idx_revision_version"`
+	EntityID   string    `json:"entity_id" gorm:"type:varchar(64);unique_index:idx_revision_version"`
+	Version    int       `json:"version" gorm:"unique_index:idx_revision_version"`
+	Action     string    `json:"action" gorm:"type:varchar(32)"`
+	Actor      string    `json:"actor" gorm:"type:varchar(255)"`
+	Snapshot   JSON      `json:"snapshot" gorm:"type:jsonb"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Create creates a new revision numbered after the latest one of the entity
+func (r *Revision) Create(db *gorm.DB) (*Revision, error) {
+	latest := struct{ Version int }{}
+	err := db.Model(&Revision{}).
+		Select("COALESCE(MAX(version), 0) AS version").
+		Where("entity_type = ? AND entity_id = ?", r.EntityType, r.EntityID).
+		Scan(&latest).Error
+	if err != nil {
+		return &Revision{}, err
+	}
+
+	r.Version = latest.Version + 1
+	if err := db.Create(&r).Error; err != nil {
+		return &Revision{}, err
+	}
+
+	return r, nil
+}
+
+// FindAll returns the revisions of an entity, newest first
+func (r *Revision) FindAll(db *gorm.DB) (*[]Revision, error) {
+	revisions := []Revision{}
+
+	err := db.Model(&Revision{}).
+		Where("entity_type = ? AND entity_id = ?", r.EntityType, r.EntityID).
+		Order("version desc").
+		Find(&revisions).Error
+	if err != nil {
+		return &[]Revision{}, err
+	}
+
+	return &revisions, nil
+}
+
+// FindByVersion searchs a revision of an entity by version. Version 0
+// selects the latest one.
+func (r *Revision) FindByVersion(db *gorm.DB) error {
+	query := db.Where("entity_type = ? AND entity_id = ?", r.EntityType, r.EntityID)
+	if r.Version > 0 {
+		query = query.Where("version = ?", r.Version)
+	}
+
+	err := query.Order("version desc").Take(&r).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return errors.New("Revision not found")
+	}
+	return err
+}
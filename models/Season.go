@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MaxSeasonNumber bounds the numbers of seasons and episodes
+const MaxSeasonNumber = 9999
+
+// season errors
+var (
+	ErrSeriesNotFound    = errors.New("Series not found")
+	ErrSeasonNotFound    = errors.New("Season not found")
+	ErrSeasonNumber      = errors.New("Season number must be between 1 and 9999")
+	ErrSeasonTitle       = errors.New("Season title must be at most 255 characters")
+	ErrSeasonExists      = errors.New("Series already has a season with this number")
+	ErrSeasonHasEpisodes = errors.New("Season has episodes, remove them first")
+)
+
+// Season models a numbered season of a series
+type Season struct {
+	ID        string     `json:"id" gorm:"type:uuid;primary_key"`
+	SeriesID  string     `json:"series_id" gorm:"type:uuid;not null;index"`
+	Number    int        `json:"number" gorm:"not null"`
+	Title     string     `json:"title" gorm:"type:varchar(255)"`
+	CreatedAt *time.Time `json:"created_at,omitempty" gorm:"autoCreateTime"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" gorm:"autoUpdateTime"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" gorm:"autoDeleteTime"`
+}
+
+// SeasonSummary models a season with the totals of its episodes
+type SeasonSummary struct {
+	Season
+	EpisodeCount int `json:"episode_count"`
+	Duration     int `json:"duration"`
+}
+
+// Prepare prepares values
+func (s *Season) Prepare() {
+	s.Title = html.EscapeString(strings.TrimSpace(s.Title))
+}
+
+// Validate checks the number and title of the season
+func (s *Season) Validate() error {
+	if s.Number < 1 || s.Number > MaxSeasonNumber {
+		return ErrSeasonNumber
+	}
+	if len(s.Title) > 255 {
+		return ErrSeasonTitle
+	}
+	return nil
+}
+
+// Create creates a new season of a live series, refusing a number already
+// taken by another season of the series
+func (s *Season) Create(db *gorm.DB) (*Season, error) {
+	if err := s.checkNumber(db); err != nil {
+		return &Season{}, err
+	}
+	if err := db.Create(&s).Error; err != nil {
+		return &Season{}, NumberConflict(err)
+	}
+
+	return s, nil
+}
+
+// checkNumber tells whether the season may take its number in its series
+func (s *Season) checkNumber(db *gorm.DB) error {
+	count := 0
+	if err := db.Model(&Series{}).Where("id = ?", s.SeriesID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSeriesNotFound
+	}
+	query := db.Model(&Season{}).Where("series_id = ? AND number = ?", s.SeriesID, s.Number)
+	if s.ID != "" {
+		query = query.Where("id <> ?", s.ID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSeasonExists
+	}
+	return nil
+}
+
+// FindBySeries returns the seasons of a series by number, with the episode
+// count and total duration of each
+func (s *Season) FindBySeries(db *gorm.DB, seriesID string) ([]SeasonSummary, error) {
+	seasons := []SeasonSummary{}
+	err := db.Raw(`SELECT seasons.*, COUNT(videos.id) AS episode_count, COALESCE(SUM(videos.duration), 0) AS duration
+		FROM seasons LEFT JOIN videos ON videos.season_id = seasons.id AND videos.deleted_at IS NULL
+		WHERE seasons.series_id = ? AND seasons.deleted_at IS NULL
+		GROUP BY seasons.id
+		ORDER BY seasons.number`, seriesID).Scan(&seasons).Error
+	return seasons, err
+}
+
+// FindByNumber searchs the season of a series by number, with the totals
+// of its episodes
+func (s *Season) FindByNumber(db *gorm.DB, seriesID string, number int) (*SeasonSummary, error) {
+	seasons, err := s.FindBySeries(db, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range seasons {
+		if seasons[i].Number == number {
+			return &seasons[i], nil
+		}
+	}
+	return nil, ErrSeasonNotFound
+}
+
+// Update updates the number and title of a season
+func (s *Season) Update(db *gorm.DB) (*Season, error) {
+	if err := s.checkNumber(db); err != nil {
+		return &Season{}, err
+	}
+	req := db.Model(&Season{}).Where("id = ?", s.ID).Updates(map[string]interface{}{"number": s.Number, "title": s.Title})
+	if req.Error != nil {
+		if err := NumberConflict(req.Error); err == ErrSeasonExists {
+			return &Season{}, err
+		}
+		return &Season{}, errors.New("Internal server error")
+	}
+	if req.RowsAffected == 0 {
+		return &Season{}, ErrSeasonNotFound
+	}
+	if err := db.Take(&s).Error; err != nil {
+		return &Season{}, err
+	}
+
+	return s, nil
+}
+
+// Delete deletes a season by id. Seasons with live episodes are kept.
+func (s *Season) Delete(db *gorm.DB) error {
+	count := 0
+	if err := db.Model(&Video{}).Where("season_id = ?", s.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSeasonHasEpisodes
+	}
+
+	db = db.Delete(&s)
+	if db.Error != nil {
+		return db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		return ErrSeasonNotFound
+	}
+	return nil
+}
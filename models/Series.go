@@ -0,0 +1,125 @@
+package models
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/jinzhu/gorm"
+)
+
+// ErrSeriesHasSeasons is returned when deleting a series with live seasons
+var ErrSeriesHasSeasons = errors.New("Series has seasons, delete them first")
+
+// Series models a show made of seasons of episodes
+type Series struct {
+	ID           string     `json:"id" valid:"uuid" gorm:"type:uuid;primary_key"`
+	Title        string     `json:"title" valid:"type(string),required~Series title is required,stringlength(3|255)~Series title must be between 3 and 255 characters" gorm:"type:varchar(255)"`
+	Description  string     `json:"description" valid:"type(string),optional" gorm:"type:text"`
+	YearLaunched int        `json:"year_launched" valid:"-"`
+	CreatedAt    *time.Time `json:"created_at,omitempty" valid:"-" gorm:"autoCreateTime"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty" valid:"-" gorm:"autoUpdateTime"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty" valid:"-" gorm:"autoDeleteTime"`
+}
+
+// TableName sets the table of series, whose plural is the singular
+func (Series) TableName() string {
+	return "series"
+}
+
+// Validate validates basic struct
+func (s *Series) Validate() error {
+	if _, err := govalidator.ValidateStruct(s); err != nil {
+		return err
+	}
+	if s.YearLaunched != 0 {
+		if err := validateYearLaunched(s.YearLaunched); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prepare prepares values
+func (s *Series) Prepare() {
+	s.Title = html.EscapeString(strings.TrimSpace(s.Title))
+	s.Description = html.EscapeString(strings.TrimSpace(s.Description))
+}
+
+// Create creates a new series
+func (s *Series) Create(db *gorm.DB) (*Series, error) {
+	if err := db.Create(&s).Error; err != nil {
+		return &Series{}, err
+	}
+
+	return s, nil
+}
+
+// FindAll returns all series in db
+func (s *Series) FindAll(db *gorm.DB) (*[]Series, error) {
+	series := []Series{}
+
+	if err := db.Model(&Series{}).Order("title, created_at").Find(&series).Error; err != nil {
+		return &[]Series{}, err
+	}
+
+	return &series, nil
+}
+
+// FindByID searchs a series by id
+func (s *Series) FindByID(db *gorm.DB) error {
+	err := db.Take(&s).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrSeriesNotFound
+	}
+	return err
+}
+
+// Update updates a series by id
+func (s *Series) Update(db *gorm.DB) (*Series, error) {
+	req := db.Model(&s).Updates(&s).Find(&s)
+	if req.Error != nil {
+		return &Series{}, errors.New("Internal server error")
+	}
+	if req.RowsAffected == 0 {
+		return &Series{}, errors.New("Series not found")
+	}
+
+	return s, nil
+}
+
+// Delete deletes a series by id. Series with live seasons are kept.
+func (s *Series) Delete(db *gorm.DB) error {
+	count := 0
+	if err := db.Model(&Season{}).Where("series_id = ?", s.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSeriesHasSeasons
+	}
+
+	db = db.Delete(&s)
+	if db.Error != nil {
+		return db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		return errors.New("Series not found")
+	}
+	return nil
+}
+
+// Restore restores a soft deleted series by id
+func (s *Series) Restore(db *gorm.DB) error {
+	req := db.Unscoped().Model(&s).Where("deleted_at IS NOT NULL").Update("deleted_at", nil)
+	if req.Error != nil {
+		return errors.New("Internal server error")
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("Series not found")
+	}
+
+	return db.Take(&s).Error
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"video-catalog/slug"
+
+	"github.com/jinzhu/gorm"
+)
+
+// slugLockID serializes slug assignments, so two entities can't take the
+// same slug together
+const slugLockID = 4242005
+
+// slugSuffixRoom is kept free at the end of a base slug for its numeric
+// suffix
+const slugSuffixRoom = 8
+
+// errNoSlugs is returned for entity types without slugs
+var errNoSlugs = errors.New("Entity type has no slugs")
+
+// SlugRedirect models a slug an entity had before being renamed. Lookups by
+// that slug lead to the entity, and no other entity can take it.
+type SlugRedirect struct {
+	EntityType string    `json:"entity_type" gorm:"type:varchar(32);primary_key"`
+	Slug       string    `json:"slug" gorm:"type:varchar(255);primary_key"`
+	EntityID   string    `json:"entity_id" gorm:"type:uuid;not null;index"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName sets the table of slug redirects
+func (SlugRedirect) TableName() string {
+	return "slug_redirects"
+}
+
+type slugSource struct {
+	table  string
+	column string
+}
+
+// slugSources maps the entity types with slugs to the column their slug is
+// made of
+var slugSources = map[string]slugSource{
+	"category":    {"categories", "name"},
+	"genre":       {"genres", "name"},
+	"cast_member": {"cast_members", "name"},
+	"video":       {"videos", "title"},
+}
+
+// SlugTables returns the tables of the entity types with slugs
+func SlugTables() map[string]string {
+	tables := map[string]string{}
+	for entityType, source := range slugSources {
+		tables[entityType] = source.table
+	}
+	return tables
+}
+
+// NewSlug returns a free slug for an entity about to be created with text
+// as its name or title
+func NewSlug(db *gorm.DB, entityType, id, text string) (string, error) {
+	if _, ok := slugSources[entityType]; !ok {
+		return "", errNoSlugs
+	}
+	if err := db.Exec("SELECT pg_advisory_xact_lock(?)", slugLockID).Error; err != nil {
+		return "", err
+	}
+	return freeSlug(db, entityType, id, baseSlug(entityType, text))
+}
+
+// RefreshSlug makes the slug of an entity follow its current name or title
+// and returns it. A slug still made of the name is kept; otherwise the
+// entity gets a new one and its previous slug becomes a redirect.
+func RefreshSlug(db *gorm.DB, entityType, id string) (string, error) {
+	source, ok := slugSources[entityType]
+	if !ok {
+		return "", errNoSlugs
+	}
+	if err := db.Exec("SELECT pg_advisory_xact_lock(?)", slugLockID).Error; err != nil {
+		return "", err
+	}
+
+	current := struct{ Slug, Text string }{}
+	err := db.Raw(fmt.Sprintf("SELECT slug, %s AS text FROM %s WHERE id = ?", source.column, source.table), id).Scan(&current).Error
+	if err != nil {
+		return "", err
+	}
+	base := baseSlug(entityType, current.Text)
+	if current.Slug != "" && slugOf(current.Slug, base) {
+		return current.Slug, nil
+	}
+
+	next, err := freeSlug(db, entityType, id, base)
+	if err != nil {
+		return "", err
+	}
+	if current.Slug != "" {
+		redirect := SlugRedirect{EntityType: entityType, Slug: current.Slug, EntityID: id}
+		if err := db.Create(&redirect).Error; err != nil {
+			return "", err
+		}
+	}
+	err = db.Where("entity_type = ? AND slug = ? AND entity_id = ?", entityType, next, id).Delete(&SlugRedirect{}).Error
+	if err != nil {
+		return "", err
+	}
+	err = db.Exec(fmt.Sprintf("UPDATE %s SET slug = ? WHERE id = ?", source.table), next, id).Error
+	return next, err
+}
+
+// ResolveSlug returns the id and current slug of the entity a slug leads
+// to, either its current slug or one it had before a rename
+func ResolveSlug(db *gorm.DB, entityType, value string) (string, string, error) {
+	source, ok := slugSources[entityType]
+	if !ok {
+		return "", "", errNoSlugs
+	}
+
+	found := []struct{ ID, Slug string }{}
+	err := db.Raw(fmt.Sprintf("SELECT id, slug FROM %s WHERE slug = ?", source.table), value).Scan(&found).Error
+	if err != nil {
+		return "", "", err
+	}
+	if len(found) == 0 {
+		err = db.Raw(fmt.Sprintf(`SELECT t.id, t.slug FROM slug_redirects r JOIN %s t ON t.id = r.entity_id
+			WHERE r.entity_type = ? AND r.slug = ?`, source.table), entityType, value).Scan(&found).Error
+		if err != nil {
+			return "", "", err
+		}
+	}
+	if len(found) == 0 {
+		return "", "", gorm.ErrRecordNotFound
+	}
+	return found[0].ID, found[0].Slug, nil
+}
+
+// baseSlug returns the slug of text, or one made of the entity type when
+// text has nothing to transliterate
+func baseSlug(entityType, text string) string {
+	if s := slug.Make(text); s != "" {
+		return s
+	}
+	return strings.Replace(entityType, "_", "-", -1)
+}
+
+// slugOf tells whether s is base, possibly with a numeric suffix
+func slugOf(s, base string) bool {
+	if s == base {
+		return true
+	}
+	prefix := slug.Truncate(base, slug.MaxLength-slugSuffixRoom) + "-"
+	if !strings.HasPrefix(s, prefix) || len(s) == len(prefix) {
+		return false
+	}
+	return strings.Trim(s[len(prefix):], "0123456789") == ""
+}
+
+// freeSlug returns base, or base with the lowest numeric suffix from 2, not
+// taken by another entity of the type nor kept as one of its redirects
+func freeSlug(db *gorm.DB, entityType, id, base string) (string, error) {
+	source := slugSources[entityType]
+	pattern := slug.Truncate(base, slug.MaxLength-slugSuffixRoom) + "-%"
+
+	taken := []struct{ Slug string }{}
+	err := db.Raw(fmt.Sprintf(`SELECT slug FROM %s WHERE (slug = ? OR slug LIKE ?) AND id::text <> ?
+		UNION SELECT slug FROM slug_redirects WHERE entity_type = ? AND (slug = ? OR slug LIKE ?) AND entity_id::text <> ?`, source.table),
+		base, pattern, id, entityType, base, pattern, id).Scan(&taken).Error
+	if err != nil {
+		return "", err
+	}
+	used := map[string]bool{}
+	for _, t := range taken {
+		used[t.Slug] = true
+	}
+
+	candidate := base
+	for n := 2; used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", slug.Truncate(base, slug.MaxLength-slugSuffixRoom), n)
+	}
+	return candidate, nil
+}
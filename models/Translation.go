@@ -0,0 +1,300 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// translation errors
+var (
+	ErrNoTranslation      = errors.New("Translation must set at least one field")
+	ErrTranslationField   = errors.New("Translation has a field that can't be translated")
+	ErrTranslationNotUsed = errors.New("Texts of the default locale are the stored ones, update the entity instead")
+	ErrEntityNotFound     = errors.New("Entity not found")
+)
+
+// Translation models the text of a field of an entity in a locale
+type Translation struct {
+	EntityType string    `json:"entity_type" gorm:"type:varchar(32);primary_key"`
+	EntityID   string    `json:"entity_id" gorm:"type:uuid;primary_key"`
+	Locale     string    `json:"locale" gorm:"type:varchar(35);primary_key"`
+	Field      string    `json:"field" gorm:"type:varchar(32);primary_key"`
+	Value      string    `json:"value" gorm:"type:text;not null"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Translations maps the translated fields of an entity to their text
+type Translations map[string]string
+
+type translatedSource struct {
+	table  string
+	column string
+	fields []string
+}
+
+// translatedSources maps the translatable entity types to their table, the
+// column naming them and their translatable fields
+var translatedSources = map[string]translatedSource{
+	"category":    {"categories", "name", []string{"name"}},
+	"genre":       {"genres", "name", []string{"name"}},
+	"cast_member": {"cast_members", "name", []string{"name"}},
+	"video":       {"videos", "title", []string{"title", "description"}},
+}
+
+// Translatable is implemented by the entities whose texts are translated
+type Translatable interface {
+	translationID() string
+	translate(field, value string)
+}
+
+// NameOf returns the name of an entity held outside of its model, such as
+// a search hit title or a facet label, as a translatable: its translation
+// is written to name
+func NameOf(entityType, id string, name *string) Translatable {
+	return &entityName{id: id, field: translatedSources[entityType].column, name: name}
+}
+
+type entityName struct {
+	id    string
+	field string
+	name  *string
+}
+
+func (n *entityName) translationID() string { return n.id }
+
+func (n *entityName) translate(field, value string) {
+	if field == n.field {
+		*n.name = value
+	}
+}
+
+func (c *Category) translationID() string { return c.ID }
+
+func (c *Category) translate(field, value string) {
+	if field == "name" {
+		c.Name = value
+	}
+}
+
+func (g *Genre) translationID() string { return g.ID }
+
+func (g *Genre) translate(field, value string) {
+	if field == "name" {
+		g.Name = value
+	}
+}
+
+func (c *CastMember) translationID() string { return c.ID }
+
+func (c *CastMember) translate(field, value string) {
+	if field == "name" {
+		c.Name = value
+	}
+}
+
+func (v *Video) translationID() string { return v.ID }
+
+func (v *Video) translate(field, value string) {
+	switch field {
+	case "title":
+		v.Title = value
+	case "description":
+		v.Description = value
+	}
+}
+
+// Prepare trims and escapes the texts like the entities do
+func (t Translations) Prepare() {
+	for field, value := range t {
+		t[field] = html.EscapeString(strings.TrimSpace(value))
+	}
+}
+
+// Validate applies the rules of the entity fields to their translations:
+// names and titles between 3 and 255 characters, descriptions of at least
+// 10 words and 15 characters
+func (t Translations) Validate(entityType string) error {
+	source, ok := translatedSources[entityType]
+	if !ok {
+		return ErrTranslationField
+	}
+	if len(t) == 0 {
+		return ErrNoTranslation
+	}
+	for field, value := range t {
+		if !contains(source.fields, field) {
+			return ErrTranslationField
+		}
+		switch field {
+		case "title":
+			if err := validateTitle(len(value)); err != nil {
+				return err
+			}
+		case "name":
+			if len(value) < 3 || len(value) > 255 {
+				return errors.New("Name length must be between 3 and 255 characters")
+			}
+		case "description":
+			if err := validateDescription(len(value), len(strings.Fields(value))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetTranslations stores the translations of a live entity in a locale,
+// replacing the previous text of each field given
+func SetTranslations(db *gorm.DB, entityType, entityID, locale string, values Translations) error {
+	source, ok := translatedSources[entityType]
+	if !ok {
+		return ErrTranslationField
+	}
+	count := 0
+	if err := db.Table(source.table).Where("id = ? AND deleted_at IS NULL", entityID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrEntityNotFound
+	}
+
+	for field, value := range values {
+		err := db.Exec(`INSERT INTO translations (entity_type, entity_id, locale, field, value, updated_at) VALUES (?, ?, ?, ?, ?, now())
+			ON CONFLICT (entity_type, entity_id, locale, field) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at`,
+			entityType, entityID, locale, field, value).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindTranslations returns the translations of an entity by locale
+func FindTranslations(db *gorm.DB, entityType, entityID string) (map[string]Translations, error) {
+	rows := []Translation{}
+	if err := db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byLocale := map[string]Translations{}
+	for _, row := range rows {
+		if byLocale[row.Locale] == nil {
+			byLocale[row.Locale] = Translations{}
+		}
+		byLocale[row.Locale][row.Field] = row.Value
+	}
+	return byLocale, nil
+}
+
+// DeleteTranslations removes the translations of an entity in a locale and
+// returns how many fields were translated
+func DeleteTranslations(db *gorm.DB, entityType, entityID, locale string) (int64, error) {
+	req := db.Where("entity_type = ? AND entity_id = ? AND locale = ?", entityType, entityID, locale).Delete(&Translation{})
+	return req.RowsAffected, req.Error
+}
+
+// Localize replaces the texts of entities with their translation in the
+// first locale of chain having one. Fields translated in no locale of the
+// chain keep their stored text.
+func Localize(db *gorm.DB, entityType string, chain []string, entities ...Translatable) error {
+	if len(chain) == 0 || len(entities) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(entities))
+	for _, e := range entities {
+		ids = append(ids, e.translationID())
+	}
+
+	rows := []Translation{}
+	err := db.Where("entity_type = ? AND entity_id IN (?) AND locale IN (?)", entityType, ids, chain).Find(&rows).Error
+	if err != nil {
+		return err
+	}
+	rank := map[string]int{}
+	for n, locale := range chain {
+		rank[locale] = n
+	}
+	best := map[string]map[string]Translation{}
+	for _, row := range rows {
+		if best[row.EntityID] == nil {
+			best[row.EntityID] = map[string]Translation{}
+		}
+		if current, ok := best[row.EntityID][row.Field]; !ok || rank[row.Locale] < rank[current.Locale] {
+			best[row.EntityID][row.Field] = row
+		}
+	}
+	for _, e := range entities {
+		for field, row := range best[e.translationID()] {
+			e.translate(field, row.Value)
+		}
+	}
+	return nil
+}
+
+// MissingTranslation models the fields of a live entity not translated to
+// a locale
+type MissingTranslation struct {
+	EntityType string   `json:"entity_type"`
+	EntityID   string   `json:"entity_id"`
+	Name       string   `json:"name"`
+	Locale     string   `json:"locale"`
+	Fields     []string `json:"fields"`
+}
+
+// FindMissingTranslations lists the live entities of entityTypes missing a
+// translation of some field in some of locales, by type, name and locale
+func FindMissingTranslations(db *gorm.DB, entityTypes, locales []string) ([]MissingTranslation, error) {
+	missing := []MissingTranslation{}
+	for _, entityType := range entityTypes {
+		source, ok := translatedSources[entityType]
+		if !ok {
+			continue
+		}
+		for _, locale := range locales {
+			rows := []struct {
+				ID    string
+				Name  string
+				Field string
+			}{}
+			err := db.Raw(fmt.Sprintf(`SELECT t.id, t.%[2]s AS name, f.field FROM %[1]s t CROSS JOIN unnest(?::text[]) AS f(field)
+				WHERE t.deleted_at IS NULL AND NOT EXISTS (
+					SELECT 1 FROM translations tr
+					WHERE tr.entity_type = ? AND tr.entity_id = t.id AND tr.locale = ? AND tr.field = f.field
+				)
+				ORDER BY t.%[2]s, t.id`, source.table, source.column),
+				"{"+strings.Join(source.fields, ",")+"}", entityType, locale).Scan(&rows).Error
+			if err != nil {
+				return nil, err
+			}
+
+			byID := map[string]int{}
+			for _, row := range rows {
+				n, ok := byID[row.ID]
+				if !ok {
+					n = len(missing)
+					byID[row.ID] = n
+					missing = append(missing, MissingTranslation{EntityType: entityType, EntityID: row.ID, Name: row.Name, Locale: locale, Fields: []string{}})
+				}
+				missing[n].Fields = append(missing[n].Fields, row.Field)
+			}
+		}
+	}
+	for n := range missing {
+		sort.Strings(missing[n].Fields)
+	}
+	return missing, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -5,34 +5,47 @@ import (
 	"html"
 	"strings"
 	"time"
+	"video-catalog/rating"
 
 	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
 )
 
-// RatingList enum
-var RatingList = []string{"L", "10", "12", "14", "16", "18"}
+// RatingList enum, the ClassInd levels of the rating field
+var RatingList = rating.ClassInd.Levels
 
 // Video models a video
 type Video struct {
-	ID           string     `json:"id" valid:"uuid" gorm:"type:uuid;primary_key"`
-	Title        string     `json:"title"`
-	Description  string     `json:"description"`
-	YearLaunched int        `json:"year_launched"`
-	Opened       *bool      `json:"opened" gorm:"default:false"`
-	Rating       string     `json:"rating"`
-	Duration     int        `json:"duration"`
-	CreatedAt    *time.Time `json:"created_at,omitempty" valid:"-" gorm:"autoCreateTime"`
-	UpdatedAt    *time.Time `json:"updated_at,omitempty" valid:"-" gorm:"autoUpdateTime"`
-	DeletedAt    *time.Time `json:"deleted_at,omitempty" valid:"-" gorm:"autoDeleteTime"`
+	ID            string     `json:"id" valid:"uuid" gorm:"type:uuid;primary_key"`
+	Title         string     `json:"title" gorm:"type:varchar(255)"`
+	Slug          string     `json:"slug" valid:"-" gorm:"type:varchar(255);not null;default:''"`
+	Description   string     `json:"description" gorm:"type:text"`
+	YearLaunched  int        `json:"year_launched"`
+	Opened        *bool      `json:"opened" gorm:"default:false"`
+	Rating        string     `json:"rating"`
+	Ratings       Ratings    `json:"ratings" valid:"-" gorm:"-"`
+	Duration      int        `json:"duration"`
+	CategoriesID  []string   `json:"categories_id" valid:"-" gorm:"-"`
+	GenresID      []string   `json:"genres_id" valid:"-" gorm:"-"`
+	SeasonID      *string    `json:"season_id" valid:"-" gorm:"type:uuid;index"`
+	EpisodeNumber *int       `json:"episode_number" valid:"-"`
+	CreatedAt     *time.Time `json:"created_at,omitempty" valid:"-" gorm:"autoCreateTime"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty" valid:"-" gorm:"autoUpdateTime"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" valid:"-" gorm:"autoDeleteTime"`
 }
 
-// Prepare cleans string fields
+// Prepare cleans string fields. A ClassInd rating given among the ratings
+// stands for the rating field when it is missing.
 func (v *Video) Prepare() {
 	v.Title = html.EscapeString(strings.TrimSpace(v.Title))
 	v.Description = html.EscapeString(strings.TrimSpace(v.Description))
+	if v.Rating == "" && v.Ratings != nil {
+		v.Rating = v.Ratings[rating.ClassInd.Name]
+	}
 }
 
-// Validate validates Video struct
+// Validate validates Video struct. A new video must be rated in at least
+// one system; its ClassInd rating, the rating field, may be left out.
 func (v *Video) Validate(action string) error {
 	if _, err := uuid.Parse(v.ID); err != nil {
 		return errors.New("Invalid id")
@@ -53,7 +66,15 @@ func (v *Video) Validate(action string) error {
 		if err := validateYearLaunched(v.YearLaunched); err != nil {
 			return err
 		}
-		if err := validateRating(v.Rating); err != nil {
+		if v.Rating == "" && len(v.Ratings) == 0 {
+			return errors.New("Video must be rated in at least one rating system")
+		}
+		if v.Rating != "" {
+			if err := validateRating(v.Rating); err != nil {
+				return err
+			}
+		}
+		if err := v.Ratings.Validate(v.Rating); err != nil {
 			return err
 		}
 		if err := validateDuration(v.Duration); err != nil {
@@ -61,7 +82,7 @@ func (v *Video) Validate(action string) error {
 		}
 
 	case "update":
-		if lenTitle == 0 && lenDescription == 0 && v.YearLaunched == 0 && v.Rating == "" && v.Duration == 0 {
+		if lenTitle == 0 && lenDescription == 0 && v.YearLaunched == 0 && v.Rating == "" && v.Ratings == nil && v.Duration == 0 && v.CategoriesID == nil && v.GenresID == nil && v.SeasonID == nil && v.EpisodeNumber == nil {
 			return errors.New("Video must update at least one field")
 		} else {
 			if lenTitle != 0 {
@@ -88,6 +109,10 @@ func (v *Video) Validate(action string) error {
 				}
 			}
 
+			if err := v.Ratings.Validate(v.Rating); err != nil {
+				return err
+			}
+
 			if v.Duration != 0 {
 				if err := validateDuration(v.Duration); err != nil {
 					return err
@@ -99,6 +124,87 @@ func (v *Video) Validate(action string) error {
 	return nil
 }
 
+// Create creates a new video with its categories and genres
+func (v *Video) Create(db *gorm.DB) (*Video, error) {
+	var err error
+	if v.Slug, err = NewSlug(db, "video", v.ID, v.Title); err != nil {
+		return &Video{}, err
+	}
+	if err := db.Create(&v).Error; err != nil {
+		return &Video{}, NumberConflict(err)
+	}
+	if err := v.SyncRelations(db); err != nil {
+		return &Video{}, err
+	}
+	if err := v.LoadRelations(db); err != nil {
+		return &Video{}, err
+	}
+
+	return v, nil
+}
+
+// FindAll returns all videos in db
+func (v *Video) FindAll(db *gorm.DB) (*[]Video, error) {
+	return v.FindFiltered(db, VideoFilter{})
+}
+
+// FindByID searchs a video by id
+func (v *Video) FindByID(db *gorm.DB) error {
+	var err error
+
+	err = db.Take(&v).Error
+	if err != nil {
+		return err
+	}
+
+	if gorm.IsRecordNotFoundError(err) {
+		return errors.New("Video not found")
+	}
+
+	return v.LoadRelations(db)
+}
+
+// Update updates a video by id, its slug following the title
+func (v *Video) Update(db *gorm.DB) (*Video, error) {
+	v.Slug = ""
+	req := db.Model(&v).Updates(&v).Find(&v)
+	if req.Error != nil {
+		if err := NumberConflict(req.Error); err == ErrEpisodeTaken {
+			return &Video{}, err
+		}
+		return &Video{}, errors.New("Internal server error")
+	}
+	if req.RowsAffected == 0 {
+		return &Video{}, errors.New("Video not found")
+	}
+	slug, err := RefreshSlug(db, "video", v.ID)
+	if err != nil {
+		return &Video{}, err
+	}
+	v.Slug = slug
+	if err := v.SyncRelations(db); err != nil {
+		return &Video{}, err
+	}
+	if err := v.LoadRelations(db); err != nil {
+		return &Video{}, err
+	}
+
+	return v, nil
+}
+
+// Delete deletes a video by id
+func (v *Video) Delete(db *gorm.DB) error {
+	db = db.Delete(&v)
+	if db.Error != nil {
+		return db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		return errors.New("Video not found")
+	}
+	return nil
+}
+
 func validateTitle(lenTitle int) error {
 	if lenTitle < 3 || lenTitle > 255 {
 		return errors.New("Title length must be between 3 and 255 characters")
@@ -120,17 +226,8 @@ func validateYearLaunched(yearLaunched int) error {
 	return nil
 }
 
-func validateRating(rating string) error {
-	foundRating := false
-	for _, ratingOption := range RatingList {
-		if ratingOption == rating {
-			foundRating = true
-		}
-	}
-	if !foundRating {
-		return errors.New("Rating must be a valid value")
-	}
-	return nil
+func validateRating(level string) error {
+	return rating.ClassInd.Validate(level)
 }
 
 func validateDuration(duration int) error {
@@ -139,3 +236,29 @@ func validateDuration(duration int) error {
 	}
 	return nil
 }
+
+// Restore restores a soft deleted video by id, unless its episode number
+// was taken meanwhile
+func (v *Video) Restore(db *gorm.DB) error {
+	stored := Video{}
+	if err := db.Unscoped().Where("id = ?", v.ID).Take(&stored).Error; err == nil && stored.SeasonID != nil && stored.EpisodeNumber != nil {
+		if err := checkEpisodeNumber(db, v.ID, *stored.SeasonID, *stored.EpisodeNumber); err != nil {
+			return err
+		}
+	}
+	req := db.Unscoped().Model(&v).Where("deleted_at IS NOT NULL").Update("deleted_at", nil)
+	if req.Error != nil {
+		if err := NumberConflict(req.Error); err == ErrEpisodeTaken {
+			return err
+		}
+		return errors.New("Internal server error")
+	}
+	if req.RowsAffected == 0 {
+		return errors.New("Video not found")
+	}
+
+	if err := db.Take(&v).Error; err != nil {
+		return err
+	}
+	return v.LoadRelations(db)
+}
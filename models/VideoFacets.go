@@ -0,0 +1,250 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"video-catalog/rating"
+
+	"github.com/jinzhu/gorm"
+)
+
+// video facets
+const (
+	FacetCategory = "category"
+	FacetGenre    = "genre"
+	FacetRating   = "rating"
+	FacetDecade   = "decade"
+	FacetDuration = "duration"
+	FacetOpened   = "opened"
+)
+
+// DurationBucket groups videos by duration in minutes, from Min included
+// to Max excluded. A zero Max is unbounded.
+type DurationBucket struct {
+	Name string
+	Min  int
+	Max  int
+}
+
+// DurationBuckets lists the duration facet values
+var DurationBuckets = []DurationBucket{
+	{Name: "short", Min: 0, Max: 30},
+	{Name: "medium", Min: 30, Max: 60},
+	{Name: "long", Min: 60, Max: 120},
+	{Name: "very_long", Min: 120},
+}
+
+// VideoFilter models the video facet filters. Values of a facet are
+// alternatives, facets are combined: category a or b, and rating 12.
+// WithSubcategories also matches the videos of subcategories. MaxRating
+// keeps the videos rated at most a level of a system, by their rating in
+// that system or else by the equivalent ClassInd one; it isn't a facet.
+type VideoFilter struct {
+	Categories        []string
+	WithSubcategories bool
+	Genres            []string
+	Ratings           []string
+	MaxRating         *rating.Ceiling
+	Decades           []int
+	Durations         []string
+	Opened            *bool
+}
+
+// FacetCount models a facet value and the videos having it
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// VideoFacets maps each facet to its value counts
+type VideoFacets map[string][]FacetCount
+
+// Empty tells whether the filter filters nothing
+func (f VideoFilter) Empty() bool {
+	return len(f.Categories) == 0 && len(f.Genres) == 0 && len(f.Ratings) == 0 &&
+		f.MaxRating == nil && len(f.Decades) == 0 && len(f.Durations) == 0 && f.Opened == nil
+}
+
+// Validate checks the filter values
+func (f VideoFilter) Validate() error {
+	for _, rating := range f.Ratings {
+		if err := validateRating(rating); err != nil {
+			return err
+		}
+	}
+	for _, decade := range f.Decades {
+		if decade%10 != 0 {
+			return fmt.Errorf("Decade %d must be a multiple of 10", decade)
+		}
+	}
+	for _, name := range f.Durations {
+		if _, ok := durationBucket(name); !ok {
+			return fmt.Errorf("Duration must be one of short, medium, long or very_long, got %q", name)
+		}
+	}
+	return nil
+}
+
+// Apply adds the filter conditions to a query over the videos table,
+// leaving out the except facet
+func (f VideoFilter) Apply(db *gorm.DB, except string) *gorm.DB {
+	if len(f.Categories) > 0 && except != FacetCategory {
+		if f.WithSubcategories {
+			db = db.Where("videos.id IN (SELECT video_id FROM category_video WHERE category_id IN ("+CategoryDescendantsSQL+"))", f.Categories)
+		} else {
+			db = db.Where("videos.id IN (SELECT video_id FROM category_video WHERE category_id IN (?))", f.Categories)
+		}
+	}
+	if len(f.Genres) > 0 && except != FacetGenre {
+		db = db.Where("videos.id IN (SELECT video_id FROM genre_video WHERE genre_id IN (?))", f.Genres)
+	}
+	if len(f.Ratings) > 0 && except != FacetRating {
+		db = db.Where("videos.rating IN (?)", f.Ratings)
+	}
+	if f.MaxRating != nil {
+		if f.MaxRating.System == rating.ClassInd.Name {
+			db = db.Where("videos.rating IN (?)", f.MaxRating.Levels)
+		} else {
+			db = db.Where("videos.id IN (SELECT video_id FROM video_ratings WHERE system = ? AND level IN (?)) OR "+
+				"(videos.id NOT IN (SELECT video_id FROM video_ratings WHERE system = ?) AND videos.rating IN (?))",
+				f.MaxRating.System, f.MaxRating.Levels, f.MaxRating.System, f.MaxRating.Reference)
+		}
+	}
+	if len(f.Decades) > 0 && except != FacetDecade {
+		db = db.Where("(videos.year_launched / 10) * 10 IN (?)", f.Decades)
+	}
+	if len(f.Durations) > 0 && except != FacetDuration {
+		conditions := []string{}
+		args := []interface{}{}
+		for _, name := range f.Durations {
+			bucket, _ := durationBucket(name)
+			if bucket.Max == 0 {
+				conditions = append(conditions, "videos.duration >= ?")
+				args = append(args, bucket.Min)
+			} else {
+				conditions = append(conditions, "(videos.duration >= ? AND videos.duration < ?)")
+				args = append(args, bucket.Min, bucket.Max)
+			}
+		}
+		db = db.Where(strings.Join(conditions, " OR "), args...)
+	}
+	if f.Opened != nil && except != FacetOpened {
+		db = db.Where("COALESCE(videos.opened, false) = ?", *f.Opened)
+	}
+	return db
+}
+
+// FindFiltered returns the videos matching filter
+func (v *Video) FindFiltered(db *gorm.DB, filter VideoFilter) (*[]Video, error) {
+	videos := []Video{}
+
+	if err := filter.Apply(db.Model(&Video{}), "").Find(&videos).Error; err != nil {
+		return &[]Video{}, err
+	}
+	if err := LoadVideoRelations(db, videos); err != nil {
+		return &[]Video{}, err
+	}
+
+	return &videos, nil
+}
+
+// CountVideoFacets counts the videos of base per facet value. The counts
+// of a facet respect every filter but its own, so picking a value keeps
+// the alternatives visible. base must select from videos, it may restrict
+// them further, e.g. to search hits.
+func CountVideoFacets(base *gorm.DB, filter VideoFilter) (VideoFacets, error) {
+	base = base.Table("videos").Where("videos.deleted_at IS NULL")
+	facets := VideoFacets{}
+
+	related := []struct {
+		facet string
+		join  string
+	}{
+		{FacetCategory, "JOIN category_video r ON r.video_id = videos.id JOIN categories e ON e.id = r.category_id AND e.deleted_at IS NULL"},
+		{FacetGenre, "JOIN genre_video r ON r.video_id = videos.id JOIN genres e ON e.id = r.genre_id AND e.deleted_at IS NULL"},
+	}
+	for _, r := range related {
+		counts, err := countFacet(filter.Apply(base, r.facet).Joins(r.join), "e.id", "e.name")
+		if err != nil {
+			return nil, err
+		}
+		facets[r.facet] = counts
+	}
+
+	ratings, err := countFacet(filter.Apply(base, FacetRating), "videos.rating", "''")
+	if err != nil {
+		return nil, err
+	}
+	facets[FacetRating] = fillFacet(RatingList, ratings)
+
+	decades, err := countFacet(filter.Apply(base, FacetDecade), "CAST((videos.year_launched / 10) * 10 AS text)", "''")
+	if err != nil {
+		return nil, err
+	}
+	facets[FacetDecade] = decades
+
+	cases := []string{}
+	names := []string{}
+	for _, bucket := range DurationBuckets {
+		names = append(names, bucket.Name)
+		if bucket.Max == 0 {
+			cases = append(cases, fmt.Sprintf("WHEN videos.duration >= %d THEN '%s'", bucket.Min, bucket.Name))
+		} else {
+			cases = append(cases, fmt.Sprintf("WHEN videos.duration >= %d AND videos.duration < %d THEN '%s'", bucket.Min, bucket.Max, bucket.Name))
+		}
+	}
+	durations, err := countFacet(filter.Apply(base, FacetDuration), "CASE "+strings.Join(cases, " ")+" END", "''")
+	if err != nil {
+		return nil, err
+	}
+	facets[FacetDuration] = fillFacet(names, durations)
+
+	opened, err := countFacet(filter.Apply(base, FacetOpened), "CAST(COALESCE(videos.opened, false) AS text)", "''")
+	if err != nil {
+		return nil, err
+	}
+	facets[FacetOpened] = fillFacet([]string{"true", "false"}, opened)
+
+	return facets, nil
+}
+
+// countFacet groups the videos of query by the value expression
+func countFacet(query *gorm.DB, value, label string) ([]FacetCount, error) {
+	counts := []FacetCount{}
+	err := query.
+		Select(value + " AS value, " + label + " AS label, count(DISTINCT videos.id) AS count").
+		Group(value + ", " + label).
+		Order("count DESC, value").
+		Scan(&counts).Error
+	for i := range counts {
+		// postgres answers "t" and "f" for booleans cast to text on old versions
+		if counts[i].Value == "t" || counts[i].Value == "f" {
+			counts[i].Value = strconv.FormatBool(counts[i].Value == "t")
+		}
+	}
+	return counts, err
+}
+
+// fillFacet orders counts as values, with zero counts for missing values
+func fillFacet(values []string, counts []FacetCount) []FacetCount {
+	found := map[string]int{}
+	for _, c := range counts {
+		found[c.Value] = c.Count
+	}
+	filled := []FacetCount{}
+	for _, v := range values {
+		filled = append(filled, FacetCount{Value: v, Count: found[v]})
+	}
+	return filled
+}
+
+func durationBucket(name string) (DurationBucket, bool) {
+	for _, b := range DurationBuckets {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return DurationBucket{}, false
+}
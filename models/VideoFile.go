@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// video file statuses
+const (
+	VideoFileStatusUploaded = "uploaded"
+	VideoFileStatusEncoded  = "encoded"
+	VideoFileStatusFailed   = "failed"
+)
+
+// ErrVideoFileNotFound is returned when a video file does not exist
+var ErrVideoFileNotFound = errors.New("Video file not found")
+
+// VideoFileTypes enum
+var VideoFileTypes = []string{"video", "trailer", "thumbnail", "banner"}
+
+// VideoFile models a file of a video, registered once stored and encoded by
+// the encoder service
+type VideoFile struct {
+	ID          string     `json:"id" gorm:"type:uuid;primary_key"`
+	VideoID     string     `json:"video_id" gorm:"type:uuid;index;not null"`
+	Type        string     `json:"type" gorm:"type:varchar(32);not null"`
+	Key         string     `json:"key" gorm:"type:varchar(255);not null"`
+	URL         string     `json:"url" gorm:"type:varchar(1024)"`
+	Size        int64      `json:"size"`
+	ContentType string     `json:"content_type" gorm:"type:varchar(255)"`
+	Status      string     `json:"status" gorm:"type:varchar(32);not null"`
+	OutputPath  string     `json:"output_path" gorm:"type:varchar(1024)"`
+	Error       string     `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" gorm:"autoCreateTime"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" gorm:"autoUpdateTime"`
+}
+
+// Validate validates basic struct
+func (f *VideoFile) Validate() error {
+	for _, t := range VideoFileTypes {
+		if t == f.Type {
+			return nil
+		}
+	}
+	return errors.New("File type must be video, trailer, thumbnail or banner")
+}
+
+// Create creates a new video file
+func (f *VideoFile) Create(db *gorm.DB) (*VideoFile, error) {
+	if err := db.Create(&f).Error; err != nil {
+		return &VideoFile{}, err
+	}
+
+	return f, nil
+}
+
+// FindByID searchs a video file by id
+func (f *VideoFile) FindByID(db *gorm.DB) error {
+	err := db.Take(&f).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrVideoFileNotFound
+	}
+	return err
+}
+
+// UpdateStatus stores the encoding status, output path and error
+func (f *VideoFile) UpdateStatus(db *gorm.DB) (*VideoFile, error) {
+	err := db.Model(&VideoFile{}).Where("id = ?", f.ID).Updates(map[string]interface{}{
+		"status":      f.Status,
+		"output_path": f.OutputPath,
+		"error":       f.Error,
+		"updated_at":  time.Now(),
+	}).Error
+	if err != nil {
+		return &VideoFile{}, err
+	}
+
+	err = db.Take(&f).Error
+	return f, err
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"video-catalog/rating"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Ratings maps rating system names to the level of a video in them
+type Ratings map[string]string
+
+// VideoRating holds the rating of a video in a system other than ClassInd,
+// kept in the rating field of the video
+type VideoRating struct {
+	VideoID string `gorm:"type:uuid;primary_key"`
+	System  string `gorm:"type:varchar(20);primary_key"`
+	Level   string `gorm:"type:varchar(10);not null"`
+}
+
+// TableName names the ratings table
+func (VideoRating) TableName() string {
+	return "video_ratings"
+}
+
+// Validate checks every rating against its system. A ClassInd rating must
+// agree with classind, the rating field, when both are given.
+func (r Ratings) Validate(classind string) error {
+	for name, level := range r {
+		system, ok := rating.Find(name)
+		if !ok {
+			return fmt.Errorf("Rating system %q is not supported", name)
+		}
+		if err := system.Validate(level); err != nil {
+			return fmt.Errorf("%s rating must be one of %v", system.Label, system.Levels)
+		}
+		if name == rating.ClassInd.Name && classind != "" && level != classind {
+			return fmt.Errorf("%s rating must match the rating", system.Label)
+		}
+	}
+	return nil
+}
+
+// syncRatings sets the ratings of the video in the systems other than
+// ClassInd given. Systems left out keep their rating, nil ratings leave
+// them all untouched.
+func (v *Video) syncRatings(db *gorm.DB) error {
+	for system, level := range v.Ratings {
+		if system == rating.ClassInd.Name {
+			continue
+		}
+		err := db.Exec(`INSERT INTO video_ratings (video_id, system, level) VALUES (?, ?, ?)
+			ON CONFLICT (video_id, system) DO UPDATE SET level = EXCLUDED.level`,
+			v.ID, system, level).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRatings sets the ratings of every indexed video, its ClassInd rating
+// included
+func loadRatings(db *gorm.DB, ids []string, index map[string]*Video) error {
+	for _, v := range index {
+		v.Ratings = Ratings{}
+		if v.Rating != "" {
+			v.Ratings[rating.ClassInd.Name] = v.Rating
+		}
+	}
+
+	ratings := []VideoRating{}
+	if err := db.Where("video_id IN (?)", ids).Find(&ratings).Error; err != nil {
+		return err
+	}
+	for _, r := range ratings {
+		index[r.VideoID].Ratings[r.System] = r.Level
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// VideoCategory links a video to one of its categories
+type VideoCategory struct {
+	VideoID    string `gorm:"type:uuid;primary_key"`
+	CategoryID string `gorm:"type:uuid;primary_key;index"`
+}
+
+// TableName names the join table
+func (VideoCategory) TableName() string {
+	return "category_video"
+}
+
+// VideoGenre links a video to one of its genres
+type VideoGenre struct {
+	VideoID string `gorm:"type:uuid;primary_key"`
+	GenreID string `gorm:"type:uuid;primary_key;index"`
+}
+
+// TableName names the join table
+func (VideoGenre) TableName() string {
+	return "genre_video"
+}
+
+// ValidateRelations checks that the categories and genres given exist
+func (v *Video) ValidateRelations(db *gorm.DB) error {
+	if err := validateRelated(db, &Category{}, v.CategoriesID); err != nil {
+		return errors.New("Categories must be existing category ids")
+	}
+	if err := validateRelated(db, &Genre{}, v.GenresID); err != nil {
+		return errors.New("Genres must be existing genre ids")
+	}
+	return nil
+}
+
+func validateRelated(db *gorm.DB, model interface{}, ids []string) error {
+	ids = uniqueIDs(ids)
+	for _, id := range ids {
+		if _, err := uuid.FromString(id); err != nil {
+			return err
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	count := 0
+	if err := db.Model(model).Where("id IN (?)", ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if count != len(ids) {
+		return errors.New("unknown id")
+	}
+	return nil
+}
+
+// SyncRelations replaces the categories and genres of the video with the
+// ones given and sets its ratings in the systems given. A nil list leaves
+// the relation untouched.
+func (v *Video) SyncRelations(db *gorm.DB) error {
+	if v.CategoriesID != nil {
+		if err := db.Where("video_id = ?", v.ID).Delete(&VideoCategory{}).Error; err != nil {
+			return err
+		}
+		for _, id := range uniqueIDs(v.CategoriesID) {
+			if err := db.Create(&VideoCategory{VideoID: v.ID, CategoryID: id}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if v.GenresID != nil {
+		if err := db.Where("video_id = ?", v.ID).Delete(&VideoGenre{}).Error; err != nil {
+			return err
+		}
+		for _, id := range uniqueIDs(v.GenresID) {
+			if err := db.Create(&VideoGenre{VideoID: v.ID, GenreID: id}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return v.syncRatings(db)
+}
+
+// RestoreRelations sets the categories, genres and ratings of the video to
+// the ones of a snapshot, removing the ratings in systems it doesn't hold
+func (v *Video) RestoreRelations(db *gorm.DB) error {
+	if v.Ratings != nil {
+		systems := []string{}
+		for system := range v.Ratings {
+			systems = append(systems, system)
+		}
+		query := db.Where("video_id = ?", v.ID)
+		if len(systems) > 0 {
+			query = query.Where("system NOT IN (?)", systems)
+		}
+		if err := query.Delete(&VideoRating{}).Error; err != nil {
+			return err
+		}
+	}
+	return v.SyncRelations(db)
+}
+
+// LoadRelations sets the category and genre ids and the ratings of the
+// video
+func (v *Video) LoadRelations(db *gorm.DB) error {
+	videos := []Video{*v}
+	if err := LoadVideoRelations(db, videos); err != nil {
+		return err
+	}
+	v.CategoriesID = videos[0].CategoriesID
+	v.GenresID = videos[0].GenresID
+	v.Ratings = videos[0].Ratings
+	return nil
+}
+
+// LoadVideoRelations sets the category and genre ids and the ratings of
+// every video with one query per relation
+func LoadVideoRelations(db *gorm.DB, videos []Video) error {
+	if len(videos) == 0 {
+		return nil
+	}
+	ids := []string{}
+	index := map[string]*Video{}
+	for i := range videos {
+		videos[i].CategoriesID = []string{}
+		videos[i].GenresID = []string{}
+		ids = append(ids, videos[i].ID)
+		index[videos[i].ID] = &videos[i]
+	}
+
+	categories := []VideoCategory{}
+	if err := db.Where("video_id IN (?)", ids).Order("category_id").Find(&categories).Error; err != nil {
+		return err
+	}
+	for _, c := range categories {
+		index[c.VideoID].CategoriesID = append(index[c.VideoID].CategoriesID, c.CategoryID)
+	}
+
+	genres := []VideoGenre{}
+	if err := db.Where("video_id IN (?)", ids).Order("genre_id").Find(&genres).Error; err != nil {
+		return err
+	}
+	for _, g := range genres {
+		index[g.VideoID].GenresID = append(index[g.VideoID].GenresID, g.GenreID)
+	}
+	return loadRatings(db, ids, index)
+}
+
+func uniqueIDs(ids []string) []string {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,277 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// ErrWebhookNotFound is returned when a webhook subscription does not exist
+var ErrWebhookNotFound = errors.New("Webhook not found")
+
+// Webhook models a subscription of an http endpoint to catalog events.
+// EventTypes holds routing key patterns such as video.* or category.created.
+type Webhook struct {
+	ID                  string     `json:"id" gorm:"type:uuid;primary_key"`
+	URL                 string     `json:"url" gorm:"type:varchar(2048);not null"`
+	EventTypes          StringList `json:"event_types" gorm:"type:text;not null"`
+	Secret              string     `json:"-" gorm:"type:varchar(255);not null"`
+	Active              bool       `json:"active" gorm:"not null;default:true"`
+	ConsecutiveFailures int        `json:"consecutive_failures" gorm:"not null;default:0"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	CreatedBy           string     `json:"created_by" gorm:"type:varchar(255)"`
+	CreatedAt           *time.Time `json:"created_at,omitempty" gorm:"autoCreateTime"`
+	UpdatedAt           *time.Time `json:"updated_at,omitempty" gorm:"autoUpdateTime"`
+	DeletedAt           *time.Time `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// WebhookDelivery models the delivery of one event to one webhook and the
+// outcome of its latest attempt
+type WebhookDelivery struct {
+	ID             string     `json:"id" gorm:"type:uuid;primary_key"`
+	WebhookID      string     `json:"webhook_id" gorm:"type:uuid;not null;unique_index:idx_webhook_delivery_event"`
+	EventID        string     `json:"event_id" gorm:"type:uuid;not null;unique_index:idx_webhook_delivery_event"`
+	EventType      string     `json:"event_type" gorm:"type:varchar(128);not null"`
+	Payload        JSON       `json:"-" gorm:"type:jsonb;not null"`
+	Status         string     `json:"status" gorm:"type:varchar(32);not null;index"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" gorm:"not null"`
+	ResponseStatus int        `json:"response_status"`
+	LastError      string     `json:"last_error,omitempty" gorm:"type:text"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Prepare prepares values
+func (w *Webhook) Prepare() {
+	w.URL = strings.TrimSpace(w.URL)
+	types := StringList{}
+	for _, t := range w.EventTypes {
+		if t = strings.TrimSpace(t); t != "" && !types.Contains(t) {
+			types = append(types, t)
+		}
+	}
+	w.EventTypes = types
+}
+
+// Validate validates basic struct
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("Webhook url must be an absolute http or https url")
+	}
+	if len(w.EventTypes) == 0 {
+		return errors.New("Webhook must have at least one event type")
+	}
+	for _, t := range w.EventTypes {
+		if strings.Contains(t, ",") || strings.Contains(t, " ") {
+			return errors.New("Webhook event types must not contain commas or spaces")
+		}
+	}
+	if len(w.Secret) < 16 || len(w.Secret) > 255 {
+		return errors.New("Webhook secret must be between 16 and 255 characters")
+	}
+	return nil
+}
+
+// Create creates a new webhook
+func (w *Webhook) Create(db *gorm.DB) (*Webhook, error) {
+	if err := db.Create(&w).Error; err != nil {
+		return &Webhook{}, err
+	}
+
+	return w, nil
+}
+
+// FindAll returns every webhook, active or not
+func (w *Webhook) FindAll(db *gorm.DB) (*[]Webhook, error) {
+	webhooks := []Webhook{}
+
+	if err := db.Model(&Webhook{}).Order("created_at").Find(&webhooks).Error; err != nil {
+		return &[]Webhook{}, err
+	}
+
+	return &webhooks, nil
+}
+
+// FindActive returns the webhooks receiving events
+func (w *Webhook) FindActive(db *gorm.DB) (*[]Webhook, error) {
+	webhooks := []Webhook{}
+
+	if err := db.Model(&Webhook{}).Where("active = ?", true).Find(&webhooks).Error; err != nil {
+		return &[]Webhook{}, err
+	}
+
+	return &webhooks, nil
+}
+
+// FindByID searchs a webhook by id
+func (w *Webhook) FindByID(db *gorm.DB) error {
+	err := db.Take(&w).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+// Update saves the url, event types, secret and active flag. Activating a
+// webhook clears its failures.
+func (w *Webhook) Update(db *gorm.DB) (*Webhook, error) {
+	if w.Active {
+		w.ConsecutiveFailures = 0
+		w.DisabledAt = nil
+	}
+	err := db.Model(&Webhook{}).Where("id = ?", w.ID).Updates(map[string]interface{}{
+		"url":                  w.URL,
+		"event_types":          w.EventTypes,
+		"secret":               w.Secret,
+		"active":               w.Active,
+		"consecutive_failures": w.ConsecutiveFailures,
+		"disabled_at":          w.DisabledAt,
+		"updated_at":           time.Now(),
+	}).Error
+	if err != nil {
+		return &Webhook{}, err
+	}
+
+	err = db.Take(&w).Error
+	return w, err
+}
+
+// Delete deletes a webhook
+func (w *Webhook) Delete(db *gorm.DB) error {
+	result := db.Delete(&w)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// RecordAttempt counts a delivery success or failure. The webhook is
+// disabled once it failed disableAfter times in a row.
+func (w *Webhook) RecordAttempt(db *gorm.DB, ok bool, disableAfter int) error {
+	if ok {
+		if w.ConsecutiveFailures == 0 {
+			return nil
+		}
+		w.ConsecutiveFailures = 0
+		return db.Model(&Webhook{}).Where("id = ?", w.ID).Update("consecutive_failures", 0).Error
+	}
+
+	w.ConsecutiveFailures++
+	values := map[string]interface{}{"consecutive_failures": gorm.Expr("consecutive_failures + 1")}
+	if w.ConsecutiveFailures >= disableAfter {
+		now := time.Now()
+		w.Active = false
+		w.DisabledAt = &now
+		values["active"] = false
+		values["disabled_at"] = now
+	}
+	return db.Model(&Webhook{}).Where("id = ?", w.ID).Updates(values).Error
+}
+
+// Create creates a new delivery, ignoring events already delivered to the
+// webhook
+func (d *WebhookDelivery) Create(db *gorm.DB) (*WebhookDelivery, error) {
+	existing := 0
+	err := db.Model(&WebhookDelivery{}).Where("webhook_id = ? AND event_id = ?", d.WebhookID, d.EventID).Count(&existing).Error
+	if err != nil {
+		return &WebhookDelivery{}, err
+	}
+	if existing > 0 {
+		return d, nil
+	}
+
+	if err := db.Create(&d).Error; err != nil {
+		return &WebhookDelivery{}, err
+	}
+
+	return d, nil
+}
+
+// FindAll returns the deliveries of the webhook, newest first
+func (d *WebhookDelivery) FindAll(db *gorm.DB, limit, offset int) (*[]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+
+	err := db.Model(&WebhookDelivery{}).Where("webhook_id = ?", d.WebhookID).
+		Order("created_at desc").Limit(limit).Offset(offset).Find(&deliveries).Error
+	if err != nil {
+		return &[]WebhookDelivery{}, err
+	}
+
+	return &deliveries, nil
+}
+
+// ClaimDue claims up to limit pending deliveries of active webhooks whose
+// next attempt is due, pushing their next attempt to until so no other
+// sender takes them while they are posted. db must be a transaction.
+func (d *WebhookDelivery) ClaimDue(db *gorm.DB, now, until time.Time, limit int) (*[]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+
+	err := db.Raw(`SELECT webhook_deliveries.* FROM webhook_deliveries
+		JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id AND webhooks.active AND webhooks.deleted_at IS NULL
+		WHERE webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?
+		ORDER BY webhook_deliveries.created_at LIMIT ?
+		FOR UPDATE OF webhook_deliveries SKIP LOCKED`, WebhookDeliveryPending, now, limit).Scan(&deliveries).Error
+	if err != nil {
+		return &[]WebhookDelivery{}, err
+	}
+	if len(deliveries) == 0 {
+		return &deliveries, nil
+	}
+
+	ids := []string{}
+	for i := range deliveries {
+		ids = append(ids, deliveries[i].ID)
+		deliveries[i].NextAttemptAt = until
+	}
+	err = db.Model(&WebhookDelivery{}).Where("id IN (?)", ids).Update("next_attempt_at", until).Error
+	if err != nil {
+		return &[]WebhookDelivery{}, err
+	}
+
+	return &deliveries, nil
+}
+
+// Release gives up the claim on a pending delivery, making it due again
+func (d *WebhookDelivery) Release(db *gorm.DB, at time.Time) error {
+	d.NextAttemptAt = at
+	return db.Model(&WebhookDelivery{}).Where("id = ? AND status = ?", d.ID, WebhookDeliveryPending).
+		Update("next_attempt_at", at).Error
+}
+
+// FindByID searchs a delivery of the webhook by id
+func (d *WebhookDelivery) FindByID(db *gorm.DB) error {
+	err := db.Where("webhook_id = ?", d.WebhookID).Take(&d).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return errors.New("Webhook delivery not found")
+	}
+	return err
+}
+
+// Save stores the outcome of the latest attempt
+func (d *WebhookDelivery) Save(db *gorm.DB) error {
+	return db.Model(&WebhookDelivery{}).Where("id = ?", d.ID).Updates(map[string]interface{}{
+		"status":          d.Status,
+		"attempts":        d.Attempts,
+		"next_attempt_at": d.NextAttemptAt,
+		"response_status": d.ResponseStatus,
+		"last_error":      d.LastError,
+		"delivered_at":    d.DeliveredAt,
+		"updated_at":      time.Now(),
+	}).Error
+}
@@ -0,0 +1,169 @@
+// Package rating models the content rating systems videos are classified
+// in and the equivalences between their levels
+package rating
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errors returned when checking ratings
+var (
+	ErrUnknownSystem = errors.New("Rating system is not supported")
+	ErrUnknownLevel  = errors.New("Rating must be a valid value")
+)
+
+// System models a content rating system, its levels ordered from the
+// least to the most restrictive
+type System struct {
+	Name    string   `json:"name"`
+	Label   string   `json:"label"`
+	Country string   `json:"country"`
+	Levels  []string `json:"levels"`
+}
+
+// rating systems
+var (
+	ClassInd = System{Name: "classind", Label: "ClassInd", Country: "BR", Levels: []string{"L", "10", "12", "14", "16", "18"}}
+	MPAA     = System{Name: "mpaa", Label: "MPAA", Country: "US", Levels: []string{"G", "PG", "PG-13", "R", "NC-17"}}
+	PEGI     = System{Name: "pegi", Label: "PEGI", Country: "EU", Levels: []string{"3", "7", "12", "16", "18"}}
+)
+
+// Systems lists the supported rating systems. ClassInd, the first, is the
+// reference the others are made equivalent to.
+var Systems = []System{ClassInd, MPAA, PEGI}
+
+// DefaultEquivalences maps the levels of every system to ClassInd ones
+const DefaultEquivalences = "mpaa:G=L,mpaa:PG=10,mpaa:PG-13=12,mpaa:R=16,mpaa:NC-17=18," +
+	"pegi:3=L,pegi:7=10,pegi:12=12,pegi:16=16,pegi:18=18"
+
+// Find returns the system named name
+func Find(name string) (System, bool) {
+	for _, s := range Systems {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return System{}, false
+}
+
+// Rank returns the position of level in the system, -1 when unknown
+func (s System) Rank(level string) int {
+	for i, l := range s.Levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validate checks that level belongs to the system
+func (s System) Validate(level string) error {
+	if s.Rank(level) < 0 {
+		return ErrUnknownLevel
+	}
+	return nil
+}
+
+// upTo returns the levels of the system as restrictive as rank or less
+func (s System) upTo(rank int) []string {
+	return append([]string{}, s.Levels[:rank+1]...)
+}
+
+// Ceiling models a "max rating" limit: the levels allowed in its system
+// and, for videos not rated in it, the equivalent ClassInd levels
+type Ceiling struct {
+	System    string
+	Levels    []string
+	Reference []string
+}
+
+// Equivalences maps the levels of every system to the rank of the
+// equivalent ClassInd level
+type Equivalences struct {
+	ranks map[string][]int
+}
+
+// ParseEquivalences builds the equivalences from comma separated
+// system:level=classind pairs. Every level of every system must be mapped,
+// and more restrictive levels can't map to less restrictive ones.
+func ParseEquivalences(value string) (*Equivalences, error) {
+	e := &Equivalences{ranks: map[string][]int{}}
+	for _, s := range Systems {
+		ranks := make([]int, len(s.Levels))
+		for i := range ranks {
+			ranks[i] = -1
+		}
+		e.ranks[s.Name] = ranks
+	}
+	for i := range ClassInd.Levels {
+		e.ranks[ClassInd.Name][i] = i
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		source := strings.SplitN(parts[0], ":", 2)
+		if len(parts) != 2 || len(source) != 2 {
+			return nil, fmt.Errorf("equivalence %q must be system:level=%s level", pair, ClassInd.Label)
+		}
+		system, ok := Find(strings.ToLower(strings.TrimSpace(source[0])))
+		if !ok || system.Name == ClassInd.Name {
+			return nil, fmt.Errorf("equivalence %q: unknown system %q", pair, source[0])
+		}
+		rank := system.Rank(strings.TrimSpace(source[1]))
+		if rank < 0 {
+			return nil, fmt.Errorf("equivalence %q: unknown %s level %q", pair, system.Label, source[1])
+		}
+		reference := ClassInd.Rank(strings.TrimSpace(parts[1]))
+		if reference < 0 {
+			return nil, fmt.Errorf("equivalence %q: unknown %s level %q", pair, ClassInd.Label, parts[1])
+		}
+		e.ranks[system.Name][rank] = reference
+	}
+
+	for _, s := range Systems {
+		for i, reference := range e.ranks[s.Name] {
+			if reference < 0 {
+				return nil, fmt.Errorf("%s level %s has no equivalence", s.Label, s.Levels[i])
+			}
+			if i > 0 && reference < e.ranks[s.Name][i-1] {
+				return nil, fmt.Errorf("%s level %s is equivalent to a less restrictive level than %s", s.Label, s.Levels[i], s.Levels[i-1])
+			}
+		}
+	}
+	return e, nil
+}
+
+// Ceiling returns the limit of videos rated at most level in system
+func (e *Equivalences) Ceiling(system, level string) (Ceiling, error) {
+	s, ok := Find(system)
+	if !ok {
+		return Ceiling{}, ErrUnknownSystem
+	}
+	rank := s.Rank(level)
+	if rank < 0 {
+		return Ceiling{}, ErrUnknownLevel
+	}
+	return Ceiling{
+		System:    s.Name,
+		Levels:    s.upTo(rank),
+		Reference: ClassInd.upTo(e.ranks[s.Name][rank]),
+	}, nil
+}
+
+// Equivalent returns the ClassInd level equivalent to level in system
+func (e *Equivalences) Equivalent(system, level string) (string, error) {
+	s, ok := Find(system)
+	if !ok {
+		return "", ErrUnknownSystem
+	}
+	rank := s.Rank(level)
+	if rank < 0 {
+		return "", ErrUnknownLevel
+	}
+	return ClassInd.Levels[e.ranks[s.Name][rank]], nil
+}
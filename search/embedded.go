@@ -0,0 +1,378 @@
+package search
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"video-catalog/events"
+	"video-catalog/models"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// syncBatch bounds the outbox messages applied per sync
+const syncBatch = 500
+
+// rebuildBatch bounds the rows read per query during a rebuild
+const rebuildBatch = 1000
+
+// Embedded searches an inverted index kept in memory and saved to a
+// directory. The index follows the catalog by reading the outbox, so
+// catalog writes never wait for it, and searches only wait for the short
+// updates of single documents.
+type Embedded struct {
+	db    *gorm.DB
+	index *Index
+	dir   string
+
+	syncInterval  time.Duration
+	flushInterval time.Duration
+
+	loaded   bool
+	fileTime time.Time
+	tail     events.OutboxTail
+}
+
+// NewEmbedded returns a backend indexing db into dir. The index is empty
+// until loaded or rebuilt.
+func NewEmbedded(db *gorm.DB, dir string, syncInterval, flushInterval time.Duration) *Embedded {
+	return &Embedded{
+		db:            db,
+		index:         NewIndex(),
+		dir:           dir,
+		syncInterval:  syncInterval,
+		flushInterval: flushInterval,
+	}
+}
+
+// Index returns the index searched
+func (e *Embedded) Index() *Index {
+	return e.index
+}
+
+// Load replaces the index with the one saved in the directory
+func (e *Embedded) Load() error {
+	index, err := LoadIndex(e.dir)
+	if err != nil {
+		return err
+	}
+	e.index.replace(index, true)
+	e.loaded = true
+	e.fileTime = e.modTime()
+	return nil
+}
+
+// Save writes the index to the directory
+func (e *Embedded) Save() error {
+	if err := e.index.Save(e.dir); err != nil {
+		return err
+	}
+	e.fileTime = e.modTime()
+	return nil
+}
+
+func (e *Embedded) modTime() time.Time {
+	info, err := os.Stat(filepath.Join(e.dir, indexFile))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Run keeps the index in sync with the catalog until ctx is cancelled. A
+// missing or outdated index is rebuilt first; the index is saved
+// periodically and on exit.
+func (e *Embedded) Run(ctx context.Context) error {
+	rebuild, err := e.stale()
+	if err != nil {
+		log.Printf("Error checking search index: %v", err)
+	}
+	if rebuild {
+		started := time.Now()
+		count, err := e.Rebuild()
+		if err != nil {
+			log.Printf("Error rebuilding search index: %v", err)
+		} else {
+			log.Printf("Search index rebuilt with %d document(s) in %s", count, time.Since(started))
+			if err := e.Save(); err != nil {
+				log.Printf("Error saving search index: %v", err)
+			}
+		}
+	}
+
+	syncTicker := time.NewTicker(e.syncInterval)
+	defer syncTicker.Stop()
+	flushTicker := time.NewTicker(e.flushInterval)
+	defer flushTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if e.index.Dirty() {
+				if err := e.Save(); err != nil {
+					log.Printf("Error saving search index: %v", err)
+				}
+			}
+			return ctx.Err()
+		case <-syncTicker.C:
+			if err := e.Sync(); err != nil {
+				log.Printf("Error syncing search index: %v", err)
+			}
+		case <-flushTicker.C:
+			e.flush()
+		}
+	}
+}
+
+// flush saves the index, unless a reindex wrote a newer one meanwhile, in
+// which case that one is loaded
+func (e *Embedded) flush() {
+	if e.modTime().After(e.fileTime) {
+		if err := e.Load(); err != nil {
+			log.Printf("Error loading reindexed search index: %v", err)
+			return
+		}
+		log.Printf("Search index reloaded with %d document(s)", e.index.Len())
+		return
+	}
+	if e.index.Dirty() {
+		if err := e.Save(); err != nil {
+			log.Printf("Error saving search index: %v", err)
+		}
+	}
+}
+
+// stale tells whether the index misses changes the outbox no longer holds.
+// Published messages are purged, so when the message following the index
+// sequence is gone the index can only be rebuilt.
+func (e *Embedded) stale() (bool, error) {
+	if !e.loaded {
+		return true, nil
+	}
+	sequence := e.index.Sequence()
+	if sequence == 0 {
+		return true, nil
+	}
+
+	outbox := models.OutboxMessage{}
+	kept := 0
+	if err := e.db.Model(&outbox).Where("sequence <= ?", sequence).Count(&kept).Error; err != nil {
+		return true, err
+	}
+	return kept == 0, nil
+}
+
+// Rebuild indexes the whole catalog again and returns the number of
+// documents indexed. Searches keep using the previous index meanwhile.
+func (e *Embedded) Rebuild() (int, error) {
+	outbox := models.OutboxMessage{}
+	latest, err := outbox.FindLatest(e.db, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	index := NewIndex()
+	for _, t := range Types {
+		if err := e.scan(t, index); err != nil {
+			return 0, err
+		}
+	}
+	if len(*latest) > 0 {
+		index.sequence = (*latest)[0].Sequence
+	}
+
+	e.index.replace(index, false)
+	e.tail = events.OutboxTail{}
+	return index.Len(), nil
+}
+
+// scan puts every live entity of a type into index, in batches
+func (e *Embedded) scan(docType string, index *Index) error {
+	query := e.db.Order("id").Limit(rebuildBatch)
+	for {
+		docs, err := e.documents(query, docType)
+		if err != nil {
+			return err
+		}
+		for n := range docs {
+			index.put(&docs[n])
+		}
+		if len(docs) < rebuildBatch {
+			return nil
+		}
+		query = e.db.Where("id > ?", docs[len(docs)-1].ID).Order("id").Limit(rebuildBatch)
+	}
+}
+
+// Sync applies the catalog changes written to the outbox since the last
+// sync. Changed entities are read again, so applying a change twice is
+// harmless.
+func (e *Embedded) Sync() error {
+	e.tail.Last = e.index.Sequence()
+	messages, err := e.tail.Next(e.db, syncBatch)
+	if err != nil {
+		return err
+	}
+
+	changed := map[string][]string{}
+	for _, m := range messages {
+		// changes of a whole type, such as reorders, name no entity
+		if _, err := uuid.FromString(m.AggregateID); err != nil {
+			continue
+		}
+		if _, ok := searchTables[m.AggregateType]; ok {
+			changed[m.AggregateType] = append(changed[m.AggregateType], m.AggregateID)
+		}
+	}
+
+	for docType, ids := range changed {
+		docs, err := e.documents(e.db.Where("id IN (?)", ids), docType)
+		if err != nil {
+			return err
+		}
+		live := map[string]bool{}
+		for _, doc := range docs {
+			live[doc.ID] = true
+			e.index.Put(doc)
+		}
+		for _, id := range ids {
+			if !live[id] {
+				e.index.Remove(docType, id)
+			}
+		}
+	}
+	e.index.SetSequence(e.tail.Last)
+	return nil
+}
+
+// documents reads the live entities of a type selected by query
+func (e *Embedded) documents(query *gorm.DB, docType string) ([]Document, error) {
+	docs := []Document{}
+	switch docType {
+	case TypeVideo:
+		list := []models.Video{}
+		if err := query.Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for _, v := range list {
+			docs = append(docs, Document{Type: docType, ID: v.ID, Title: v.Title, Fields: []Field{
+				{Name: "title", Text: v.Title, Weight: WeightTitle},
+				{Name: "description", Text: v.Description, Weight: WeightBody},
+			}})
+		}
+	case TypeCategory:
+		list := []models.Category{}
+		if err := query.Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			docs = append(docs, Document{Type: docType, ID: c.ID, Title: c.Name, Fields: []Field{
+				{Name: "name", Text: c.Name, Weight: WeightTitle},
+				{Name: "description", Text: c.Description, Weight: WeightBody},
+			}})
+		}
+	case TypeGenre:
+		list := []models.Genre{}
+		if err := query.Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for _, g := range list {
+			docs = append(docs, Document{Type: docType, ID: g.ID, Title: g.Name, Fields: []Field{
+				{Name: "name", Text: g.Name, Weight: WeightTitle},
+			}})
+		}
+	case TypeCastMember:
+		list := []models.CastMember{}
+		if err := query.Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			docs = append(docs, Document{Type: docType, ID: c.ID, Title: c.Name, Fields: []Field{
+				{Name: "name", Text: c.Name, Weight: WeightTitle},
+			}})
+		}
+	}
+	return docs, nil
+}
+
+// Search ranks the indexed entities of every requested type together.
+// Video filters and facets are answered by the database over the matching
+// videos.
+func (e *Embedded) Search(ctx context.Context, q Query) (Result, error) {
+	terms := Terms(q.Text)
+	if strings.TrimSpace(q.Text) == "" || len(terms) == 0 {
+		return Result{}, ErrEmptyQuery
+	}
+	types := q.Types
+	if len(types) == 0 {
+		types = Types
+	}
+	if !q.Filter.Empty() {
+		types = onlyVideos(types)
+	}
+
+	hits := e.index.Match(terms, types)
+	if !q.Filter.Empty() && len(hits) > 0 {
+		allowed, err := e.filterVideos(hits, q.Filter)
+		if err != nil {
+			return Result{}, err
+		}
+		kept := hits[:0]
+		for _, hit := range hits {
+			if allowed[hit.ID] {
+				kept = append(kept, hit)
+			}
+		}
+		hits = kept
+	}
+
+	result := Result{Total: len(hits), Types: types, Hits: []Hit{}}
+	if q.Offset < len(hits) {
+		end := q.Offset + q.Limit
+		if end > len(hits) {
+			end = len(hits)
+		}
+		for _, hit := range hits[q.Offset:end] {
+			e.index.Highlight(&hit, terms)
+			result.Hits = append(result.Hits, hit)
+		}
+	}
+
+	if q.Facets {
+		matching := e.db.Where("false")
+		if ids := hitIDs(e.index.Match(terms, []string{TypeVideo})); len(ids) > 0 {
+			matching = e.db.Where("videos.id IN (?)", ids)
+		}
+		facets, err := models.CountVideoFacets(matching, q.Filter)
+		if err != nil {
+			return Result{}, err
+		}
+		result.Facets = facets
+	}
+	return result, nil
+}
+
+// filterVideos returns the ids of the video hits matching filter
+func (e *Embedded) filterVideos(hits []Hit, filter models.VideoFilter) (map[string]bool, error) {
+	ids := []string{}
+	query := e.db.Table("videos").Where("videos.deleted_at IS NULL AND videos.id IN (?)", hitIDs(hits))
+	if err := filter.Apply(query, "").Pluck("videos.id", &ids).Error; err != nil {
+		return nil, err
+	}
+	allowed := map[string]bool{}
+	for _, id := range ids {
+		allowed[id] = true
+	}
+	return allowed, nil
+}
+
+func hitIDs(hits []Hit) []string {
+	ids := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
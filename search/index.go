@@ -0,0 +1,424 @@
+package search
+
+import (
+	"encoding/gob"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"video-catalog/slug"
+)
+
+// indexVersion is bumped when the on-disk format changes, older files are
+// then rebuilt
+const indexVersion = 1
+
+// indexFile is the name of the index file in the index directory
+const indexFile = "catalog.idx"
+
+// field weights, matching the postgres ranking of titles over descriptions
+const (
+	WeightTitle = 1.0
+	WeightBody  = 0.4
+)
+
+// bm25K bounds the score a repeated term can add
+const bm25K = 1.2
+
+// highlightWords bounds the words of a highlight fragment
+const highlightWords = 25
+
+// Field models an indexed text of a document
+type Field struct {
+	Name   string
+	Text   string
+	Weight float64
+}
+
+// Document models an indexed entity
+type Document struct {
+	Type   string
+	ID     string
+	Title  string
+	Fields []Field
+}
+
+func (d Document) key() string {
+	return d.Type + "/" + d.ID
+}
+
+// Index is an in-memory inverted index of the catalog. Words are matched
+// case and accent insensitive by their Portuguese stem, stop words are
+// ignored, as in the postgres backend. Every query word must match.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*Document
+	postings map[string]map[string]float64
+	sequence uint64
+
+	generation uint64
+	saved      uint64
+}
+
+// NewIndex returns an empty index
+func NewIndex() *Index {
+	return &Index{docs: map[string]*Document{}, postings: map[string]map[string]float64{}}
+}
+
+// Put indexes doc, replacing the document with the same type and id
+func (i *Index) Put(doc Document) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(doc.key())
+	i.put(&doc)
+	i.generation++
+}
+
+// Remove drops a document from the index
+func (i *Index) Remove(docType, id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(docType + "/" + id)
+	i.generation++
+}
+
+func (i *Index) put(doc *Document) {
+	key := doc.key()
+	i.docs[key] = doc
+	for _, field := range doc.Fields {
+		for _, t := range tokenize(field.Text) {
+			term, ok := indexTerm(t.term)
+			if !ok {
+				continue
+			}
+			postings, ok := i.postings[term]
+			if !ok {
+				postings = map[string]float64{}
+				i.postings[term] = postings
+			}
+			postings[key] += field.Weight
+		}
+	}
+}
+
+func (i *Index) remove(key string) {
+	doc, ok := i.docs[key]
+	if !ok {
+		return
+	}
+	for _, field := range doc.Fields {
+		for _, t := range tokenize(field.Text) {
+			term, ok := indexTerm(t.term)
+			if !ok {
+				continue
+			}
+			if postings, ok := i.postings[term]; ok {
+				delete(postings, key)
+				if len(postings) == 0 {
+					delete(i.postings, term)
+				}
+			}
+		}
+	}
+	delete(i.docs, key)
+}
+
+// Len returns the number of indexed documents
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.docs)
+}
+
+// Sequence returns the last outbox sequence applied to the index
+func (i *Index) Sequence() uint64 {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.sequence
+}
+
+// SetSequence records the last outbox sequence applied to the index
+func (i *Index) SetSequence(sequence uint64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if sequence != i.sequence {
+		i.sequence = sequence
+		i.generation++
+	}
+}
+
+// Dirty tells whether the index changed since it was last saved or loaded
+func (i *Index) Dirty() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.generation != i.saved
+}
+
+// replace swaps the content of the index with the one of other. A saved
+// content is not dirty.
+func (i *Index) replace(other *Index, saved bool) {
+	other.mu.RLock()
+	docs, postings, sequence := other.docs, other.postings, other.sequence
+	other.mu.RUnlock()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.docs, i.postings, i.sequence = docs, postings, sequence
+	i.generation++
+	if saved {
+		i.saved = i.generation
+	}
+}
+
+// Match returns the documents of types matching every word of terms, best
+// first. Hits have no highlights, see Highlight.
+func (i *Index) Match(words []string, types []string) []Hit {
+	terms := indexTerms(words)
+	if len(terms) == 0 {
+		return []Hit{}
+	}
+	allowed := map[string]bool{}
+	for _, t := range types {
+		allowed[t] = true
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	lists := make([]map[string]float64, 0, len(terms))
+	for _, term := range terms {
+		postings, ok := i.postings[term]
+		if !ok {
+			return []Hit{}
+		}
+		lists = append(lists, postings)
+	}
+	// the rarest term bounds the candidates
+	sort.Slice(lists, func(a, b int) bool { return len(lists[a]) < len(lists[b]) })
+
+	total := float64(len(i.docs))
+	hits := []Hit{}
+	for key := range lists[0] {
+		doc := i.docs[key]
+		if !allowed[doc.Type] {
+			continue
+		}
+		score := 0.0
+		for _, postings := range lists {
+			tf, ok := postings[key]
+			if !ok {
+				score = -1
+				break
+			}
+			df := float64(len(postings))
+			idf := math.Log(1 + (total-df+0.5)/(df+0.5))
+			score += idf * tf * (bm25K + 1) / (tf + bm25K)
+		}
+		if score < 0 {
+			continue
+		}
+		hits = append(hits, Hit{Type: doc.Type, ID: doc.ID, Title: doc.Title, Score: score})
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].Title < hits[b].Title
+	})
+	return hits
+}
+
+// Highlight fills the highlights of hit, wrapping the words of terms found
+// in each field of its document
+func (i *Index) Highlight(hit *Hit, words []string) {
+	wanted := map[string]bool{}
+	for _, term := range indexTerms(words) {
+		wanted[term] = true
+	}
+	hit.Highlights = map[string]string{}
+
+	i.mu.RLock()
+	doc, ok := i.docs[hit.Type+"/"+hit.ID]
+	i.mu.RUnlock()
+	if !ok {
+		return
+	}
+	for _, field := range doc.Fields {
+		if fragment, ok := highlight(field.Text, wanted); ok {
+			hit.Highlights[field.Name] = fragment
+		}
+	}
+}
+
+// highlight returns a fragment of text around its first wanted word, with
+// the wanted words marked
+func highlight(text string, wanted map[string]bool) (string, bool) {
+	tokens := tokenize(text)
+	first := -1
+	marked := make([]bool, len(tokens))
+	for n, t := range tokens {
+		term, ok := indexTerm(t.term)
+		marked[n] = ok && wanted[term]
+	}
+	for n := range tokens {
+		if marked[n] {
+			first = n
+			break
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	from := first - 5
+	if from < 0 {
+		from = 0
+	}
+	to := from + highlightWords
+	if to > len(tokens) {
+		to = len(tokens)
+	}
+
+	var b strings.Builder
+	pos := tokens[from].start
+	if from == 0 {
+		pos = 0
+	}
+	for n, t := range tokens[from:to] {
+		b.WriteString(text[pos:t.start])
+		if marked[from+n] {
+			b.WriteString(HighlightStart + text[t.start:t.end] + HighlightStop)
+		} else {
+			b.WriteString(text[t.start:t.end])
+		}
+		pos = t.end
+	}
+	if to == len(tokens) {
+		b.WriteString(text[pos:])
+	}
+	return b.String(), true
+}
+
+// snapshot is the on-disk format of the index. Postings are rebuilt from
+// the documents when the index is loaded.
+type snapshot struct {
+	Version   int
+	Sequence  uint64
+	SavedAt   time.Time
+	Documents []Document
+}
+
+// errIndexVersion is returned when loading an index of another format
+var errIndexVersion = errors.New("search index has an unsupported version")
+
+// Save writes the index to dir. The file is replaced atomically, so a
+// crash leaves the previous index in place.
+func (i *Index) Save(dir string) error {
+	i.mu.RLock()
+	snap := snapshot{Version: indexVersion, Sequence: i.sequence, SavedAt: time.Now()}
+	snap.Documents = make([]Document, 0, len(i.docs))
+	for _, doc := range i.docs {
+		snap.Documents = append(snap.Documents, *doc)
+	}
+	generation := i.generation
+	i.mu.RUnlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(dir, indexFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := gob.NewEncoder(file).Encode(snap); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), filepath.Join(dir, indexFile)); err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	i.saved = generation
+	i.mu.Unlock()
+	return nil
+}
+
+// LoadIndex reads the index saved in dir. A missing index is reported
+// with an error satisfying os.IsNotExist.
+func LoadIndex(dir string) (*Index, error) {
+	file, err := os.Open(filepath.Join(dir, indexFile))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	snap := snapshot{}
+	if err := gob.NewDecoder(file).Decode(&snap); err != nil {
+		return nil, err
+	}
+	if snap.Version != indexVersion {
+		return nil, errIndexVersion
+	}
+
+	index := NewIndex()
+	for n := range snap.Documents {
+		index.put(&snap.Documents[n])
+	}
+	index.sequence = snap.Sequence
+	return index, nil
+}
+
+// token models a word of a text, with its byte offsets
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into lowercase unaccented words
+func tokenize(text string) []token {
+	tokens := []token{}
+	start := -1
+	for pos, r := range text {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if word && start < 0 {
+			start = pos
+		} else if !word && start >= 0 {
+			tokens = append(tokens, token{term: slug.Fold(text[start:pos]), start: start, end: pos})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{term: slug.Fold(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// Terms returns the distinct folded words of a query
+func Terms(text string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, t := range tokenize(text) {
+		if !seen[t.term] {
+			seen[t.term] = true
+			terms = append(terms, t.term)
+		}
+	}
+	return terms
+}
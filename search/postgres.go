@@ -0,0 +1,209 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"video-catalog/database"
+	"video-catalog/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Postgres searches the catalog tables with the full-text indexes created
+// by the search migration
+type Postgres struct {
+	db *gorm.DB
+}
+
+// NewPostgres returns a backend searching db
+func NewPostgres(db *gorm.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// searchTable describes how to search the table of an entity type
+type searchTable struct {
+	table  string
+	title  string
+	fields []string
+}
+
+var searchTables = map[string]searchTable{
+	TypeVideo:      {table: "videos", title: "title", fields: []string{"title", "description"}},
+	TypeCategory:   {table: "categories", title: "name", fields: []string{"name", "description"}},
+	TypeGenre:      {table: "genres", title: "name", fields: []string{"name"}},
+	TypeCastMember: {table: "cast_members", title: "name", fields: []string{"name"}},
+}
+
+type postgresHit struct {
+	Type        string
+	ID          string
+	Title       string
+	Score       float64
+	Highlight0  string
+	Highlight1  string
+	TotalResult int
+}
+
+// Search ranks the matching entities of every requested type together
+func (p *Postgres) Search(ctx context.Context, q Query) (Result, error) {
+	if strings.TrimSpace(q.Text) == "" {
+		return Result{}, ErrEmptyQuery
+	}
+	types := q.Types
+	if len(types) == 0 {
+		types = Types
+	}
+	if !q.Filter.Empty() {
+		types = onlyVideos(types)
+	}
+
+	options := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxFragments=2, MinWords=5, MaxWords=25", HighlightStart, HighlightStop)
+	selects := []string{}
+	args := []interface{}{}
+	for _, t := range types {
+		st, ok := searchTables[t]
+		if !ok {
+			return Result{}, fmt.Errorf("Unknown search type %q", t)
+		}
+		highlights := []string{"''", "''"}
+		for i, field := range st.fields {
+			highlights[i] = fmt.Sprintf("ts_headline('%s', coalesce(%s, ''), q, ?)", database.SearchConfig, field)
+			args = append(args, options)
+		}
+		document := database.SearchDocuments[st.table]
+		filter := ""
+		args = append(args, q.Text)
+		if t == TypeVideo && !q.Filter.Empty() {
+			filter = " AND id IN ?"
+			args = append(args, q.Filter.Apply(p.db.Table("videos").Select("videos.id"), "").SubQuery())
+		}
+		selects = append(selects, fmt.Sprintf(
+			"SELECT '%s' AS type, id, %s AS title, ts_rank(%s, q) AS score, %s AS highlight0, %s AS highlight1 "+
+				"FROM %s, plainto_tsquery('%s', ?) q WHERE deleted_at IS NULL AND %s @@ q%s",
+			t, st.title, document, highlights[0], highlights[1], st.table, database.SearchConfig, document, filter))
+	}
+	if len(selects) == 0 {
+		return Result{Types: types, Hits: []Hit{}}, nil
+	}
+
+	sql := "SELECT *, count(*) OVER () AS total_result FROM (" + strings.Join(selects, " UNION ALL ") + ") hits " +
+		"ORDER BY score DESC, title LIMIT ? OFFSET ?"
+	args = append(args, q.Limit, q.Offset)
+
+	rows := []postgresHit{}
+	if err := p.db.Raw(sql, args...).Scan(&rows).Error; err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Types: types, Hits: []Hit{}}
+	for _, row := range rows {
+		result.Total = row.TotalResult
+		hit := Hit{Type: row.Type, ID: row.ID, Title: row.Title, Score: row.Score, Highlights: map[string]string{}}
+		for i, field := range searchTables[row.Type].fields {
+			value := []string{row.Highlight0, row.Highlight1}[i]
+			if strings.Contains(value, HighlightStart) {
+				hit.Highlights[field] = value
+			}
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+
+	if q.Facets {
+		matching := p.db.Where(
+			fmt.Sprintf("%s @@ plainto_tsquery('%s', ?)", database.SearchDocuments["videos"], database.SearchConfig), q.Text)
+		facets, err := models.CountVideoFacets(matching, q.Filter)
+		if err != nil {
+			return Result{}, err
+		}
+		result.Facets = facets
+	}
+	return result, nil
+}
+
+// onlyVideos keeps the video type of types, the only one filters apply to
+func onlyVideos(types []string) []string {
+	for _, t := range types {
+		if t == TypeVideo {
+			return []string{TypeVideo}
+		}
+	}
+	return []string{}
+}
+
+// suggestTables maps each suggestible type to its table
+var suggestTables = map[string]string{
+	TypeCategory:   "categories",
+	TypeGenre:      "genres",
+	TypeCastMember: "cast_members",
+}
+
+type postgresSuggestion struct {
+	ID     string
+	Name   string
+	Score  float64
+	Prefix bool
+}
+
+// Suggest returns the entities whose name starts with the text, completed
+// by the ones with a similar name when there are not enough
+func (p *Postgres) Suggest(ctx context.Context, q SuggestQuery) ([]Suggestion, error) {
+	if strings.TrimSpace(q.Text) == "" {
+		return nil, ErrEmptyQuery
+	}
+	types := q.Types
+	if len(types) == 0 {
+		types = SuggestTypes
+	}
+
+	// the text is normalized first, so the prefix pattern is a constant the
+	// planner can match against the pattern indexes
+	var normalized struct{ Key string }
+	if err := p.db.Raw("SELECT "+database.SuggestKey("?")+" AS key", q.Text).Scan(&normalized).Error; err != nil {
+		return nil, err
+	}
+	pattern := escapeLike(normalized.Key) + "%"
+
+	suggestions := []Suggestion{}
+	for _, t := range types {
+		table, ok := suggestTables[t]
+		if !ok {
+			return nil, fmt.Errorf("Unknown suggestion type %q", t)
+		}
+		column := database.SuggestTables[table]
+		key := database.SuggestKey(column)
+
+		query := "SELECT id, %s AS name, similarity(%s, ?) AS score, %t AS prefix FROM %s " +
+			"WHERE deleted_at IS NULL AND %s ORDER BY score DESC, length(%s), %s LIMIT ?"
+		rows := []postgresSuggestion{}
+		err := p.db.Raw(fmt.Sprintf(query, column, key, true, table, key+" LIKE ?", column, column),
+			normalized.Key, pattern, q.Limit).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		// the trigram lookup is the costly one, it only completes prefix
+		// matches falling short of the limit
+		if len(rows) < q.Limit {
+			similar := []postgresSuggestion{}
+			err := p.db.Raw(fmt.Sprintf(query, column, key, false, table, key+" % ? AND "+key+" NOT LIKE ?", column, column),
+				normalized.Key, normalized.Key, pattern, q.Limit-len(rows)).Scan(&similar).Error
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, similar...)
+		}
+		for _, row := range rows {
+			match := MatchSimilar
+			if row.Prefix {
+				match = MatchPrefix
+			}
+			suggestions = append(suggestions, Suggestion{Type: t, ID: row.ID, Name: row.Name, Match: match, Score: row.Score})
+		}
+	}
+	return rankSuggestions(suggestions, q.Limit), nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,65 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"video-catalog/models"
+)
+
+// searchable entity types
+const (
+	TypeVideo      = "video"
+	TypeCategory   = "category"
+	TypeGenre      = "genre"
+	TypeCastMember = "cast_member"
+)
+
+// Types lists every searchable entity type
+var Types = []string{TypeVideo, TypeCategory, TypeGenre, TypeCastMember}
+
+// ErrEmptyQuery is returned when the query has no searchable word
+var ErrEmptyQuery = errors.New("Search query is empty")
+
+// Query models a search request. An empty Types searches every type. Video
+// filters apply to videos only, so a non empty Filter narrows the search
+// to videos and the result lists the types searched. Facets asks for the
+// facet counts of the matching videos.
+type Query struct {
+	Text   string
+	Types  []string
+	Limit  int
+	Offset int
+	Filter models.VideoFilter
+	Facets bool
+}
+
+// Hit models a matching entity. Highlights holds the matching fields with
+// the matched words wrapped in <mark> tags.
+type Hit struct {
+	Type       string            `json:"type"`
+	ID         string            `json:"id"`
+	Title      string            `json:"title"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// Result models a page of hits, best first. Types lists the entity types
+// searched, see Query.
+type Result struct {
+	Total      int                `json:"total"`
+	Types      []string           `json:"types"`
+	Hits       []Hit              `json:"hits"`
+	Facets     models.VideoFacets `json:"facets,omitempty"`
+	DidYouMean []string           `json:"did_you_mean,omitempty"`
+}
+
+// Backend runs searches
+type Backend interface {
+	Search(ctx context.Context, q Query) (Result, error)
+}
+
+// HighlightStart and HighlightStop wrap the matched words of highlights
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
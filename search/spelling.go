@@ -0,0 +1,303 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"video-catalog/models"
+
+	"github.com/jinzhu/gorm"
+)
+
+// minCorrectedWord is the length under which words are not corrected,
+// short words have too many neighbours
+const minCorrectedWord = 3
+
+// candidatesPerWord bounds the corrections considered for a word
+const candidatesPerWord = 2
+
+// Vocabulary holds the words of the catalog names with their frequency,
+// grouped by length to bound the words compared
+type Vocabulary struct {
+	words    map[string]int
+	display  map[string]string
+	byLength map[int][]string
+}
+
+// NewVocabulary returns the vocabulary of texts
+func NewVocabulary(texts []string) *Vocabulary {
+	v := &Vocabulary{words: map[string]int{}, display: map[string]string{}, byLength: map[int][]string{}}
+	spellings := map[string]map[string]int{}
+	for _, text := range texts {
+		for _, t := range tokenize(text) {
+			v.words[t.term]++
+			if spellings[t.term] == nil {
+				spellings[t.term] = map[string]int{}
+			}
+			spellings[t.term][strings.ToLower(text[t.start:t.end])]++
+		}
+	}
+	for word, forms := range spellings {
+		v.byLength[utf8.RuneCountInString(word)] = append(v.byLength[utf8.RuneCountInString(word)], word)
+		best := ""
+		for form, count := range forms {
+			if best == "" || count > forms[best] || (count == forms[best] && form < best) {
+				best = form
+			}
+		}
+		v.display[word] = best
+	}
+	return v
+}
+
+// Len returns the number of distinct words
+func (v *Vocabulary) Len() int {
+	return len(v.words)
+}
+
+type correction struct {
+	word     string
+	distance int
+	count    int
+}
+
+// Suggest returns up to limit spellings of text made of vocabulary words,
+// the likeliest first. Nothing is suggested when every word is known.
+func (v *Vocabulary) Suggest(text string, limit int) []string {
+	terms := Terms(text)
+	if len(terms) == 0 {
+		return []string{}
+	}
+
+	options := make([][]correction, len(terms))
+	unknown := false
+	for n, term := range terms {
+		if _, ok := v.words[term]; ok || utf8.RuneCountInString(term) < minCorrectedWord {
+			options[n] = []correction{{word: term}}
+			continue
+		}
+		unknown = true
+		options[n] = v.corrections(term)
+		if len(options[n]) == 0 {
+			return []string{}
+		}
+	}
+	if !unknown {
+		return []string{}
+	}
+
+	// the best correction of every word, then the runner-up of one word
+	phrases := [][]correction{{}}
+	for _, opts := range options {
+		phrases[0] = append(phrases[0], opts[0])
+	}
+	for n, opts := range options {
+		for _, alternative := range opts[1:] {
+			phrase := append([]correction{}, phrases[0]...)
+			phrase[n] = alternative
+			phrases = append(phrases, phrase)
+		}
+	}
+	sort.SliceStable(phrases[1:], func(a, b int) bool {
+		return cost(phrases[1+a]) < cost(phrases[1+b])
+	})
+
+	suggestions := []string{}
+	for _, phrase := range phrases {
+		if len(suggestions) == limit {
+			break
+		}
+		words := []string{}
+		for _, c := range phrase {
+			if display, ok := v.display[c.word]; ok {
+				words = append(words, display)
+			} else {
+				words = append(words, c.word)
+			}
+		}
+		suggestions = append(suggestions, strings.Join(words, " "))
+	}
+	return suggestions
+}
+
+// corrections returns the closest vocabulary words of term, within an
+// edit distance growing with its length, the nearest and most frequent
+// first
+func (v *Vocabulary) corrections(term string) []correction {
+	length := utf8.RuneCountInString(term)
+	maxDistance := 1
+	if length > 5 {
+		maxDistance = 2
+	}
+
+	found := []correction{}
+	for l := length - maxDistance; l <= length+maxDistance; l++ {
+		for _, word := range v.byLength[l] {
+			if d := editDistance(term, word, maxDistance); d <= maxDistance {
+				found = append(found, correction{word: word, distance: d, count: v.words[word]})
+			}
+		}
+	}
+	sort.Slice(found, func(a, b int) bool {
+		if found[a].distance != found[b].distance {
+			return found[a].distance < found[b].distance
+		}
+		if found[a].count != found[b].count {
+			return found[a].count > found[b].count
+		}
+		return found[a].word < found[b].word
+	})
+	if len(found) > candidatesPerWord {
+		found = found[:candidatesPerWord]
+	}
+	return found
+}
+
+func cost(phrase []correction) int {
+	total := 0
+	for _, c := range phrase {
+		total += c.distance
+	}
+	return total
+}
+
+// editDistance returns the optimal string alignment distance of a and b:
+// insertions, deletions, substitutions and transpositions of adjacent
+// letters. Distances over max are reported as max+1.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			substitution := 1
+			if ra[i-1] == rb[j-1] {
+				substitution = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+substitution)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = minInt(curr[j], prev2[j-2]+1)
+			}
+			rowMin = minInt(rowMin, curr[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// Speller suggests spellings of searches from the vocabulary of video
+// titles, cast member names and genre names. The vocabulary is rebuilt
+// when the outbox shows the catalog changed.
+type Speller struct {
+	db       *gorm.DB
+	interval time.Duration
+
+	mu         sync.RWMutex
+	vocabulary *Vocabulary
+	sequence   uint64
+}
+
+// NewSpeller returns a speller reading db, checking for changes every
+// interval
+func NewSpeller(db *gorm.DB, interval time.Duration) *Speller {
+	return &Speller{db: db, interval: interval, vocabulary: NewVocabulary(nil)}
+}
+
+// Suggest returns up to limit spellings of text
+func (s *Speller) Suggest(text string, limit int) []string {
+	s.mu.RLock()
+	vocabulary := s.vocabulary
+	s.mu.RUnlock()
+	return vocabulary.Suggest(text, limit)
+}
+
+// Run builds the vocabulary, then rebuilds it after catalog changes until
+// ctx is cancelled
+func (s *Speller) Run(ctx context.Context) error {
+	if err := s.Refresh(); err != nil {
+		log.Printf("Error building search vocabulary: %v", err)
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if err := s.Refresh(); err != nil {
+			log.Printf("Error rebuilding search vocabulary: %v", err)
+		}
+	}
+}
+
+// Refresh rebuilds the vocabulary if events were written to the outbox
+// since the last build
+func (s *Speller) Refresh() error {
+	outbox := models.OutboxMessage{}
+	latest, err := outbox.FindLatest(s.db, 1)
+	if err != nil {
+		return err
+	}
+	sequence := uint64(0)
+	if len(*latest) > 0 {
+		sequence = (*latest)[0].Sequence
+	}
+
+	s.mu.RLock()
+	built := s.vocabulary.Len() > 0 && sequence == s.sequence
+	s.mu.RUnlock()
+	if built {
+		return nil
+	}
+	return s.Rebuild(sequence)
+}
+
+// Rebuild reads the vocabulary again, recording the outbox sequence it
+// reflects
+func (s *Speller) Rebuild(sequence uint64) error {
+	texts := []string{}
+	for _, source := range []struct{ table, column string }{
+		{"videos", "title"},
+		{"cast_members", "name"},
+		{"genres", "name"},
+	} {
+		values := []string{}
+		err := s.db.Table(source.table).Where("deleted_at IS NULL").Pluck(source.column, &values).Error
+		if err != nil {
+			return err
+		}
+		texts = append(texts, values...)
+	}
+
+	vocabulary := NewVocabulary(texts)
+	s.mu.Lock()
+	s.vocabulary = vocabulary
+	s.sequence = sequence
+	s.mu.Unlock()
+	return nil
+}
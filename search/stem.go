@@ -0,0 +1,337 @@
+package search
+
+import (
+	"sort"
+	"strings"
+)
+
+// The index reduces words as the pt_unaccent text search configuration of
+// the postgres backend does: accents are removed first, then Portuguese
+// stop words are dropped and the other words replaced by their Snowball
+// stem (https://snowballstem.org/algorithms/portuguese/stemmer.html).
+
+// stopWords lists the Portuguese stop words of postgres. Words are
+// unaccented before they are looked up, so the accented stop words of the
+// list never match and are left out, as they are in postgres.
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		de a o que e do da em um para com uma os no se na por mais as dos
+		como mas ao ele das seu sua ou quando muito nos eu isso ela entre
+		depois sem mesmo aos seus quem nas me esse eles essa num nem suas meu
+		minha numa pelos elas qual lhe deles essas esses pelas este dele tu
+		te vos lhes meus minhas teu tua teus tuas nosso nossa nossos nossas
+		dela delas esta estes estas aquele aquela aqueles aquelas isto aquilo
+		estou estamos estive esteve estivemos estiveram estava estavam
+		estivera esteja estejamos estejam estivesse estivessem estiver
+		estivermos estiverem hei havemos houve houvemos houveram houvera haja
+		hajamos hajam houvesse houvessem houver houvermos houverem houverei
+		houveremos houveria houveriam sou somos era eram fui foi fomos foram
+		fora seja sejamos sejam fosse fossem for formos forem serei seremos
+		seria seriam tenho tem temos tinha tinham tive teve tivemos tiveram
+		tivera tenha tenhamos tenham tivesse tivessem tiver tivermos tiverem
+		terei teremos teria teriam pelo pela ate`) {
+		stopWords[w] = true
+	}
+}
+
+// indexTerm returns the term a folded word is indexed under, false for a
+// stop word
+func indexTerm(word string) (string, bool) {
+	if stopWords[word] {
+		return "", false
+	}
+	return stem(word), true
+}
+
+// indexTerms returns the distinct terms the folded words are indexed under
+func indexTerms(words []string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, w := range words {
+		if term, ok := indexTerm(w); ok && !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// suffixes sorts suffix lists longest first, so the first match is the
+// longest one
+func suffixes(list string) [][]rune {
+	out := [][]rune{}
+	for _, s := range strings.Fields(list) {
+		out = append(out, []rune(s))
+	}
+	sort.SliceStable(out, func(a, b int) bool { return len(out[a]) > len(out[b]) })
+	return out
+}
+
+// suffixes of the Snowball steps. ã and õ are written a~ and o~ while
+// stemming.
+var (
+	suffixesR2 = suffixes(`eza ezas ico ica icos icas ismo ismos ável ível ista
+		istas oso osa osos osas amento amentos imento imentos adora ador aça~o
+		adoras adores aço~es ante antes ância`)
+	suffixesLogia  = suffixes(`logia logias`)
+	suffixesUcao   = suffixes(`uça~o uço~es`)
+	suffixesEncia  = suffixes(`ência ências`)
+	suffixesIdade  = suffixes(`idade idades`)
+	suffixesIva    = suffixes(`iva ivo ivas ivos`)
+	suffixesIra    = suffixes(`ira iras`)
+	suffixesAmente = suffixes(`amente`)
+	suffixesMente  = suffixes(`mente`)
+	suffixesStep1  = suffixes(`eza ezas ico ica icos icas ismo ismos ável ível
+		ista istas oso osa osos osas amento amentos imento imentos adora ador
+		aça~o adoras adores aço~es ante antes ância logia logias uça~o uço~es
+		ência ências amente mente idade idades iva ivo ivas ivos ira iras`)
+
+	suffixesVerb = suffixes(`ada ida ia aria eria iria ará ara erá era irá ava
+		asse esse isse aste este iste ei arei erei irei am iam ariam eriam
+		iriam aram eram iram avam em arem erem irem assem essem issem ado ido
+		ando endo indo ara~o era~o ira~o ar er ir as adas idas ias arias erias
+		irias arás aras erás eras irás avas es ardes erdes irdes ares eres
+		ires asses esses isses astes estes istes is ais eis íeis aríeis
+		eríeis iríeis áreis areis éreis ereis íreis ireis ásseis ésseis
+		ísseis áveis ados idos ámos amos íamos aríamos eríamos iríamos áramos
+		éramos íramos ávamos emos aremos eremos iremos ássemos êssemos
+		íssemos imos armos ermos irmos eu iu ou ira iras`)
+	suffixesResidual = suffixes(`os a i o á í ó`)
+	suffixesAmenteOf = suffixes(`iv os ic ad`)
+	suffixesMenteOf  = suffixes(`ante avel ível`)
+	suffixesIdadeOf  = suffixes(`abil ic iv`)
+)
+
+// stemmer holds a word being stemmed and the start of its regions
+type stemmer struct {
+	w          []rune
+	rv, r1, r2 int
+}
+
+// stem returns the Snowball Portuguese stem of a lowercase word
+func stem(word string) string {
+	word = strings.NewReplacer("ã", "a~", "õ", "o~").Replace(word)
+	s := &stemmer{w: []rune(word)}
+	s.regions()
+
+	if s.standardSuffix() || s.verbSuffix() {
+		if s.ends("i") && s.before(1, "c") && s.in(1, s.rv) {
+			s.cut(1)
+		}
+	} else {
+		s.residualSuffix()
+	}
+	s.residualForm()
+
+	return strings.NewReplacer("a~", "ã", "o~", "õ").Replace(string(s.w))
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiouáéíóúâêô", r)
+}
+
+// regions finds the start of RV, R1 and R2
+func (s *stemmer) regions() {
+	w, n := s.w, len(s.w)
+	s.rv, s.r1, s.r2 = n, n, n
+
+	// the position after the first rune matching want from i on
+	past := func(i int, want bool) int {
+		for ; i < n; i++ {
+			if isVowel(w[i]) == want {
+				return i + 1
+			}
+		}
+		return -1
+	}
+
+	if n >= 2 {
+		switch {
+		case isVowel(w[0]) && !isVowel(w[1]):
+			if p := past(2, true); p >= 0 {
+				s.rv = p
+			} else {
+				s.rv = 2
+			}
+		case isVowel(w[0]):
+			if p := past(1, false); p >= 0 {
+				s.rv = p
+			}
+		case !isVowel(w[1]):
+			if p := past(2, true); p >= 0 {
+				s.rv = p
+			}
+		case n >= 3:
+			s.rv = 3
+		}
+	}
+
+	if p := past(0, true); p >= 0 {
+		if p = past(p, false); p >= 0 {
+			s.r1 = p
+			if p = past(p, true); p >= 0 {
+				if p = past(p, false); p >= 0 {
+					s.r2 = p
+				}
+			}
+		}
+	}
+}
+
+// ends tells whether the word ends with suffix
+func (s *stemmer) ends(suffix string) bool {
+	return hasSuffix(s.w, []rune(suffix))
+}
+
+func hasSuffix(w, suffix []rune) bool {
+	if len(suffix) > len(w) {
+		return false
+	}
+	for i, r := range suffix {
+		if w[len(w)-len(suffix)+i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// longest returns the length of the longest suffix of list the word ends
+// with, 0 when none
+func (s *stemmer) longest(list [][]rune) int {
+	for _, suffix := range list {
+		if hasSuffix(s.w, suffix) {
+			return len(suffix)
+		}
+	}
+	return 0
+}
+
+// in tells whether the last n runes lie in the region starting at region
+func (s *stemmer) in(n, region int) bool {
+	return len(s.w)-n >= region
+}
+
+// before tells whether the last n runes are preceded by prefix
+func (s *stemmer) before(n int, prefix string) bool {
+	return hasSuffix(s.w[:len(s.w)-n], []rune(prefix))
+}
+
+func (s *stemmer) cut(n int) {
+	s.w = s.w[:len(s.w)-n]
+}
+
+func (s *stemmer) replace(n int, with string) {
+	s.w = append(s.w[:len(s.w)-n], []rune(with)...)
+}
+
+// cutIn removes the longest suffix of list when it lies in region
+func (s *stemmer) cutIn(list [][]rune, region int) bool {
+	n := s.longest(list)
+	if n == 0 || !s.in(n, region) {
+		return false
+	}
+	s.cut(n)
+	return true
+}
+
+// standardSuffix is step 1, it tells whether a suffix was removed
+func (s *stemmer) standardSuffix() bool {
+	n := s.longest(suffixesStep1)
+	if n == 0 {
+		return false
+	}
+	suffix := s.w[len(s.w)-n:]
+	is := func(list [][]rune) bool {
+		for _, l := range list {
+			if string(l) == string(suffix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case is(suffixesIra):
+		if !s.in(n, s.rv) || !s.before(n, "e") {
+			return false
+		}
+		s.replace(n, "ir")
+		return true
+	case is(suffixesAmente):
+		if !s.in(n, s.r1) {
+			return false
+		}
+		s.cut(n)
+		if s.ends("iv") && s.in(2, s.r2) {
+			s.cut(2)
+			if s.ends("at") && s.in(2, s.r2) {
+				s.cut(2)
+			}
+		} else {
+			s.cutIn(suffixesAmenteOf, s.r2)
+		}
+		return true
+	}
+
+	if !s.in(n, s.r2) {
+		return false
+	}
+	switch {
+	case is(suffixesLogia):
+		s.replace(n, "log")
+	case is(suffixesUcao):
+		s.replace(n, "u")
+	case is(suffixesEncia):
+		s.replace(n, "ente")
+	case is(suffixesMente):
+		s.cut(n)
+		s.cutIn(suffixesMenteOf, s.r2)
+	case is(suffixesIdade):
+		s.cut(n)
+		s.cutIn(suffixesIdadeOf, s.r2)
+	case is(suffixesIva):
+		s.cut(n)
+		if s.ends("at") && s.in(2, s.r2) {
+			s.cut(2)
+		}
+	case is(suffixesR2):
+		s.cut(n)
+	}
+	return true
+}
+
+// verbSuffix is step 2: the longest verb suffix lying in RV is removed
+func (s *stemmer) verbSuffix() bool {
+	for _, suffix := range suffixesVerb {
+		if hasSuffix(s.w, suffix) && s.in(len(suffix), s.rv) {
+			s.cut(len(suffix))
+			return true
+		}
+	}
+	return false
+}
+
+// residualSuffix is step 4, applied when steps 1 and 2 removed nothing
+func (s *stemmer) residualSuffix() {
+	s.cutIn(suffixesResidual, s.rv)
+}
+
+// residualForm is step 5, always applied
+func (s *stemmer) residualForm() {
+	switch {
+	case s.ends("e") || s.ends("é") || s.ends("ê"):
+		if !s.in(1, s.rv) {
+			return
+		}
+		s.cut(1)
+		if (s.ends("u") && s.before(1, "g")) || (s.ends("i") && s.before(1, "c")) {
+			if s.in(1, s.rv) {
+				s.cut(1)
+			}
+		}
+	case s.ends("ç"):
+		s.replace(1, "c")
+	}
+}
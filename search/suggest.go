@@ -0,0 +1,60 @@
+package search
+
+import (
+	"context"
+	"sort"
+)
+
+// SuggestTypes lists the entity types with suggestions
+var SuggestTypes = []string{TypeCategory, TypeGenre, TypeCastMember}
+
+// suggestion matches
+const (
+	MatchPrefix  = "prefix"
+	MatchSimilar = "similar"
+)
+
+// SuggestQuery models a suggestion request. An empty Types suggests every
+// suggestible type.
+type SuggestQuery struct {
+	Text  string
+	Types []string
+	Limit int
+}
+
+// Suggestion models an entity whose name starts like, or looks like, the
+// text typed
+type Suggestion struct {
+	Type  string  `json:"type"`
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Match string  `json:"match"`
+	Score float64 `json:"score"`
+}
+
+// Suggester completes partially typed names
+type Suggester interface {
+	Suggest(ctx context.Context, q SuggestQuery) ([]Suggestion, error)
+}
+
+// rankSuggestions orders prefix matches first, then by decreasing score
+// and shorter names, and keeps the limit best
+func rankSuggestions(suggestions []Suggestion, limit int) []Suggestion {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Match != b.Match {
+			return a.Match == MatchPrefix
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Name) != len(b.Name) {
+			return len(a.Name) < len(b.Name)
+		}
+		return a.Name < b.Name
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
@@ -0,0 +1,93 @@
+// Package slug turns names into lowercase ascii identifiers fit for urls
+package slug
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength bounds the length of slugs, suffixes included
+const MaxLength = 200
+
+// transliterations maps the letters without a single ascii base letter
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i",
+}
+
+// accents maps accented latin letters to their base letter
+var accents = map[rune]rune{}
+
+func init() {
+	for base, accented := range map[rune]string{
+		'a': "àáâãäåāăą", 'c': "çćĉċč", 'e': "èéêëēĕėęě", 'g': "ĝğġģ", 'h': "ĥħ",
+		'i': "ìíîïĩīĭįİ", 'j': "ĵ", 'k': "ķ", 'l': "ĺļľŀ", 'n': "ñńņňŉ",
+		'o': "òóôõöōŏő", 'r': "ŕŗř", 's': "śŝşš", 't': "ţťŧ", 'u': "ùúûüũūŭůűų",
+		'w': "ŵ", 'y': "ýÿŷ", 'z': "źżž",
+	} {
+		for _, r := range accented {
+			accents[r] = base
+		}
+	}
+}
+
+// Make returns the slug of s: transliterated, lowercase words joined by
+// hyphens. HTML entities are decoded first, characters without a
+// transliteration are dropped.
+func Make(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range Fold(html.UnescapeString(s)) {
+		hyphen = write(&b, r, hyphen)
+	}
+	return Truncate(strings.Trim(b.String(), "-"), MaxLength)
+}
+
+// Fold lowercases s and replaces its accented and special latin letters by
+// ascii ones. Combining marks and invalid bytes are dropped, other
+// characters are kept.
+func Fold(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range strings.ToLower(s) {
+		if t, ok := transliterations[r]; ok {
+			b.WriteString(t)
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) || r == utf8.RuneError {
+			continue
+		}
+		if base, ok := accents[r]; ok {
+			r = base
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// write appends r to b when it is an ascii letter or digit, and a single
+// hyphen for any run of other characters
+func write(b *strings.Builder, r rune, hyphen bool) bool {
+	if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		b.WriteRune(r)
+		return false
+	}
+	if !hyphen && b.Len() > 0 {
+		b.WriteByte('-')
+	}
+	return true
+}
+
+// Truncate shortens slug to max bytes, cutting at a word boundary when it
+// can
+func Truncate(slug string, max int) string {
+	if len(slug) <= max {
+		return slug
+	}
+	slug = slug[:max]
+	if i := strings.LastIndexByte(slug, '-'); i > 0 {
+		slug = slug[:i]
+	}
+	return strings.Trim(slug, "-")
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores files in a directory of the local file system
+type Local struct {
+	root    string
+	baseURL string
+}
+
+// NewLocal returns a local storage rooted at path, creating it if needed
+func NewLocal(path, baseURL string) (*Local, error) {
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &Local{root: root, baseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+// Size returns the size in bytes of the file stored under key
+func (l *Local) Size(ctx context.Context, key string) (int64, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// URL returns the public url of key
+func (l *Local) URL(key string) string {
+	return l.baseURL + "/" + strings.TrimLeft(filepath.ToSlash(key), "/")
+}
+
+// Ping checks the root directory is writable
+func (l *Local) Ping(ctx context.Context) error {
+	file, err := ioutil.TempFile(l.root, ".ping-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+func (l *Local) path(key string) (string, error) {
+	path := filepath.Join(l.root, filepath.FromSlash(key))
+	if path == l.root || !strings.HasPrefix(path, l.root+string(filepath.Separator)) {
+		return "", ErrInvalidKey
+	}
+	return path, nil
+}
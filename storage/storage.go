@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"video-catalog/config"
+)
+
+// storage errors
+var (
+	ErrNotFound   = errors.New("File not found")
+	ErrInvalidKey = errors.New("Invalid file key")
+)
+
+// Storage models the video file storage backend. Files are put there by
+// the ingest tooling, the catalog registers them and serves their urls.
+type Storage interface {
+	Size(ctx context.Context, key string) (int64, error)
+	URL(key string) string
+	Ping(ctx context.Context) error
+}
+
+// New returns the storage backend selected by the configuration
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Driver {
+	case "local":
+		return NewLocal(cfg.LocalPath, cfg.BaseURL)
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
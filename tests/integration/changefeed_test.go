@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"log"
+	"testing"
+	"time"
+	"video-catalog/changefeed"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeFeedPoller(t *testing.T) {
+	if err := refreshOutboxTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	feed := changefeed.NewLog(10)
+	poller := changefeed.NewPoller(server.DB, feed, time.Second)
+
+	first := seedOutboxEvent("video", "video-1")
+	second := seedOutboxEvent("genre", "genre-1")
+	assert.Nil(t, poller.Poll())
+
+	entries, ok := feed.Since(0)
+	assert.True(t, ok)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, first.ID, entries[0].Event.ID)
+	assert.Equal(t, second.ID, entries[1].Event.ID)
+
+	// events are read once
+	assert.Nil(t, poller.Poll())
+	entries, _ = feed.Since(0)
+	assert.Len(t, entries, 2)
+}
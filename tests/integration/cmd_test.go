@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"video-catalog/cmd"
+	"video-catalog/database"
+	"video-catalog/models"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateCommand(t *testing.T) {
+	assert.Equal(t, 0, cmd.Execute([]string{"migrate"}))
+	pending, err := database.Pending(server.DB)
+	require.Nil(t, err)
+	assert.Empty(t, pending)
+
+	// a forgotten migration is listed, then applied again
+	last := database.Migrations[len(database.Migrations)-1].ID
+	require.Nil(t, server.DB.Where("id = ?", last).Delete(&database.SchemaMigration{}).Error)
+	pending, err = database.Pending(server.DB)
+	require.Nil(t, err)
+	assert.Equal(t, []string{last}, pending)
+
+	assert.Equal(t, 0, cmd.Execute([]string{"migrate", "-status"}))
+	pending, err = database.Pending(server.DB)
+	require.Nil(t, err)
+	assert.Equal(t, []string{last}, pending)
+
+	assert.Equal(t, 0, cmd.Execute([]string{"migrate"}))
+	pending, err = database.Pending(server.DB)
+	require.Nil(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestSeedCommandTwice(t *testing.T) {
+	refreshCatalogTables()
+
+	assert.Equal(t, 0, cmd.Execute([]string{"seed"}))
+	first := catalogCounts(t)
+	assert.NotZero(t, first["videos"])
+	slugs := []models.Genre{}
+	require.Nil(t, server.DB.Order("id").Find(&slugs).Error)
+	// every seeded record is published like one created through the api
+	events := outboxCount(t, "video.created")
+	assert.Equal(t, first["videos"], events)
+
+	// seeding again finds every record and changes nothing
+	assert.Equal(t, 0, cmd.Execute([]string{"seed"}))
+	assert.Equal(t, first, catalogCounts(t))
+	assert.Equal(t, events, outboxCount(t, "video.created"))
+	again := []models.Genre{}
+	require.Nil(t, server.DB.Order("id").Find(&again).Error)
+	assert.Equal(t, slugs, again)
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	refreshCatalogTables()
+	require.Equal(t, 0, cmd.Execute([]string{"seed"}))
+
+	dir, err := ioutil.TempDir("", "catalog-transfer")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	exported := filepath.Join(dir, "export.ndjson")
+	reexported := filepath.Join(dir, "reexport.ndjson")
+
+	require.Equal(t, 0, cmd.Execute([]string{"export", "-out", exported}))
+	counts := catalogCounts(t)
+
+	refreshCatalogTables()
+	assert.Equal(t, 0, cmd.Execute([]string{"import", "-in", exported, "-dry-run"}))
+	assert.Zero(t, catalogCounts(t)["videos"])
+
+	require.Equal(t, 0, cmd.Execute([]string{"import", "-in", exported}))
+	assert.Equal(t, counts, catalogCounts(t))
+	assert.Equal(t, counts["videos"], outboxCount(t, "video.created"))
+
+	// importing twice updates the records in place, unchanged records are
+	// not published again
+	require.Equal(t, 0, cmd.Execute([]string{"import", "-in", exported}))
+	assert.Equal(t, counts, catalogCounts(t))
+	assert.Zero(t, outboxCount(t, "video.updated"))
+
+	require.Equal(t, 0, cmd.Execute([]string{"export", "-out", reexported}))
+	assert.Equal(t, readRecords(t, exported), readRecords(t, reexported))
+}
+
+func TestImportRefusesBrokenCategoryTree(t *testing.T) {
+	refreshCatalogTables()
+
+	dir, err := ioutil.TempDir("", "catalog-transfer")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	first, second := uuid.NewV4().String(), uuid.NewV4().String()
+	category := func(id, name, parentID string) string {
+		return `{"type":"category","data":{"id":"` + id + `","name":"` + name + `","parent_id":"` + parentID + `"}}` + "\n"
+	}
+	for name, content := range map[string]string{
+		"cycle.ndjson":   category(first, "First", second) + category(second, "Second", first),
+		"missing.ndjson": category(first, "First", uuid.NewV4().String()),
+	} {
+		path := filepath.Join(dir, name)
+		require.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+		assert.Equal(t, 1, cmd.Execute([]string{"import", "-in", path}), name)
+		assert.Zero(t, catalogCounts(t)["categories"], name)
+	}
+}
+
+// refreshCatalogTables empties the tables the catalog commands write
+func refreshCatalogTables() {
+	for _, refresh := range []func() error{
+		refreshCategoryTable, refreshGenreTable, refreshCastMemberTable,
+		refreshVideoTable, refreshSeriesTables, refreshSlugRedirectTable,
+		refreshOutboxTable,
+	} {
+		if err := refresh(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func catalogCounts(t *testing.T) map[string]int {
+	counts := map[string]int{}
+	for _, table := range []string{"categories", "genres", "cast_members", "videos", "category_video", "genre_video"} {
+		count := 0
+		require.Nil(t, server.DB.Table(table).Count(&count).Error)
+		counts[table] = count
+	}
+	return counts
+}
+
+// outboxCount counts the outbox messages of an event type
+func outboxCount(t *testing.T, eventType string) int {
+	count := 0
+	require.Nil(t, server.DB.Model(&models.OutboxMessage{}).Where("event_type = ?", eventType).Count(&count).Error)
+	return count
+}
+
+// readRecords reads an export, leaving out the update times import sets
+func readRecords(t *testing.T, path string) []map[string]interface{} {
+	file, err := os.Open(path)
+	require.Nil(t, err)
+	defer file.Close()
+
+	records := []map[string]interface{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		rec := map[string]interface{}{}
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &rec))
+		if data, ok := rec["data"].(map[string]interface{}); ok {
+			delete(data, "updated_at")
+		}
+		records = append(records, rec)
+	}
+	require.Nil(t, scanner.Err())
+	return records
+}
@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type apiKeyResponse struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshAPIKeyTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		inputJSON  string
+		statusCode int
+	}{
+		{
+			// basic creation
+			inputJSON:  `{"name":"encoder", "scopes":["video:read", "video:update"]}`,
+			statusCode: http.StatusCreated,
+		},
+		{
+			// unknown scope
+			inputJSON:  `{"name":"encoder", "scopes":["video:publish"]}`,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			// no scopes
+			inputJSON:  `{"name":"encoder"}`,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, v := range samples {
+		r := gin.Default()
+		r.POST("/api_key", server.CreateAPIKey)
+		req, _ := http.NewRequest(http.MethodPost, "/api_key", bytes.NewBufferString(v.inputJSON))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, v.statusCode, rr.Code)
+
+		if v.statusCode == http.StatusCreated {
+			response := apiKeyResponse{}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			assert.NotEmpty(t, response.Key)
+
+			stored := models.APIKey{}
+			assert.Nil(t, stored.FindByKey(server.DB, response.Key))
+			assert.Equal(t, response.ID, stored.ID)
+		}
+	}
+}
+
+func TestAuthenticateWithAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshAPIKeyTable(); err != nil {
+		log.Fatal(err)
+	}
+	apiKey, key, err := seedOneAPIKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.GET("/protected", server.Authenticate, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("subject"))
+	})
+
+	for _, v := range []struct {
+		key        string
+		statusCode int
+	}{
+		{key: key, statusCode: http.StatusOK},
+		{key: "cfk_invalid", statusCode: http.StatusUnauthorized},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+		req.Header.Set("X-API-Key", v.key)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, v.statusCode, rr.Code)
+	}
+
+	stored := models.APIKey{ID: apiKey.ID}
+	assert.Nil(t, stored.FindByID(server.DB))
+	assert.NotNil(t, stored.LastUsedAt)
+}
+
+func TestAPIKeyScopesWithinCaller(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshAPIKeyTable(); err != nil {
+		log.Fatal(err)
+	}
+	minter := models.APIKey{
+		ID:     uuid.NewV4().String(),
+		Name:   "provisioner",
+		Scopes: models.StringList{"api_key:create", "video:*", "genre:read"},
+	}
+	key, err := minter.Generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := server.DB.Create(&minter).Error; err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.POST("/api_key", server.Authenticate, server.CreateAPIKey)
+	for _, v := range []struct {
+		inputJSON  string
+		statusCode int
+	}{
+		{`{"name":"reader", "scopes":["video:read", "genre:read"]}`, http.StatusCreated},
+		{`{"name":"video", "scopes":["video:*"]}`, http.StatusCreated},
+		{`{"name":"writer", "scopes":["video:read", "genre:update"]}`, http.StatusForbidden},
+		{`{"name":"genres", "scopes":["genre:*"]}`, http.StatusForbidden},
+		{`{"name":"admin", "scopes":["api_key:*"]}`, http.StatusForbidden},
+	} {
+		req, _ := http.NewRequest(http.MethodPost, "/api_key", bytes.NewBufferString(v.inputJSON))
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, v.statusCode, rr.Code, v.inputJSON)
+	}
+}
+
+func TestRevokeAndRotateAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshAPIKeyTable(); err != nil {
+		log.Fatal(err)
+	}
+	revoked, revokedKey, err := seedOneAPIKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	rotated, rotatedKey, err := seedOneAPIKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.DELETE("/api_key/:id", server.RevokeAPIKey)
+	r.POST("/api_key/:id/rotate", server.RotateAPIKey)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api_key/"+revoked.ID, nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Error(t, (&models.APIKey{}).FindByKey(server.DB, revokedKey))
+
+	req, _ = http.NewRequest(http.MethodDelete, "/api_key/"+uuid.NewV4().String(), nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "/api_key/"+rotated.ID+"/rotate", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	response := apiKeyResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, rotated.Scopes, response.Scopes)
+	assert.Error(t, (&models.APIKey{}).FindByKey(server.DB, rotatedKey))
+	assert.Nil(t, (&models.APIKey{}).FindByKey(server.DB, response.Key))
+}
+
+func refreshAPIKeyTable() error {
+	err := server.DB.DropTableIfExists(&models.APIKey{}).Error
+	if err != nil {
+		return err
+	}
+	err = server.DB.AutoMigrate(&models.APIKey{}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("Sucessfully refreshed ApiKey table")
+	return nil
+}
+
+func seedOneAPIKey() (models.APIKey, string, error) {
+	apiKey := models.APIKey{
+		ID:     uuid.NewV4().String(),
+		Name:   "encoder",
+		Scopes: models.StringList{"video:read"},
+	}
+	key, err := apiKey.Generate()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+
+	err = server.DB.Create(&apiKey).Error
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	return apiKey, key, nil
+}
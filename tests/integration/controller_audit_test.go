@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryMutationsAreAudited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshCategoryTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshAuditTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	actor := func(c *gin.Context) {
+		c.Set("subject", "editor@codeflix")
+	}
+	r.POST("/category", server.RequestID, actor, server.CreateCategory)
+	r.PUT("/category/:id", server.RequestID, actor, server.UpdateCategory)
+	r.GET("/audit", server.GetAuditEntries)
+
+	req, _ := http.NewRequest(http.MethodPost, "/category", bytes.NewBufferString(`{"name":"category name"}`))
+	req.Header.Set("X-Request-ID", "request-1")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	category := models.Category{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &category); err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, "/category/"+category.ID, bytes.NewBufferString(`{"name":"renamed category"}`))
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/audit?entity_type=category&actor=editor@codeflix", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	entries := []struct {
+		models.AuditEntry
+		Diff map[string]models.FieldChange `json:"diff"`
+	}{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &entries); err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, models.AuditActionUpdate, entries[0].Action)
+	assert.Equal(t, "renamed category", entries[0].Diff["name"].After)
+	assert.Equal(t, models.AuditActionCreate, entries[1].Action)
+	assert.Equal(t, "request-1", entries[1].RequestID)
+
+	req, _ = http.NewRequest(http.MethodGet, "/audit?from=yesterday", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
+
+func refreshAuditTable() error {
+	err := server.DB.DropTableIfExists(&models.AuditEntry{}).Error
+	if err != nil {
+		return err
+	}
+	err = server.DB.AutoMigrate(&models.AuditEntry{}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("Sucessfully refreshed AuditEntry table")
+	return nil
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryTree(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshCategoryTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.POST("/category", server.CreateCategory)
+	r.PUT("/category/:id", server.UpdateCategory)
+	r.DELETE("/category/:id", server.DeleteCategory)
+	r.GET("/categories/tree", server.GetCategoryTree)
+	r.GET("/category/:id/tree", server.GetCategorySubtree)
+	r.GET("/category/:id/ancestors", server.GetCategoryAncestors)
+	r.POST("/category/:id/move", server.MoveCategory)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	create := func(name string, parentID string) models.Category {
+		body := `{"name":"` + name + `"}`
+		if parentID != "" {
+			body = `{"name":"` + name + `", "parent_id":"` + parentID + `"}`
+		}
+		rr := do(http.MethodPost, "/category", body)
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		category := models.Category{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &category); err != nil {
+			log.Fatal(err)
+		}
+		return category
+	}
+
+	movies := create("Movies", "")
+	kids := create("Kids", movies.ID)
+	animation := create("Animation", kids.ID)
+	series := create("Series", "")
+
+	rr := do(http.MethodPost, "/category", `{"name":"Lost", "parent_id":"`+uuid.NewV4().String()+`"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	// parent ids that aren't uuids are refused before reaching the db
+	rr = do(http.MethodPost, "/category", `{"name":"Lost", "parent_id":"lost"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	rr = do(http.MethodPut, "/category/"+series.ID, `{"name":"Series", "parent_id":""}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	rr = do(http.MethodPost, "/category/"+series.ID+"/move", `{"parent_id":"lost"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	// breadcrumbs list the ancestors from the root
+	rr = do(http.MethodGet, "/category/"+animation.ID+"/ancestors", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	ancestors := []models.Category{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &ancestors))
+	assert.Len(t, ancestors, 2)
+	assert.Equal(t, movies.ID, ancestors[0].ID)
+	assert.Equal(t, kids.ID, ancestors[1].ID)
+
+	rr = do(http.MethodGet, "/category/"+kids.ID+"/tree", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	subtree := models.CategoryNode{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &subtree))
+	assert.Equal(t, kids.ID, subtree.ID)
+	assert.Len(t, subtree.Children, 1)
+	assert.Equal(t, animation.ID, subtree.Children[0].ID)
+
+	// moving a category under its own subtree builds a cycle
+	rr = do(http.MethodPost, "/category/"+movies.ID+"/move", `{"parent_id":"`+animation.ID+`"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	rr = do(http.MethodPost, "/category/"+movies.ID+"/move", `{"parent_id":"`+movies.ID+`"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = do(http.MethodPost, "/category/"+kids.ID+"/move", `{"parent_id":"`+series.ID+`"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = do(http.MethodGet, "/categories/tree", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	tree := []models.CategoryNode{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &tree))
+	assert.Len(t, tree, 2)
+	assert.Equal(t, "Movies", tree[0].Name)
+	assert.Empty(t, tree[0].Children)
+	assert.Equal(t, kids.ID, tree[1].Children[0].ID)
+
+	// a parent can't be deleted before its children
+	rr = do(http.MethodDelete, "/category/"+series.ID, "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = do(http.MethodPost, "/category/"+kids.ID+"/move", `{"parent_id":null}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	moved := models.Category{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &moved))
+	assert.Nil(t, moved.ParentID)
+}
+
+func TestCategoryTreeMaxDepth(t *testing.T) {
+	if err := refreshCategoryTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	parentID := (*string)(nil)
+	chain := []models.Category{}
+	for i := 0; i < models.MaxCategoryDepth; i++ {
+		category := models.Category{ID: uuid.NewV4().String(), Name: "level " + string(rune('a'+i)), ParentID: parentID}
+		assert.Nil(t, category.ValidateParent(server.DB, parentID))
+		if err := server.DB.Create(&category).Error; err != nil {
+			log.Fatal(err)
+		}
+		chain = append(chain, category)
+		parentID = &chain[len(chain)-1].ID
+	}
+
+	deeper := models.Category{ID: uuid.NewV4().String(), Name: "too deep"}
+	assert.Equal(t, models.ErrCategoryTooDeep, deeper.ValidateParent(server.DB, parentID))
+
+	// moving a root under another root would exceed the depth with its
+	// whole subtree
+	other := models.Category{ID: uuid.NewV4().String(), Name: "other root"}
+	if err := server.DB.Create(&other).Error; err != nil {
+		log.Fatal(err)
+	}
+	assert.Equal(t, models.ErrCategoryTooDeep, chain[0].ValidateParent(server.DB, &other.ID))
+	assert.Nil(t, chain[1].ValidateParent(server.DB, &other.ID))
+}
+
+func TestGetVideosWithSubcategories(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshCategoryTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	movies := models.Category{ID: uuid.NewV4().String(), Name: "Movies"}
+	kids := models.Category{ID: uuid.NewV4().String(), Name: "Kids", ParentID: &movies.ID}
+	for _, category := range []*models.Category{&movies, &kids} {
+		if err := server.DB.Create(category).Error; err != nil {
+			log.Fatal(err)
+		}
+	}
+	video := models.Video{
+		ID:           uuid.NewV4().String(),
+		Title:        "cartoon",
+		Description:  "a long enough description with more than ten words in it",
+		YearLaunched: 2015,
+		Rating:       "L",
+		Duration:     80,
+		CategoriesID: []string{kids.ID},
+	}
+	if _, err := video.Create(server.DB); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.GET("/videos", server.GetVideos)
+	for query, count := range map[string]int{
+		"category=" + movies.ID:                                 0,
+		"category=" + movies.ID + "&include_subcategories=true": 1,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "/videos?"+query, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		videos := []models.Video{}
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &videos))
+		assert.Len(t, videos, count, query)
+	}
+}
@@ -356,6 +356,55 @@ func TestDeleteCategory(t *testing.T) {
 	}
 }
 
+func TestRestoreCategory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	err := refreshCategoryTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	category, err := seedOneCategory()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = server.DB.Delete(&category).Error; err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		id         string
+		statusCode int
+	}{
+		{ // soft deleted category
+			id:         category.ID,
+			statusCode: http.StatusOK,
+		},
+		{ // category not deleted anymore
+			id:         category.ID,
+			statusCode: http.StatusNotFound,
+		},
+		{ // invalid category
+			id:         uuid.NewV4().String(),
+			statusCode: http.StatusNotFound,
+		},
+		{ // invalid id parameter
+			id:         "abc",
+			statusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, v := range samples {
+		r := gin.Default()
+		r.POST("/category/:id/restore", server.RestoreCategory)
+		req, _ := http.NewRequest(http.MethodPost, "/category/"+v.id+"/restore", nil)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, v.statusCode, rr.Code)
+	}
+}
+
 func refreshCategoryTable() error {
 	err := server.DB.DropTableIfExists(&models.Category{}).Error
 	if err != nil {
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVideoMaxRating(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	videos := []models.Video{
+		{Title: "kids", Rating: "L", Ratings: models.Ratings{"mpaa": "G"}},
+		{Title: "teens", Rating: "12", Ratings: models.Ratings{"mpaa": "PG-13", "pegi": "12"}},
+		// rated stricter in mpaa than its classind equivalent
+		{Title: "strict", Rating: "12", Ratings: models.Ratings{"mpaa": "R"}},
+		{Title: "unrated", Rating: "14"},
+		{Title: "adults", Rating: "18", Ratings: models.Ratings{"pegi": "18"}},
+		// rated in mpaa only, without a classind rating
+		{Title: "imported", Ratings: models.Ratings{"mpaa": "PG"}},
+	}
+	for _, video := range videos {
+		video.ID = uuid.NewV4().String()
+		video.Description = "a long enough description with more than ten words in it"
+		video.YearLaunched = 2010
+		video.Duration = 90
+		if err := video.Validate("create"); err != nil {
+			log.Fatal(err)
+		}
+		if _, err := video.Create(server.DB); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	r := gin.Default()
+	r.GET("/videos", server.GetVideos)
+	titles := func(query string) []string {
+		req, _ := http.NewRequest(http.MethodGet, "/videos?"+query, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, query)
+		response := []models.Video{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Cannot convert to json: %v", err)
+		}
+		found := []string{}
+		for _, video := range response {
+			found = append(found, video.Title)
+		}
+		sort.Strings(found)
+		return found
+	}
+
+	assert.Equal(t, []string{"kids", "strict", "teens"}, titles("max_rating=12"))
+	assert.Equal(t, []string{"kids", "strict", "teens"}, titles("max_rating=classind:12"))
+	// videos rated in mpaa are compared in mpaa, the others by equivalence
+	assert.Equal(t, []string{"imported", "kids", "teens"}, titles("max_rating=mpaa:PG-13"))
+	assert.Equal(t, []string{"imported", "kids", "strict", "teens", "unrated"}, titles("max_rating=mpaa:R"))
+	assert.Equal(t, []string{"kids", "strict", "teens"}, titles("max_rating=pegi:12"))
+
+	for _, query := range []string{"max_rating=PG-13", "max_rating=bbfc:15"} {
+		req, _ := http.NewRequest(http.MethodGet, "/videos?"+query, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, query)
+	}
+
+	// ratings are returned by system, classind included
+	req, _ := http.NewRequest(http.MethodGet, "/videos?max_rating=L", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	response := []models.Video{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Len(t, response, 1)
+	assert.Equal(t, models.Ratings{"classind": "L", "mpaa": "G"}, response[0].Ratings)
+}
+
+func TestVideoRatingsUpdate(t *testing.T) {
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	video := models.Video{
+		ID:           uuid.NewV4().String(),
+		Title:        "teens",
+		Description:  "a long enough description with more than ten words in it",
+		YearLaunched: 2010,
+		Rating:       "12",
+		Ratings:      models.Ratings{"mpaa": "PG-13", "pegi": "12"},
+		Duration:     90,
+	}
+	_, err := video.Create(server.DB)
+	assert.Nil(t, err)
+
+	// systems left out of an update keep their rating
+	update := models.Video{ID: video.ID, Ratings: models.Ratings{"mpaa": "R"}}
+	_, err = update.Update(server.DB)
+	assert.Nil(t, err)
+	found := models.Video{ID: video.ID}
+	assert.Nil(t, found.FindByID(server.DB))
+	assert.Equal(t, models.Ratings{"classind": "12", "mpaa": "R", "pegi": "12"}, found.Ratings)
+
+	// a restored snapshot drops the ratings it doesn't hold
+	restored := models.Video{ID: video.ID, Ratings: models.Ratings{"mpaa": "PG-13"}}
+	assert.Nil(t, restored.RestoreRelations(server.DB))
+	found = models.Video{ID: video.ID}
+	assert.Nil(t, found.FindByID(server.DB))
+	assert.Equal(t, models.Ratings{"classind": "12", "mpaa": "PG-13"}, found.Ratings)
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorderGenres(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshGenreTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshOutboxTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.POST("/genre", server.CreateGenre)
+	r.PUT("/genre/:id", server.UpdateGenre)
+	r.GET("/genres", server.GetGenres)
+	r.PUT("/genres/order", server.ReorderEntities("genre"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	names := func(rr *httptest.ResponseRecorder) []string {
+		genres := []models.Genre{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &genres); err != nil {
+			t.Fatalf("Cannot convert to json: %v", err)
+		}
+		list := []string{}
+		for _, g := range genres {
+			list = append(list, g.Name)
+		}
+		return list
+	}
+
+	ids := map[string]string{}
+	for _, name := range []string{"Drama", "Action", "Comedy"} {
+		rr := do(http.MethodPost, "/genre", `{"name":"`+name+`"}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		genre := models.Genre{}
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &genre))
+		ids[name] = genre.ID
+	}
+
+	// new genres are appended
+	assert.Equal(t, []string{"Drama", "Action", "Comedy"}, names(do(http.MethodGet, "/genres", "")))
+
+	// listed ids come first, the others keep their order
+	rr := do(http.MethodPut, "/genres/order", `{"ids":["`+ids["Comedy"]+`","`+ids["Action"]+`"]}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []string{"Comedy", "Action", "Drama"}, names(rr))
+	// the whole order is a single event
+	events := 0
+	assert.Nil(t, server.DB.Model(&models.OutboxMessage{}).Where("event_type = ?", "genre.reordered").Count(&events).Error)
+	assert.Equal(t, 1, events)
+
+	// updates don't move genres
+	rr = do(http.MethodPut, "/genre/"+ids["Drama"], `{"name":"Dramas", "position":1}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []string{"Comedy", "Action", "Dramas"}, names(do(http.MethodGet, "/genres", "")))
+
+	for _, body := range []string{
+		`{"ids":[]}`,
+		`{"ids":["` + ids["Comedy"] + `","` + ids["Comedy"] + `"]}`,
+		`{"ids":["` + uuid.NewV4().String() + `"]}`,
+		`{"ids":["abc"]}`,
+	} {
+		rr := do(http.MethodPut, "/genres/order", body)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, body)
+	}
+	assert.Equal(t, []string{"Comedy", "Action", "Dramas"}, names(do(http.MethodGet, "/genres", "")))
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVideoRevisionsAndRollback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshRevisionTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.POST("/video", server.CreateVideo)
+	r.PUT("/video/:id", server.UpdateVideo)
+	r.GET("/video/:id/revisions", server.GetRevisions("video"))
+	r.GET("/video/:id/revisions/diff", server.DiffRevisions("video"))
+	r.POST("/video/:id/revisions/:version/rollback", server.RollbackRevision("video"))
+
+	req, _ := http.NewRequest(http.MethodPost, "/video", bytes.NewBufferString(`{"title":"original title", "description":"a long enough description with more than ten words in it", "year_launched":2010, "rating":"L", "duration":90}`))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	video := models.Video{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &video); err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, "/video/"+video.ID, bytes.NewBufferString(`{"title":"bad edit"}`))
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/video/"+video.ID+"/revisions/diff?from=1&to=2", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	diff := struct {
+		Changes map[string]models.FieldChange `json:"changes"`
+	}{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &diff); err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, "original title", diff.Changes["title"].Before)
+	assert.Equal(t, "bad edit", diff.Changes["title"].After)
+
+	req, _ = http.NewRequest(http.MethodPost, "/video/"+video.ID+"/revisions/1/rollback", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	restored := models.Video{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &restored); err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, "original title", restored.Title)
+
+	req, _ = http.NewRequest(http.MethodGet, "/video/"+video.ID+"/revisions", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	revisions := []models.Revision{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &revisions); err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, 3, len(revisions))
+	assert.Equal(t, 3, revisions[0].Version)
+	assert.Equal(t, models.AuditActionRollback, revisions[0].Action)
+
+	// a snapshot the rules no longer accept is refused
+	err := server.DB.Exec(`UPDATE revisions SET snapshot = jsonb_set(snapshot, '{title}', '""') WHERE entity_id = ? AND version = 1`, video.ID).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	req, _ = http.NewRequest(http.MethodPost, "/video/"+video.ID+"/revisions/1/rollback", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	req, _ = http.NewRequest(http.MethodPost, "/video/"+video.ID+"/revisions/9/rollback", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestCategoryRollbackKeepsTree(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshCategoryTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshRevisionTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.POST("/category", server.CreateCategory)
+	r.POST("/category/:id/move", server.MoveCategory)
+	r.POST("/category/:id/revisions/:version/rollback", server.RollbackRevision("category"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	create := func(name string) models.Category {
+		rr := do(http.MethodPost, "/category", `{"name":"`+name+`"}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		category := models.Category{}
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &category))
+		return category
+	}
+	movies := create("Movies")
+	kids := create("Kids")
+
+	// version 2 of movies sits under kids, then kids moves under movies
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/category/"+movies.ID+"/move", `{"parent_id":"`+kids.ID+`"}`).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/category/"+movies.ID+"/move", `{"parent_id":null}`).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/category/"+kids.ID+"/move", `{"parent_id":"`+movies.ID+`"}`).Code)
+
+	// restoring version 2 would build a cycle
+	rr := do(http.MethodPost, "/category/"+movies.ID+"/revisions/2/rollback", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	// positions only change through a reorder
+	assert.Nil(t, server.DB.Model(&models.Category{}).Where("id = ?", movies.ID).Update("position", 7).Error)
+	rr = do(http.MethodPost, "/category/"+movies.ID+"/revisions/1/rollback", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	restored := models.Category{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &restored))
+	assert.Equal(t, 7, restored.Position)
+}
+
+func refreshRevisionTable() error {
+	err := server.DB.DropTableIfExists(&models.Revision{}).Error
+	if err != nil {
+		return err
+	}
+	err = server.DB.AutoMigrate(&models.Revision{}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("Sucessfully refreshed Revision table")
+	return nil
+}
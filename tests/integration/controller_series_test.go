@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeriesEpisodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshSeriesTables(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.POST("/series", server.CreateSeries)
+	r.GET("/series/:id", server.GetSeries)
+	r.DELETE("/series/:id", server.DeleteSeries)
+	r.POST("/series/:id/seasons", server.CreateSeason)
+	r.GET("/series/:id/seasons", server.GetSeasons)
+	r.PUT("/series/:id/seasons/:number", server.UpdateSeason)
+	r.DELETE("/series/:id/seasons/:number", server.DeleteSeason)
+	r.POST("/series/:id/seasons/:number/episodes", server.CreateEpisode)
+	r.GET("/series/:id/seasons/:number/episodes", server.GetEpisodes)
+	r.GET("/series/:id/seasons/:number/episodes/:episode", server.GetEpisode)
+	r.DELETE("/series/:id/seasons/:number/episodes/:episode", server.RemoveEpisode)
+	r.PUT("/video/:id", server.UpdateVideo)
+	r.GET("/video/:id/next", server.GetNextEpisode)
+	r.GET("/video/:id/previous", server.GetPreviousEpisode)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	episodeBody := func(number, duration int) string {
+		return fmt.Sprintf(`{"title":"episode %d", "description":"a long enough description with more than ten words in it", "year_launched":2010, "rating":"L", "duration":%d, "episode_number":%d}`, number, duration, number)
+	}
+
+	rr := do(http.MethodPost, "/series", `{"title":"The Series", "year_launched":2010}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	series := models.Series{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &series))
+	base := "/series/" + series.ID
+
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, base+"/seasons", `{"number":1}`).Code)
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, base+"/seasons", `{"number":2, "title":"Second"}`).Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, base+"/seasons", `{"number":1}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(http.MethodPost, base+"/seasons", `{"number":0}`).Code)
+
+	// episode numbers are unique within a season
+	ids := map[string]string{}
+	for _, e := range []struct {
+		season, number, duration int
+	}{{1, 1, 40}, {1, 2, 45}, {2, 1, 50}} {
+		rr := do(http.MethodPost, fmt.Sprintf("%s/seasons/%d/episodes", base, e.season), episodeBody(e.number, e.duration))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		video := models.Video{}
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &video))
+		ids[fmt.Sprintf("%d.%d", e.season, e.number)] = video.ID
+	}
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, base+"/seasons/1/episodes", episodeBody(2, 30)).Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPut, "/video/"+ids["1.1"], `{"episode_number":2}`).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, base+"/seasons/3/episodes", episodeBody(1, 30)).Code)
+
+	// durations add up per season and per series
+	rr = do(http.MethodGet, base+"/seasons", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	seasons := []models.SeasonSummary{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &seasons))
+	assert.Equal(t, 2, len(seasons))
+	assert.Equal(t, 2, seasons[0].EpisodeCount)
+	assert.Equal(t, 85, seasons[0].Duration)
+	assert.Equal(t, 50, seasons[1].Duration)
+
+	rr = do(http.MethodGet, base, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	totals := struct {
+		EpisodeCount int `json:"episode_count"`
+		Duration     int `json:"duration"`
+	}{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &totals))
+	assert.Equal(t, 3, totals.EpisodeCount)
+	assert.Equal(t, 135, totals.Duration)
+
+	// next and previous cross season boundaries
+	adjacent := func(path string) (int, string) {
+		rr := do(http.MethodGet, path, "")
+		video := models.Video{}
+		json.Unmarshal(rr.Body.Bytes(), &video)
+		return rr.Code, video.ID
+	}
+	code, id := adjacent("/video/" + ids["1.2"] + "/next")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ids["2.1"], id)
+	code, id = adjacent("/video/" + ids["2.1"] + "/previous")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ids["1.2"], id)
+	code, _ = adjacent("/video/" + ids["1.1"] + "/previous")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// seasons with episodes and series with seasons can't be deleted
+	assert.Equal(t, http.StatusConflict, do(http.MethodDelete, base+"/seasons/2", "").Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodDelete, base, "").Code)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, base+"/seasons/2/episodes/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, base+"/seasons/2/episodes/1", "").Code)
+	code, _ = adjacent("/video/" + ids["2.1"] + "/previous")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, base+"/seasons/2", "").Code)
+
+	// a renumbered season keeps its episodes
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, base+"/seasons/1", `{"number":3}`).Code)
+	rr = do(http.MethodGet, base+"/seasons/3/episodes", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	episodes := []models.Video{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &episodes))
+	assert.Equal(t, 2, len(episodes))
+}
+
+func refreshSeriesTables() error {
+	err := server.DB.DropTableIfExists(&models.Series{}, &models.Season{}).Error
+	if err != nil {
+		return err
+	}
+	err = server.DB.AutoMigrate(&models.Series{}, &models.Season{}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("Sucessfully refreshed Series tables")
+	return nil
+}
+
+func TestSeasonNumberRace(t *testing.T) {
+	if err := refreshSeriesTables(); err != nil {
+		log.Fatal(err)
+	}
+	err := server.DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_seasons_number ON seasons (series_id, number) WHERE deleted_at IS NULL").Error
+	assert.Nil(t, err)
+
+	series := models.Series{ID: uuid.NewV4().String(), Title: "The Series", YearLaunched: 2010}
+	series.Prepare()
+	_, err = series.Create(server.DB)
+	assert.Nil(t, err)
+
+	// both checks pass before either season is committed, the index
+	// refuses the second insert once the first commits
+	first := server.DB.Begin()
+	second := server.DB.Begin()
+	_, err = (&models.Season{ID: uuid.NewV4().String(), SeriesID: series.ID, Number: 1}).Create(first)
+	assert.Nil(t, err)
+
+	created := make(chan error, 1)
+	go func() {
+		_, err := (&models.Season{ID: uuid.NewV4().String(), SeriesID: series.ID, Number: 1}).Create(second)
+		created <- err
+	}()
+	time.Sleep(200 * time.Millisecond)
+	assert.Nil(t, first.Commit().Error)
+	assert.Equal(t, models.ErrSeasonExists, <-created)
+	second.Rollback()
+
+	missing := models.Series{ID: uuid.NewV4().String()}
+	assert.Equal(t, models.ErrSeriesNotFound, missing.FindByID(server.DB))
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenreSlugs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshGenreTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshSlugRedirectTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.POST("/genre", server.CreateGenre)
+	r.PUT("/genre/:id", server.UpdateGenre)
+	r.GET("/genre/:id", server.ResolveSlug("genre"), server.GetGenre)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	genre := func(rr *httptest.ResponseRecorder) models.Genre {
+		g := models.Genre{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &g); err != nil {
+			t.Fatalf("Cannot convert to json: %v", err)
+		}
+		return g
+	}
+
+	// names differing only by accents share a base slug
+	rr := do(http.MethodPost, "/genre", `{"name":"Ação"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	action := genre(rr)
+	assert.Equal(t, "acao", action.Slug)
+	rr = do(http.MethodPost, "/genre", `{"name":"Acao"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "acao-2", genre(rr).Slug)
+
+	rr = do(http.MethodGet, "/genre/acao", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, action.ID, genre(rr).ID)
+
+	// renames change the slug, the old one redirects
+	rr = do(http.MethodPut, "/genre/"+action.ID, `{"name":"Ação e Aventura"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "acao-e-aventura", genre(rr).Slug)
+
+	rr = do(http.MethodGet, "/genre/acao?fields=name", "")
+	assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+	assert.Equal(t, "/genre/acao-e-aventura?fields=name", rr.Header().Get("Location"))
+	rr = do(http.MethodGet, "/genre/acao-e-aventura", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, action.ID, genre(rr).ID)
+
+	// updates keeping the name keep the slug
+	rr = do(http.MethodPut, "/genre/"+action.ID, `{"name":"Ação e Aventura", "slug":"other"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "acao-e-aventura", genre(rr).Slug)
+
+	// old slugs are not given to other genres
+	rr = do(http.MethodPost, "/genre", `{"name":"AÇÃO!"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "acao-3", genre(rr).Slug)
+
+	// taking back a previous name takes back its slug
+	rr = do(http.MethodPut, "/genre/"+action.ID, `{"name":"Ação."}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "acao", genre(rr).Slug)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/genre/acao", "").Code)
+	assert.Equal(t, http.StatusMovedPermanently, do(http.MethodGet, "/genre/acao-e-aventura", "").Code)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/genre/unknown", "").Code)
+}
+
+func refreshSlugRedirectTable() error {
+	err := server.DB.DropTableIfExists(&models.SlugRedirect{}).Error
+	if err != nil {
+		return err
+	}
+	err = server.DB.AutoMigrate(&models.SlugRedirect{}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("Sucessfully refreshed SlugRedirect table")
+	return nil
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/models"
+	"video-catalog/search"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenreTranslations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshGenreTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshTranslationTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.POST("/genre", server.CreateGenre)
+	r.GET("/genre/:id", server.GetGenre)
+	r.GET("/genre/:id/translations", server.GetTranslations("genre"))
+	r.PUT("/genre/:id/translations/:locale", server.SetTranslation("genre"))
+	r.DELETE("/genre/:id/translations/:locale", server.DeleteTranslation("genre"))
+	r.GET("/translations/missing", server.GetMissingTranslations)
+
+	do := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if len(header) > 0 {
+			req.Header.Set("Accept-Language", header[0])
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	genre := func(rr *httptest.ResponseRecorder) models.Genre {
+		g := models.Genre{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &g); err != nil {
+			t.Fatalf("Cannot convert to json: %v", err)
+		}
+		return g
+	}
+
+	rr := do(http.MethodPost, "/genre", `{"name":"Ação"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	id := genre(rr).ID
+	base := "/genre/" + id
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, base+"/translations/es", `{"name":"Acción"}`).Code)
+	// the change reaches the feed of the translated genre
+	message := models.OutboxMessage{}
+	assert.Nil(t, server.DB.Where("event_type = ? AND aggregate_id = ?", "genre.translated", id).First(&message).Error)
+	assert.Equal(t, "genre", message.AggregateType)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(http.MethodPut, base+"/translations/pt-PT", `{"name":"Ac"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(http.MethodPut, base+"/translations/pt-PT", `{"title":"Acção"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(http.MethodPut, base+"/translations/pt-BR", `{"name":"Ação"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(http.MethodPut, base+"/translations/fr", `{"name":"Action"}`).Code)
+
+	rr = do(http.MethodGet, base+"?locale=es", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Acción", genre(rr).Name)
+	assert.Equal(t, "es", rr.Header().Get("Content-Language"))
+
+	rr = do(http.MethodGet, base, "", "fr, es-MX;q=0.8")
+	assert.Equal(t, "Acción", genre(rr).Name)
+	rr = do(http.MethodGet, base, "", "pt-PT")
+	assert.Equal(t, "Ação", genre(rr).Name)
+	assert.Equal(t, "pt-PT", rr.Header().Get("Content-Language"))
+	assert.Equal(t, http.StatusUnprocessableEntity, do(http.MethodGet, base+"?locale=fr", "").Code)
+
+	rr = do(http.MethodGet, "/translations/missing?types=genre", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	missing := struct {
+		Data  []models.MissingTranslation `json:"data"`
+		Total int                         `json:"total"`
+	}{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &missing))
+	assert.Equal(t, 1, missing.Total)
+	assert.Equal(t, "pt-PT", missing.Data[0].Locale)
+	assert.Equal(t, []string{"name"}, missing.Data[0].Fields)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, base+"/translations/es", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, base+"/translations/es", "").Code)
+	rr = do(http.MethodGet, base+"?locale=es", "")
+	assert.Equal(t, "Ação", genre(rr).Name)
+}
+
+func TestLocalizedTreeAndSuggestions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshCategoryTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshGenreTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshTranslationTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	category := models.Category{ID: uuid.NewV4().String(), Name: "Filmes"}
+	genre := models.Genre{ID: uuid.NewV4().String(), Name: "Ação"}
+	for _, entity := range []interface{}{&category, &genre} {
+		if err := server.DB.Create(entity).Error; err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := models.SetTranslations(server.DB, "category", category.ID, "es", models.Translations{"name": "Películas"}); err != nil {
+		log.Fatal(err)
+	}
+	if err := models.SetTranslations(server.DB, "genre", genre.ID, "es", models.Translations{"name": "Acción"}); err != nil {
+		log.Fatal(err)
+	}
+
+	server.Suggester = search.NewPostgres(server.DB)
+	r := gin.Default()
+	r.GET("/categories/tree", server.GetCategoryTree)
+	r.GET("/suggest", server.Suggest)
+
+	req, _ := http.NewRequest(http.MethodGet, "/categories/tree?locale=es", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	tree := []models.CategoryNode{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &tree))
+	assert.Len(t, tree, 1)
+	assert.Equal(t, "Películas", tree[0].Name)
+
+	req, _ = http.NewRequest(http.MethodGet, "/suggest?q=aca&types=genre", nil)
+	req.Header.Set("Accept-Language", "es")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	suggestions := []search.Suggestion{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &suggestions))
+	assert.Len(t, suggestions, 1)
+	assert.Equal(t, "Acción", suggestions[0].Name)
+}
+
+func refreshTranslationTable() error {
+	err := server.DB.DropTableIfExists(&models.Translation{}).Error
+	if err != nil {
+		return err
+	}
+	err = server.DB.AutoMigrate(&models.Translation{}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("Sucessfully refreshed Translation table")
+	return nil
+}
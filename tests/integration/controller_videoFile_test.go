@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"video-catalog/models"
+	"video-catalog/storage"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterVideoFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	video, cleanup := storedVideoFile(t, "videos/source.mp4")
+	defer cleanup()
+	if err := refreshOutboxTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.POST("/video/:id/files", server.RegisterVideoFile)
+	do := func(videoID, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/video/"+videoID+"/files", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(video.ID, `{"type":"video", "key":"videos/source.mp4", "content_type":"video/mp4"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	videoFile := models.VideoFile{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &videoFile))
+	assert.Equal(t, models.VideoFileStatusUploaded, videoFile.Status)
+	assert.Equal(t, int64(7), videoFile.Size)
+	assert.Equal(t, "http://cdn.local/videos/source.mp4", videoFile.URL)
+
+	messages, err := (&models.OutboxMessage{}).FindUnpublished(server.DB, 10)
+	assert.Nil(t, err)
+	assert.Len(t, *messages, 1)
+	assert.Equal(t, "video.file_uploaded", (*messages)[0].EventType)
+	assert.Equal(t, video.ID, (*messages)[0].AggregateID)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, do(video.ID, `{"type":"video", "key":"videos/missing.mp4"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(video.ID, `{"type":"video", "key":"../source.mp4"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(video.ID, `{"type":"poster", "key":"videos/source.mp4"}`).Code)
+	assert.Equal(t, http.StatusNotFound, do(uuid.NewV4().String(), `{"type":"video", "key":"videos/source.mp4"}`).Code)
+}
+
+// storedVideoFile creates a video and stores a file under key in a
+// temporary local storage, cleanup restores the server storage
+func storedVideoFile(t *testing.T, key string) (models.Video, func()) {
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshVideoFileTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "storage")
+	assert.Nil(t, err)
+	path := filepath.Join(dir, filepath.FromSlash(key))
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, ioutil.WriteFile(path, []byte("content"), 0644))
+
+	previous := server.Storage
+	if server.Storage, err = storage.NewLocal(dir, "http://cdn.local"); err != nil {
+		log.Fatal(err)
+	}
+
+	video := models.Video{
+		ID:           uuid.NewV4().String(),
+		Title:        "stored",
+		Description:  "a long enough description with more than ten words in it",
+		YearLaunched: 2010,
+		Rating:       "L",
+		Duration:     90,
+	}
+	if _, err := video.Create(server.DB); err != nil {
+		log.Fatal(err)
+	}
+	return video, func() {
+		server.Storage = previous
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,289 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateVideo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		inputJSON  string
+		statusCode int
+		title      string
+		rating     string
+	}{
+		{
+			// basic creation
+			inputJSON:  `{"title":"video title", "description":"a long enough description with more than ten words in it", "year_launched":2010, "rating":"L", "duration":90}`,
+			statusCode: http.StatusCreated,
+			title:      "video title",
+			rating:     "L",
+		},
+		{
+			// invalid rating
+			inputJSON:  `{"title":"video title", "description":"a long enough description with more than ten words in it", "year_launched":2010, "rating":"99", "duration":90}`,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			// wrong title data type
+			inputJSON:  `{"title":1}`,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			// no data
+			inputJSON:  `{}`,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, v := range samples {
+		r := gin.Default()
+		r.POST("/video", server.CreateVideo)
+		req, err := http.NewRequest(http.MethodPost, "/video", bytes.NewBufferString(v.inputJSON))
+		if err != nil {
+			t.Errorf("Error: %v\n", err)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, v.statusCode, rr.Code)
+
+		// parsing response body to test json response
+		if v.statusCode == http.StatusCreated {
+			responseVideo := models.Video{}
+			err = json.Unmarshal([]byte(rr.Body.String()), &responseVideo)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+
+			assert.Equal(t, v.title, responseVideo.Title)
+			assert.Equal(t, v.rating, responseVideo.Rating)
+		}
+	}
+}
+
+func TestGetVideoByID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+	video, err := seedOneVideo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		id         string
+		statusCode int
+	}{
+		{id: video.ID, statusCode: http.StatusOK},
+		{id: "invalid id parameter", statusCode: http.StatusUnprocessableEntity},
+		{id: uuid.NewV4().String(), statusCode: http.StatusNotFound},
+	}
+
+	for _, v := range samples {
+		req, _ := http.NewRequest(http.MethodGet, "/video/"+v.id, nil)
+		rr := httptest.NewRecorder()
+
+		r := gin.Default()
+		r.GET("/video/:id", server.GetVideo)
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, v.statusCode, rr.Code)
+	}
+}
+
+func TestUpdateVideo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+	video, err := seedOneVideo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		id         string
+		updateJSON string
+		statusCode int
+		title      string
+	}{
+		{
+			// partial update
+			id:         video.ID,
+			updateJSON: `{"title":"updated title"}`,
+			statusCode: http.StatusOK,
+			title:      "updated title",
+		},
+		{
+			// no data
+			id:         video.ID,
+			updateJSON: `{}`,
+			statusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, v := range samples {
+		r := gin.Default()
+		r.PUT("/video/:id", server.UpdateVideo)
+		req, _ := http.NewRequest(http.MethodPut, "/video/"+v.id, bytes.NewBufferString(v.updateJSON))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, v.statusCode, rr.Code)
+
+		if v.statusCode == http.StatusOK {
+			responseVideo := models.Video{}
+			if err := json.Unmarshal([]byte(rr.Body.String()), &responseVideo); err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			assert.Equal(t, v.title, responseVideo.Title)
+		}
+	}
+}
+
+func TestDeleteVideo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+	video, err := seedOneVideo()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		id         string
+		statusCode int
+	}{
+		{id: video.ID, statusCode: http.StatusNoContent},
+		{id: video.ID, statusCode: http.StatusNotFound},
+		{id: "abc", statusCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, v := range samples {
+		r := gin.Default()
+		r.DELETE("/video/:id", server.DeleteVideo)
+		req, _ := http.NewRequest(http.MethodDelete, "/video/"+v.id, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, v.statusCode, rr.Code)
+	}
+}
+
+func TestGetVideosFacets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshCategoryTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	movies := models.Category{ID: uuid.NewV4().String(), Name: "Movies"}
+	shows := models.Category{ID: uuid.NewV4().String(), Name: "Shows"}
+	for _, category := range []*models.Category{&movies, &shows} {
+		if err := server.DB.Create(category).Error; err != nil {
+			log.Fatal(err)
+		}
+	}
+	isTrue := true
+	videos := []models.Video{
+		{Title: "first", YearLaunched: 1995, Rating: "L", Duration: 20, CategoriesID: []string{movies.ID}},
+		{Title: "second", YearLaunched: 2012, Rating: "12", Duration: 90, CategoriesID: []string{movies.ID, shows.ID}},
+		{Title: "third", YearLaunched: 2018, Rating: "12", Duration: 150, Opened: &isTrue, CategoriesID: []string{shows.ID}},
+	}
+	for _, video := range videos {
+		video.ID = uuid.NewV4().String()
+		video.Description = "a long enough description with more than ten words in it"
+		if _, err := video.Create(server.DB); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	r := gin.Default()
+	r.GET("/videos", server.GetVideos)
+	req, _ := http.NewRequest(http.MethodGet, "/videos?facets=true&rating=12&category="+movies.ID, nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	response := struct {
+		Data   []models.Video     `json:"data"`
+		Facets models.VideoFacets `json:"facets"`
+	}{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Cannot convert to json: %v", err)
+	}
+	assert.Len(t, response.Data, 1)
+	assert.Equal(t, "second", response.Data[0].Title)
+
+	counts := func(facet string) map[string]int {
+		values := map[string]int{}
+		for _, count := range response.Facets[facet] {
+			values[count.Value] = count.Count
+		}
+		return values
+	}
+	// categories ignore the category filter but respect the rating one
+	assert.Equal(t, map[string]int{movies.ID: 1, shows.ID: 2}, counts(models.FacetCategory))
+	// ratings ignore the rating filter but respect the category one
+	assert.Equal(t, 1, counts(models.FacetRating)["L"])
+	assert.Equal(t, 1, counts(models.FacetRating)["12"])
+	assert.Equal(t, map[string]int{"2010": 1}, counts(models.FacetDecade))
+	assert.Equal(t, 1, counts(models.FacetDuration)["long"])
+	assert.Equal(t, 0, counts(models.FacetDuration)["short"])
+	assert.Equal(t, 1, counts(models.FacetOpened)["false"])
+
+	req, _ = http.NewRequest(http.MethodGet, "/videos?decade=2015", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
+
+func refreshVideoTable() error {
+	err := server.DB.DropTableIfExists(&models.Video{}, &models.VideoCategory{}, &models.VideoGenre{}, &models.VideoRating{}).Error
+	if err != nil {
+		return err
+	}
+	err = server.DB.AutoMigrate(&models.Video{}, &models.VideoCategory{}, &models.VideoGenre{}, &models.VideoRating{}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("Sucessfully refreshed Video table")
+	return nil
+}
+
+func seedOneVideo() (models.Video, error) {
+	isFalse := false
+	video := models.Video{
+		ID:           uuid.NewV4().String(),
+		Title:        "video title",
+		Description:  "a long enough description with more than ten words in it",
+		YearLaunched: 2015,
+		Opened:       &isFalse,
+		Rating:       "12",
+		Duration:     100,
+	}
+
+	err := server.DB.Model(&models.Video{}).Create(&video).Error
+	if err != nil {
+		return models.Video{}, err
+	}
+	return video, nil
+}
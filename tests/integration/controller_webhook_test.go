@@ -0,0 +1,290 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"video-catalog/config"
+	"video-catalog/events"
+	"video-catalog/models"
+	"video-catalog/webhooks"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const webhookSecret = "a-webhook-secret-for-tests"
+
+func TestCreateWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshWebhookTables(); err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		inputJSON  string
+		statusCode int
+	}{
+		{`{"url":"https://partner.example/hooks","event_types":["video.*"],"secret":"` + webhookSecret + `"}`, http.StatusCreated},
+		{`{"url":"partner","event_types":["video.*"],"secret":"` + webhookSecret + `"}`, http.StatusUnprocessableEntity},
+		{`{"url":"https://partner.example/hooks","event_types":[],"secret":"` + webhookSecret + `"}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, v := range samples {
+		r := gin.Default()
+		r.POST("/webhook", server.CreateWebhook)
+		req, _ := http.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(v.inputJSON))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, v.statusCode, rr.Code)
+		assert.NotContains(t, rr.Body.String(), webhookSecret)
+	}
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	if err := refreshWebhookTables(); err != nil {
+		log.Fatal(err)
+	}
+
+	received := make(chan *http.Request, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhooks.TimestampHeader), 10, 64)
+		if r.Header.Get(webhooks.SignatureHeader) != webhooks.Sign(webhookSecret, timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received <- r
+	}))
+	defer receiver.Close()
+
+	webhook := seedWebhook(receiver.URL, "video.*")
+	event := events.Event{ID: uuid.NewV4().String(), Type: "video.deleted", Data: json.RawMessage(`{}`)}
+	ignored := events.Event{ID: uuid.NewV4().String(), Type: "genre.created", Data: json.RawMessage(`{}`)}
+
+	// queueing twice must not deliver twice
+	for _, e := range []events.Event{event, event, ignored} {
+		assert.Nil(t, webhooks.Queue(server.DB, e))
+	}
+
+	sender := webhooks.NewSender(server.DB, config.Default().Webhook)
+	delivered, err := sender.SendDue(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, delivered)
+
+	r := <-received
+	assert.Equal(t, "video.deleted", r.Header.Get(webhooks.EventHeader))
+
+	deliveries, err := (&models.WebhookDelivery{WebhookID: webhook.ID}).FindAll(server.DB, 10, 0)
+	assert.Nil(t, err)
+	assert.Len(t, *deliveries, 1)
+	assert.Equal(t, models.WebhookDeliveryDelivered, (*deliveries)[0].Status)
+	assert.Equal(t, http.StatusOK, (*deliveries)[0].ResponseStatus)
+}
+
+func TestWebhookRetriesAndDisable(t *testing.T) {
+	if err := refreshWebhookTables(); err != nil {
+		log.Fatal(err)
+	}
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	webhook := seedWebhook(receiver.URL, "#")
+	cfg := config.Default().Webhook
+	cfg.DisableAfter = 2
+	sender := webhooks.NewSender(server.DB, cfg)
+
+	for i := 0; i < 2; i++ {
+		event := events.Event{ID: uuid.NewV4().String(), Type: "category.created", Data: json.RawMessage(`{}`)}
+		assert.Nil(t, webhooks.Queue(server.DB, event))
+	}
+
+	delivered, err := sender.SendDue(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, delivered)
+
+	deliveries, err := (&models.WebhookDelivery{WebhookID: webhook.ID}).FindAll(server.DB, 10, 0)
+	assert.Nil(t, err)
+	for _, d := range *deliveries {
+		assert.Equal(t, models.WebhookDeliveryPending, d.Status)
+		assert.Equal(t, 1, d.Attempts)
+		assert.Equal(t, http.StatusServiceUnavailable, d.ResponseStatus)
+		assert.True(t, d.NextAttemptAt.After(time.Now()))
+	}
+
+	disabled := models.Webhook{ID: webhook.ID}
+	assert.Nil(t, disabled.FindByID(server.DB))
+	assert.False(t, disabled.Active)
+	assert.NotNil(t, disabled.DisabledAt)
+
+	// disabled webhooks get no new deliveries
+	event := events.Event{ID: uuid.NewV4().String(), Type: "category.created", Data: json.RawMessage(`{}`)}
+	assert.Nil(t, webhooks.Queue(server.DB, event))
+	deliveries, _ = (&models.WebhookDelivery{WebhookID: webhook.ID}).FindAll(server.DB, 10, 0)
+	assert.Len(t, *deliveries, 2)
+}
+
+func TestWebhookPostsOutsideTransaction(t *testing.T) {
+	if err := refreshWebhookTables(); err != nil {
+		log.Fatal(err)
+	}
+
+	sender := webhooks.NewSender(server.DB, config.Default().Webhook)
+	// while an endpoint answers, other senders are free to claim but find
+	// the delivery taken, and the delivery row can be written
+	type probe struct {
+		delivered int
+		err       error
+		updated   error
+	}
+	probes := make(chan probe, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := probe{}
+		p.delivered, p.err = sender.SendDue(context.Background())
+		p.updated = server.DB.Model(&models.WebhookDelivery{}).
+			Where("id = ?", r.Header.Get(webhooks.DeliveryHeader)).Update("updated_at", time.Now()).Error
+		probes <- p
+	}))
+	defer receiver.Close()
+
+	seedWebhook(receiver.URL, "#")
+	event := events.Event{ID: uuid.NewV4().String(), Type: "genre.created", Data: json.RawMessage(`{}`)}
+	assert.Nil(t, webhooks.Queue(server.DB, event))
+
+	delivered, err := sender.SendDue(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, delivered)
+
+	p := <-probes
+	assert.Nil(t, p.err)
+	assert.Equal(t, 0, p.delivered)
+	assert.Nil(t, p.updated)
+}
+
+// downPublisher is a broker refusing every event
+type downPublisher struct {
+	events.NopPublisher
+}
+
+func (downPublisher) Publish(ctx context.Context, event events.Event) error {
+	return errors.New("broker unavailable")
+}
+
+func TestWebhookDeliveredWhileBrokerIsDown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, refresh := range []func() error{refreshWebhookTables, refreshCategoryTable, refreshOutboxTable} {
+		if err := refresh(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	received := make(chan *http.Request, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer receiver.Close()
+	seedWebhook(receiver.URL, "category.*")
+
+	// the write queues the delivery along with the outbox event
+	r := gin.Default()
+	r.POST("/category", server.CreateCategory)
+	req, _ := http.NewRequest(http.MethodPost, "/category", bytes.NewBufferString(`{"name":"while the broker is down"}`))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	relay := events.NewRelay(server.DB, downPublisher{}, config.Default().Outbox)
+	published, err := relay.Dispatch(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, published)
+
+	sender := webhooks.NewSender(server.DB, config.Default().Webhook)
+	delivered, err := sender.SendDue(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, "category.created", (<-received).Header.Get(webhooks.EventHeader))
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := refreshWebhookTables(); err != nil {
+		log.Fatal(err)
+	}
+	webhook := seedWebhook("https://partner.example/hooks", "#")
+	delivery := models.WebhookDelivery{
+		ID:            uuid.NewV4().String(),
+		WebhookID:     webhook.ID,
+		EventID:       uuid.NewV4().String(),
+		EventType:     "video.created",
+		Payload:       models.JSON(`{}`),
+		Status:        models.WebhookDeliveryFailed,
+		Attempts:      10,
+		NextAttemptAt: time.Now(),
+	}
+	if _, err := delivery.Create(server.DB); err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		deliveryID string
+		statusCode int
+	}{
+		{deliveryID: delivery.ID, statusCode: http.StatusAccepted},
+		{deliveryID: uuid.NewV4().String(), statusCode: http.StatusNotFound},
+	}
+
+	for _, v := range samples {
+		r := gin.Default()
+		r.POST("/webhook/:id/deliveries/:delivery_id/redeliver", server.RedeliverWebhook)
+		req, _ := http.NewRequest(http.MethodPost, "/webhook/"+webhook.ID+"/deliveries/"+v.deliveryID+"/redeliver", nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, v.statusCode, rr.Code)
+	}
+
+	redelivered := models.WebhookDelivery{ID: delivery.ID, WebhookID: webhook.ID}
+	assert.Nil(t, redelivered.FindByID(server.DB))
+	assert.Equal(t, models.WebhookDeliveryPending, redelivered.Status)
+	assert.Equal(t, 0, redelivered.Attempts)
+}
+
+func refreshWebhookTables() error {
+	err := server.DB.DropTableIfExists(&models.WebhookDelivery{}, &models.Webhook{}).Error
+	if err != nil {
+		return err
+	}
+	err = server.DB.AutoMigrate(&models.Webhook{}, &models.WebhookDelivery{}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("Sucessfully refreshed Webhook tables")
+	return nil
+}
+
+func seedWebhook(url string, eventTypes ...string) models.Webhook {
+	webhook := models.Webhook{
+		ID:         uuid.NewV4().String(),
+		URL:        url,
+		EventTypes: models.StringList(eventTypes),
+		Secret:     webhookSecret,
+		Active:     true,
+	}
+	if _, err := webhook.Create(server.DB); err != nil {
+		log.Fatal(err)
+	}
+	return webhook
+}
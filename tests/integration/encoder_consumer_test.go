@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/events"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleEncoderResult(t *testing.T) {
+	if err := refreshVideoFileTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshOutboxTable(); err != nil {
+		log.Fatal(err)
+	}
+	videoFile := models.VideoFile{
+		ID:      uuid.NewV4().String(),
+		VideoID: uuid.NewV4().String(),
+		Type:    "video",
+		Key:     "videos/file.mp4",
+		Status:  models.VideoFileStatusUploaded,
+	}
+	if _, err := videoFile.Create(server.DB); err != nil {
+		log.Fatal(err)
+	}
+
+	message := events.Message{
+		ID:   uuid.NewV4().String(),
+		Body: []byte(`{"event":"encoding.finished","video_file_id":"` + videoFile.ID + `","output_path":"videos/hls/index.m3u8"}`),
+	}
+
+	// a redelivered message must not change anything again
+	for i := 0; i < 2; i++ {
+		err := server.HandleEncoderResult(context.Background(), message)
+		assert.Nil(t, err)
+	}
+
+	updated := models.VideoFile{ID: videoFile.ID}
+	assert.Nil(t, updated.FindByID(server.DB))
+	assert.Equal(t, models.VideoFileStatusEncoded, updated.Status)
+	assert.Equal(t, "videos/hls/index.m3u8", updated.OutputPath)
+
+	messages, err := (&models.OutboxMessage{}).FindUnpublished(server.DB, 10)
+	assert.Nil(t, err)
+	assert.Len(t, *messages, 1)
+	assert.Equal(t, "video.file_encoded", (*messages)[0].EventType)
+
+	unknown := events.Message{Body: []byte(`{"event":"encoding.failed","video_file_id":"` + uuid.NewV4().String() + `"}`)}
+	err = server.HandleEncoderResult(context.Background(), unknown)
+	assert.True(t, events.IsPermanent(err))
+}
+
+func TestEncoderResultOfRegisteredFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	video, cleanup := storedVideoFile(t, "videos/source.mp4")
+	defer cleanup()
+	if err := refreshOutboxTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	r := gin.Default()
+	r.POST("/video/:id/files", server.RegisterVideoFile)
+	req, _ := http.NewRequest(http.MethodPost, "/video/"+video.ID+"/files", bytes.NewBufferString(`{"type":"video", "key":"videos/source.mp4"}`))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	registered := models.VideoFile{}
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &registered))
+
+	failed := events.Message{
+		ID:   uuid.NewV4().String(),
+		Body: []byte(`{"event":"encoding.failed","video_file_id":"` + registered.ID + `","error":"unsupported codec"}`),
+	}
+	assert.Nil(t, server.HandleEncoderResult(context.Background(), failed))
+	videoFile := models.VideoFile{ID: registered.ID}
+	assert.Nil(t, videoFile.FindByID(server.DB))
+	assert.Equal(t, models.VideoFileStatusFailed, videoFile.Status)
+	assert.Equal(t, "unsupported codec", videoFile.Error)
+
+	// a later successful encoding replaces the failure
+	finished := events.Message{
+		ID:   uuid.NewV4().String(),
+		Body: []byte(`{"event":"encoding.finished","video_file_id":"` + registered.ID + `","output_path":"videos/hls/index.m3u8"}`),
+	}
+	assert.Nil(t, server.HandleEncoderResult(context.Background(), finished))
+	videoFile = models.VideoFile{ID: registered.ID}
+	assert.Nil(t, videoFile.FindByID(server.DB))
+	assert.Equal(t, models.VideoFileStatusEncoded, videoFile.Status)
+	assert.Equal(t, "videos/hls/index.m3u8", videoFile.OutputPath)
+	assert.Empty(t, videoFile.Error)
+
+	eventTypes := []string{}
+	messages, err := (&models.OutboxMessage{}).FindUnpublished(server.DB, 10)
+	assert.Nil(t, err)
+	for _, message := range *messages {
+		assert.Equal(t, video.ID, message.AggregateID)
+		eventTypes = append(eventTypes, message.EventType)
+	}
+	assert.Equal(t, []string{"video.file_uploaded", "video.file_encoding_failed", "video.file_encoded"}, eventTypes)
+}
+
+func refreshVideoFileTable() error {
+	err := server.DB.DropTableIfExists(&models.VideoFile{}).Error
+	if err != nil {
+		return err
+	}
+	err = server.DB.AutoMigrate(&models.VideoFile{}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("Sucessfully refreshed VideoFile table")
+	return nil
+}
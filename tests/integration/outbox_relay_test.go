@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+	"video-catalog/config"
+	"video-catalog/events"
+	"video-catalog/models"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyPublisher refuses the events listed in fail, once each
+type flakyPublisher struct {
+	events.InMemoryBroker
+	fail map[string]bool
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, event events.Event) error {
+	if p.fail[event.ID] {
+		delete(p.fail, event.ID)
+		return errors.New("broker unavailable")
+	}
+	return p.InMemoryBroker.Publish(ctx, event)
+}
+
+func TestOutboxRelayKeepsAggregateOrder(t *testing.T) {
+	if err := refreshOutboxTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	first := seedOutboxEvent("video", "video-1")
+	second := seedOutboxEvent("video", "video-1")
+	other := seedOutboxEvent("genre", "genre-1")
+
+	publisher := &flakyPublisher{fail: map[string]bool{first.ID: true}}
+	cfg := config.Default().Outbox
+	relay := events.NewRelay(server.DB, publisher, cfg)
+
+	// the first video event fails, so the second one must wait for it
+	published, err := relay.Dispatch(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, other.ID, publisher.Published()[0].ID)
+
+	// retry is backed off, nothing is due yet
+	published, err = relay.Dispatch(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, published)
+
+	err = server.DB.Model(&models.OutboxMessage{}).Where("event_id = ?", first.ID).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error
+	assert.Nil(t, err)
+
+	published, err = relay.Dispatch(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, published)
+
+	sent := publisher.Published()
+	assert.Equal(t, first.ID, sent[1].ID)
+	assert.Equal(t, second.ID, sent[2].ID)
+
+	metrics := relay.Metrics()
+	assert.Equal(t, uint64(3), metrics.Published)
+	assert.Equal(t, uint64(1), metrics.FailedAttempts)
+
+	stats, err := (&models.OutboxMessage{}).Stats(server.DB)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, stats.Pending)
+}
+
+func TestOutboxRelaySkipsBackingOffAggregates(t *testing.T) {
+	if err := refreshOutboxTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	first := seedOutboxEvent("video", "video-1")
+	seedOutboxEvent("video", "video-1")
+	seedOutboxEvent("genre", "genre-1")
+	seedOutboxEvent("genre", "genre-2")
+
+	publisher := &flakyPublisher{fail: map[string]bool{first.ID: true}}
+	cfg := config.Default().Outbox
+	cfg.BatchSize = 2
+	relay := events.NewRelay(server.DB, publisher, cfg)
+
+	published, err := relay.Dispatch(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, published)
+
+	// the video events wait for their retry without filling the batch
+	published, err = relay.Dispatch(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, published)
+
+	// claimed messages are kept from other relays until the claim expires
+	seedOutboxEvent("genre", "genre-3")
+	tx := server.DB.Begin()
+	claimed, err := (&models.OutboxMessage{}).ClaimDue(tx, 10, time.Now(), time.Now().Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Len(t, *claimed, 1)
+	assert.Nil(t, tx.Commit().Error)
+	published, err = relay.Dispatch(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, published)
+}
+
+func refreshOutboxTable() error {
+	err := server.DB.DropTableIfExists(&models.OutboxMessage{}).Error
+	if err != nil {
+		return err
+	}
+	err = server.DB.AutoMigrate(&models.OutboxMessage{}).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("Sucessfully refreshed OutboxMessage table")
+	return nil
+}
+
+func seedOutboxEvent(aggregateType, aggregateID string) events.Event {
+	event := events.Event{
+		ID:            uuid.NewV4().String(),
+		Type:          aggregateType + ".updated",
+		Version:       events.PayloadVersion,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		OccurredAt:    time.Now(),
+		Data:          []byte(`{}`),
+	}
+	message, err := events.NewOutboxMessage(event)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := message.Create(server.DB); err != nil {
+		log.Fatal(err)
+	}
+	return event
+}
@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+	"time"
+	"video-catalog/database"
+	"video-catalog/models"
+	"video-catalog/search"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresSearch(t *testing.T) {
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshGenreTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	isFalse := false
+	video := models.Video{
+		ID:           uuid.NewV4().String(),
+		Title:        "Filme de Ação",
+		Description:  "perseguições e explosões do começo ao fim da história toda",
+		YearLaunched: 2019,
+		Opened:       &isFalse,
+		Rating:       "14",
+		Duration:     120,
+	}
+	if err := server.DB.Create(&video).Error; err != nil {
+		log.Fatal(err)
+	}
+	genre := models.Genre{ID: uuid.NewV4().String(), Name: "Ação"}
+	if err := server.DB.Create(&genre).Error; err != nil {
+		log.Fatal(err)
+	}
+
+	backend := search.NewPostgres(server.DB)
+	result, err := backend.Search(context.Background(), search.Query{Text: "acao", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Total)
+
+	types := map[string]search.Hit{}
+	for _, hit := range result.Hits {
+		types[hit.Type] = hit
+	}
+	assert.Contains(t, types[search.TypeVideo].Highlights["title"], "<mark>Ação</mark>")
+	assert.Equal(t, genre.ID, types[search.TypeGenre].ID)
+
+	result, err = backend.Search(context.Background(), search.Query{Text: "explosao", Types: []string{search.TypeVideo}, Limit: 10})
+	assert.Nil(t, err)
+	assert.Len(t, result.Hits, 1)
+	assert.Contains(t, result.Hits[0].Highlights["description"], "<mark>")
+
+	// filters apply to videos only, which the result tells
+	result, err = backend.Search(context.Background(), search.Query{Text: "acao", Limit: 10, Filter: models.VideoFilter{Decades: []int{2010}}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{search.TypeVideo}, result.Types)
+	assert.Len(t, result.Hits, 1)
+}
+
+func TestPostgresSuggest(t *testing.T) {
+	if err := refreshGenreTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshCastMemberTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	for _, name := range []string{"Ação", "Aventura", "Animação", "Drama"} {
+		if err := server.DB.Create(&models.Genre{ID: uuid.NewV4().String(), Name: name}).Error; err != nil {
+			log.Fatal(err)
+		}
+	}
+	castMember := models.CastMember{ID: uuid.NewV4().String(), Name: "Fernanda Montenegro", Type: 1}
+	if err := server.DB.Create(&castMember).Error; err != nil {
+		log.Fatal(err)
+	}
+
+	backend := search.NewPostgres(server.DB)
+	suggestions, err := backend.Suggest(context.Background(), search.SuggestQuery{Text: "A", Limit: 2})
+	assert.Nil(t, err)
+	assert.Len(t, suggestions, 2)
+	// shorter names come first among prefix matches
+	assert.Equal(t, "Ação", suggestions[0].Name)
+	assert.Equal(t, search.MatchPrefix, suggestions[0].Match)
+
+	suggestions, err = backend.Suggest(context.Background(), search.SuggestQuery{Text: "ANIMAC", Types: []string{search.TypeGenre}, Limit: 10})
+	assert.Nil(t, err)
+	assert.Len(t, suggestions, 1)
+	assert.Equal(t, "Animação", suggestions[0].Name)
+
+	// a typo misses every prefix but is close enough by trigrams
+	suggestions, err = backend.Suggest(context.Background(), search.SuggestQuery{Text: "fernada montenegro", Limit: 10})
+	assert.Nil(t, err)
+	assert.Len(t, suggestions, 1)
+	assert.Equal(t, castMember.ID, suggestions[0].ID)
+	assert.Equal(t, search.MatchSimilar, suggestions[0].Match)
+
+	suggestions, err = backend.Suggest(context.Background(), search.SuggestQuery{Text: "%", Limit: 10})
+	assert.Nil(t, err)
+	assert.Empty(t, suggestions)
+}
+
+// BenchmarkPostgresSuggest measures suggestions over 100k genres, which
+// must answer within 50ms
+func BenchmarkPostgresSuggest(b *testing.B) {
+	if err := refreshGenreTable(); err != nil {
+		log.Fatal(err)
+	}
+	key := database.SuggestKey("name")
+	statements := []string{
+		"CREATE INDEX idx_genres_suggest_prefix ON genres (" + key + " text_pattern_ops)",
+		"CREATE INDEX idx_genres_suggest_trgm ON genres USING gin (" + key + " gin_trgm_ops)",
+		"INSERT INTO genres (id, name, slug, is_active, created_at, updated_at) " +
+			"SELECT md5(i::text)::uuid, 'Gênero ' || md5(i::text), 'genero-' || i, true, now(), now() " +
+			"FROM generate_series(1, 100000) i",
+		"ANALYZE genres",
+	}
+	for _, statement := range statements {
+		if err := server.DB.Exec(statement).Error; err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	backend := search.NewPostgres(server.DB)
+	texts := []string{"genero a", "gênero 12", "genro 3f", "Ação"}
+	b.ResetTimer()
+	started := time.Now()
+	for i := 0; i < b.N; i++ {
+		q := search.SuggestQuery{Text: texts[i%len(texts)], Types: []string{search.TypeGenre}, Limit: 10}
+		if _, err := backend.Suggest(context.Background(), q); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if average := time.Since(started) / time.Duration(b.N); average > 50*time.Millisecond {
+		b.Errorf("Suggestions took %s on average, more than 50ms", average)
+	}
+}
+
+func TestEmbeddedSearchSync(t *testing.T) {
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshGenreTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshOutboxTable(); err != nil {
+		log.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "index")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	genre := models.Genre{ID: uuid.NewV4().String(), Name: "Ação"}
+	if err := server.DB.Create(&genre).Error; err != nil {
+		log.Fatal(err)
+	}
+	seedOutboxEvent("genre", genre.ID)
+
+	backend := search.NewEmbedded(server.DB, dir, time.Second, time.Second)
+	count, err := backend.Rebuild()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	// changes written after the rebuild are applied by the next sync
+	video, err := seedOneVideo()
+	if err != nil {
+		log.Fatal(err)
+	}
+	seedOutboxEvent("video", video.ID)
+	if err := server.DB.Delete(&genre).Error; err != nil {
+		log.Fatal(err)
+	}
+	seedOutboxEvent("genre", genre.ID)
+	assert.Nil(t, backend.Sync())
+
+	result, err := backend.Search(context.Background(), search.Query{Text: "video", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, result.Total)
+	assert.Equal(t, "<mark>video</mark> title", result.Hits[0].Highlights["title"])
+	result, err = backend.Search(context.Background(), search.Query{Text: "acao", Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, result.Total)
+
+	// the index survives restarts
+	assert.Nil(t, backend.Save())
+	restarted := search.NewEmbedded(server.DB, dir, time.Second, time.Second)
+	assert.Nil(t, restarted.Load())
+	result, err = restarted.Search(context.Background(), search.Query{Text: "video", Limit: 10, Facets: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, result.Total)
+	assert.NotEmpty(t, result.Facets[models.FacetRating])
+}
+
+func TestSpellerRebuild(t *testing.T) {
+	if err := refreshVideoTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshCastMemberTable(); err != nil {
+		log.Fatal(err)
+	}
+	if err := refreshOutboxTable(); err != nil {
+		log.Fatal(err)
+	}
+
+	speller := search.NewSpeller(server.DB, time.Second)
+	assert.Nil(t, speller.Refresh())
+	assert.Empty(t, speller.Suggest("fernada", 3))
+
+	castMember := models.CastMember{ID: uuid.NewV4().String(), Name: "Fernanda Montenegro", Type: 1}
+	if err := server.DB.Create(&castMember).Error; err != nil {
+		log.Fatal(err)
+	}
+	seedOutboxEvent("cast_member", castMember.ID)
+
+	// the vocabulary follows the catalog once the outbox moved
+	assert.Nil(t, speller.Refresh())
+	assert.Equal(t, []string{"fernanda"}, speller.Suggest("fernada", 3))
+}
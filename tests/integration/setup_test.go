@@ -6,6 +6,7 @@ import (
 	"os"
 	"testing"
 	"video-catalog/controllers"
+	"video-catalog/database"
 	"video-catalog/models"
 
 	"github.com/jinzhu/gorm"
@@ -39,4 +40,9 @@ func Database() {
 	} else {
 		fmt.Printf("Connected to %s database\n", os.Getenv("DB_DRIVER"))
 	}
+
+	// handlers record audit entries, so every table must exist
+	if _, err = database.Migrate(server.DB); err != nil {
+		log.Fatal("Error migrating database: ", err)
+	}
 }
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"video-catalog/auth"
+	"video-catalog/config"
+	"video-catalog/controllers"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "a-long-test-secret"
+
+func signHS256(t *testing.T, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	require.Nil(t, err)
+	return token
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "editor@codeflix",
+		"aud": "catalog",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestAuthenticateHS256(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{Secret: testSecret, Audience: "catalog"})
+	require.Nil(t, err)
+
+	principal, err := authenticator.Authenticate(signHS256(t, validClaims()))
+	require.Nil(t, err)
+	require.Equal(t, "editor@codeflix", principal.Subject)
+
+	listAudience := validClaims()
+	listAudience["aud"] = []string{"other", "catalog"}
+	_, err = authenticator.Authenticate(signHS256(t, listAudience))
+	require.Nil(t, err)
+}
+
+func TestAuthenticateRejectsInvalidClaims(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{Secret: testSecret, Audience: "catalog"})
+	require.Nil(t, err)
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+
+	notYetValid := validClaims()
+	notYetValid["nbf"] = time.Now().Add(time.Hour).Unix()
+
+	wrongAudience := validClaims()
+	wrongAudience["aud"] = "other"
+
+	noExpiration := validClaims()
+	delete(noExpiration, "exp")
+
+	for _, claims := range []jwt.MapClaims{expired, notYetValid, wrongAudience, noExpiration} {
+		_, err := authenticator.Authenticate(signHS256(t, claims))
+		require.Error(t, err)
+	}
+
+	wrongSecret, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("another-secret"))
+	_, err = authenticator.Authenticate(wrongSecret)
+	require.Error(t, err)
+}
+
+func TestAuthenticateRS256WithJWKSFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": "key-1",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	dir, err := ioutil.TempDir("", "jwks")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	require.Nil(t, ioutil.WriteFile(path, jwks, 0644))
+
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{JWKSFile: path, Audience: "catalog"})
+	require.Nil(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims())
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(key)
+	require.Nil(t, err)
+
+	principal, err := authenticator.Authenticate(signed)
+	require.Nil(t, err)
+	require.Equal(t, "editor@codeflix", principal.Subject)
+
+	// no secret is configured, so HS256 tokens must be refused
+	_, err = authenticator.Authenticate(signHS256(t, validClaims()))
+	require.Error(t, err)
+}
+
+func TestAuthenticateMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{Secret: testSecret})
+	require.Nil(t, err)
+	server := controllers.Server{Auth: authenticator}
+
+	r := gin.New()
+	r.GET("/protected", server.Authenticate, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("subject"))
+	})
+
+	samples := []struct {
+		header     string
+		statusCode int
+	}{
+		{header: "Bearer " + signHS256(t, validClaims()), statusCode: http.StatusOK},
+		{header: "", statusCode: http.StatusUnauthorized},
+		{header: "Basic dXNlcjpwYXNz", statusCode: http.StatusUnauthorized},
+		{header: "Bearer invalid", statusCode: http.StatusUnauthorized},
+	}
+
+	for _, v := range samples {
+		req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+		if v.header != "" {
+			req.Header.Set("Authorization", v.header)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		require.Equal(t, v.statusCode, rr.Code)
+		if v.statusCode == http.StatusOK {
+			require.Equal(t, "editor@codeflix", rr.Body.String())
+		} else {
+			require.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+		}
+	}
+}
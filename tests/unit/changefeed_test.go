@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"video-catalog/changefeed"
+	"video-catalog/config"
+	"video-catalog/controllers"
+	"video-catalog/events"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func changeEntry(id uint64, aggregateType string) changefeed.Entry {
+	return changefeed.Entry{ID: id, Event: events.Event{Type: aggregateType + ".updated", AggregateType: aggregateType}}
+}
+
+func TestChangeLogResume(t *testing.T) {
+	l := changefeed.NewLog(3)
+	l.Append(changeEntry(1, "video"), changeEntry(2, "genre"), changeEntry(4, "video"))
+
+	entries, ok := l.Since(1)
+	require.True(t, ok)
+	require.Len(t, entries, 2)
+	require.Equal(t, uint64(2), entries[0].ID)
+
+	// 1 is evicted, resuming from 0 would skip it
+	l.Append(changeEntry(5, "category"))
+	_, ok = l.Since(0)
+	require.False(t, ok)
+	entries, ok = l.Since(1)
+	require.True(t, ok)
+	require.Len(t, entries, 3)
+	require.Equal(t, uint64(5), l.Last())
+}
+
+func TestChangeLogDropsSlowSubscribers(t *testing.T) {
+	l := changefeed.NewLog(100)
+	slow := l.Tail(1)
+	fast := l.Tail(10)
+
+	l.Append(changeEntry(1, "video"), changeEntry(2, "video"))
+
+	require.Equal(t, uint64(1), (<-slow.C).ID)
+	_, open := <-slow.C
+	require.False(t, open)
+	require.True(t, slow.Dropped())
+
+	require.Len(t, fast.C, 2)
+	l.Close()
+	require.False(t, fast.Dropped())
+}
+
+func TestStreamChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.HTTP.WriteTimeout = 500 * time.Millisecond
+	server := controllers.Server{Config: cfg, Changes: changefeed.NewLog(100)}
+	server.Changes.Append(changeEntry(1, "video"), changeEntry(2, "genre"), changeEntry(3, "video"))
+
+	r := gin.New()
+	r.GET("/changes", server.StreamChanges)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/changes?types=video", nil)
+	req.Header.Set(controllers.LastEventIDHeader, "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	go server.Changes.Append(changeEntry(4, "video"))
+
+	ids := []string{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "id: ") {
+			ids = append(ids, strings.TrimPrefix(scanner.Text(), "id: "))
+		}
+	}
+	// the stream ends before the write timeout
+	require.Equal(t, []string{"3", "4"}, ids)
+
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/changes?types=unknown", nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"video-catalog/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setConfigEnv(t *testing.T, env map[string]string) {
+	for key, value := range env {
+		previous, existed := os.LookupEnv(key)
+		os.Setenv(key, value)
+		key := key
+		t.Cleanup(func() {
+			if existed {
+				os.Setenv(key, previous)
+			} else {
+				os.Unsetenv(key)
+			}
+		})
+	}
+}
+
+func validConfigEnv() map[string]string {
+	return map[string]string{
+		"DB_HOST":     "localhost",
+		"DB_USER":     "postgres",
+		"DB_PASSWORD": "root",
+		"DB_NAME":     "catalog",
+		"API_SECRET":  "a-long-secret",
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	env := validConfigEnv()
+	env["DB_DEBUG"] = "on"
+	env["API_PORT"] = "9000"
+	env["HTTP_READ_TIMEOUT"] = "5s"
+	setConfigEnv(t, env)
+
+	cfg, err := config.Load([]string{})
+	require.Nil(t, err)
+	require.Equal(t, "localhost", cfg.DB.Host)
+	require.True(t, cfg.DB.Debug)
+	require.Equal(t, ":9000", cfg.HTTP.Addr())
+	require.Equal(t, 5*time.Second, cfg.HTTP.ReadTimeout)
+	require.Equal(t, 25, cfg.DB.MaxOpenConns)
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	env := validConfigEnv()
+	env["API_PORT"] = "9000"
+	env["DB_PORT"] = "5433"
+	setConfigEnv(t, env)
+
+	dir, err := ioutil.TempDir("", "config")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(path, []byte("http:\n  port: 9100\ndb:\n  max_open_conns: 50\n"), 0644)
+	require.Nil(t, err)
+
+	cfg, err := config.Load([]string{"-config", path, "-db-port", "6543"})
+	require.Nil(t, err)
+	require.Equal(t, 9100, cfg.HTTP.Port)
+	require.Equal(t, 50, cfg.DB.MaxOpenConns)
+	require.Equal(t, 6543, cfg.DB.Port)
+}
+
+func TestLoadConfigInvalidValues(t *testing.T) {
+	env := validConfigEnv()
+	env["DB_PORT"] = "abc"
+	env["HTTP_IDLE_TIMEOUT"] = "forever"
+	setConfigEnv(t, env)
+
+	_, err := config.Load([]string{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "DB_PORT")
+	require.Contains(t, err.Error(), "HTTP_IDLE_TIMEOUT")
+}
+
+func TestValidateConfigMissingValues(t *testing.T) {
+	cfg := config.Default()
+	cfg.HTTP.GinMode = "debug # debug / release"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "GIN_MODE")
+	require.Contains(t, err.Error(), "DB_HOST is required")
+	require.Contains(t, err.Error(), "API_SECRET is required")
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"video-catalog/events"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEncoderResult(t *testing.T) {
+	fileID := "0b8f5d1c-5c1e-4e8e-9f55-3d5f0f7d1a10"
+
+	result, err := events.ParseEncoderResult([]byte(`{"event":"encoding.finished","video_file_id":"` + fileID + `","output_path":"videos/1/hls"}`))
+	require.Nil(t, err)
+	require.Equal(t, "videos/1/hls", result.OutputPath)
+
+	result, err = events.ParseEncoderResult([]byte(`{"event":"encoding.failed","video_file_id":"` + fileID + `"}`))
+	require.Nil(t, err)
+	require.NotEmpty(t, result.Error)
+
+	malformed := []string{
+		`not json`,
+		`{"event":"encoding.finished","video_file_id":"abc","output_path":"x"}`,
+		`{"event":"encoding.finished","video_file_id":"` + fileID + `"}`,
+		`{"event":"encoding.started","video_file_id":"` + fileID + `"}`,
+	}
+	for _, body := range malformed {
+		_, err := events.ParseEncoderResult([]byte(body))
+		require.Error(t, err, body)
+		require.True(t, events.IsPermanent(err), body)
+	}
+}
+
+func TestDispose(t *testing.T) {
+	transient := errors.New("database unavailable")
+
+	require.Equal(t, events.Acknowledge, events.Dispose(nil, 0, 3))
+	require.Equal(t, events.Retry, events.Dispose(transient, 0, 3))
+	require.Equal(t, events.Retry, events.Dispose(transient, 2, 3))
+	require.Equal(t, events.DeadLetter, events.Dispose(transient, 3, 3))
+	require.Equal(t, events.DeadLetter, events.Dispose(events.Permanent(transient), 0, 3))
+}
+
+func TestRetryDelays(t *testing.T) {
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second},
+		events.RetryDelays(5, 5*time.Second))
+	require.Empty(t, events.RetryDelays(0, time.Minute))
+	require.Equal(t, "encoder.results.retry.4s", events.RetryQueue("encoder.results", 4*time.Second))
+}
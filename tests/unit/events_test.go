@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+	"video-catalog/events"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventType(t *testing.T) {
+	require.Equal(t, "category.created", events.EventType("category", "create"))
+	require.Equal(t, "genre.updated", events.EventType("genre", "update"))
+	require.Equal(t, "video.deleted", events.EventType("video", "delete"))
+	require.Equal(t, "video.updated", events.EventType("video", "rollback"))
+}
+
+func TestMatchRoutingKey(t *testing.T) {
+	samples := []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{pattern: "video.deleted", key: "video.deleted", match: true},
+		{pattern: "video.*", key: "video.file_uploaded", match: true},
+		{pattern: "*.created", key: "genre.created", match: true},
+		{pattern: "#", key: "category.created", match: true},
+		{pattern: "video.#", key: "video", match: true},
+		{pattern: "video.*", key: "video", match: false},
+		{pattern: "video.*", key: "genre.created", match: false},
+		{pattern: "*.created", key: "category.deleted", match: false},
+	}
+
+	for _, v := range samples {
+		require.Equal(t, v.match, events.MatchRoutingKey(v.pattern, v.key), v.pattern+" "+v.key)
+	}
+}
+
+func TestInMemoryBroker(t *testing.T) {
+	broker := events.NewInMemoryBroker()
+	videos := broker.Subscribe("video.*", 10)
+
+	require.Nil(t, broker.Publish(context.Background(), events.Event{ID: "1", Type: "category.created"}))
+	require.Nil(t, broker.Publish(context.Background(), events.Event{ID: "2", Type: "video.deleted"}))
+
+	received := <-videos
+	require.Equal(t, "2", received.ID)
+	require.Len(t, broker.Published(), 2)
+
+	require.Nil(t, broker.Close())
+	_, open := <-videos
+	require.False(t, open)
+	require.Equal(t, events.ErrClosed, broker.Publish(context.Background(), events.Event{Type: "video.deleted"}))
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, time.Second, events.Backoff(1, time.Minute))
+	require.Equal(t, 8*time.Second, events.Backoff(4, time.Minute))
+	require.Equal(t, time.Minute, events.Backoff(10, time.Minute))
+	require.Equal(t, time.Minute, events.Backoff(1000, time.Minute))
+}
+
+func TestNewOutboxMessage(t *testing.T) {
+	event := events.Event{
+		ID:            "c6f1e2a4-0000-4000-8000-000000000001",
+		Type:          "video.file_uploaded",
+		Version:       events.PayloadVersion,
+		AggregateType: "video",
+		AggregateID:   "c6f1e2a4-0000-4000-8000-000000000002",
+		OccurredAt:    time.Now(),
+		Data:          json.RawMessage(`{"entity":{}}`),
+	}
+
+	message, err := events.NewOutboxMessage(event)
+	require.Nil(t, err)
+	require.Equal(t, event.ID, message.EventID)
+	require.Equal(t, "video", message.AggregateType)
+	require.Equal(t, event.AggregateID, message.AggregateID)
+	require.Equal(t, event.OccurredAt, message.NextAttemptAt)
+
+	decoded := events.Event{}
+	require.Nil(t, json.Unmarshal(message.Payload, &decoded))
+	require.Equal(t, event.Type, decoded.Type)
+	require.JSONEq(t, string(event.Data), string(decoded.Data))
+}
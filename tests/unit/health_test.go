@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"video-catalog/config"
+	"video-catalog/controllers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := controllers.Server{Config: config.Default()}
+	r := gin.New()
+	r.GET("/healthz", server.Liveness)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestReadinessReportsEachComponent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.HTTP.HealthCheckTimeout = 50 * time.Millisecond
+
+	server := controllers.Server{Config: cfg}
+	server.AddHealthCheck("database", func(ctx context.Context) error { return nil })
+	server.AddHealthCheck("broker", func(ctx context.Context) error { return errors.New("connection refused") })
+	server.AddHealthCheck("storage", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	r := gin.New()
+	r.GET("/readyz", server.Readiness)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	body := struct {
+		Status     string
+		Components map[string]struct {
+			Status string
+			Error  string
+		}
+	}{}
+	require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	require.Equal(t, "unavailable", body.Status)
+	require.Equal(t, "ok", body.Components["database"].Status)
+	require.Equal(t, "connection refused", body.Components["broker"].Error)
+	require.Equal(t, "context deadline exceeded", body.Components["storage"].Error)
+}
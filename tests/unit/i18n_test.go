@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"video-catalog/i18n"
+	"video-catalog/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	locales, err := i18n.Parse("pt-BR", "pt-PT, es, es_ar", "es-AR:es,pt-PT:pt-BR")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"pt-BR", "pt-PT", "es", "es-AR"}, locales.Supported())
+
+	samples := []struct {
+		query  string
+		header string
+		locale string
+		err    error
+	}{
+		{"", "", "pt-BR", nil},
+		{"es-ar", "pt-PT", "es-AR", nil},
+		{"fr", "", "", i18n.ErrUnsupportedLocale},
+		{"", "pt-PT,pt;q=0.8", "pt-PT", nil},
+		{"", "fr-FR, es-MX;q=0.9, pt;q=0.5", "es", nil},
+		{"", "en;q=1, es;q=0", "pt-BR", nil},
+		{"", "*", "pt-BR", nil},
+	}
+	for _, sample := range samples {
+		locale, err := locales.Negotiate(sample.query, sample.header)
+		assert.Equal(t, sample.err, err, sample.query+"|"+sample.header)
+		assert.Equal(t, sample.locale, locale, sample.query+"|"+sample.header)
+	}
+
+	assert.Equal(t, []string{"es-AR", "es"}, locales.Chain("es-AR"))
+	assert.Equal(t, []string{"pt-PT"}, locales.Chain("pt-PT"))
+	assert.Equal(t, []string{}, locales.Chain("pt-BR"))
+}
+
+func TestParseLocalesErrors(t *testing.T) {
+	samples := []struct{ defaultLocale, locales, fallbacks string }{
+		{"", "es", ""},
+		{"pt-BR", "es!", ""},
+		{"pt-BR", "es", "es"},
+		{"pt-BR", "es", "es:fr"},
+		{"pt-BR", "es", "pt-BR:es"},
+		{"pt-BR", "es,es-AR", "es:es-AR,es-AR:es"},
+	}
+	for _, sample := range samples {
+		_, err := i18n.Parse(sample.defaultLocale, sample.locales, sample.fallbacks)
+		assert.NotNil(t, err, sample)
+	}
+}
+
+func TestValidateTranslations(t *testing.T) {
+	description := "una descripción suficientemente larga con más de diez palabras en ella"
+	samples := []struct {
+		entityType string
+		values     models.Translations
+		valid      bool
+	}{
+		{"video", models.Translations{"title": "Título", "description": description}, true},
+		{"video", models.Translations{"title": "Ti"}, false},
+		{"video", models.Translations{"description": "muy corta"}, false},
+		{"video", models.Translations{"title": strings.Repeat("a", 256)}, false},
+		{"video", models.Translations{"name": "Nombre"}, false},
+		{"video", models.Translations{}, false},
+		{"genre", models.Translations{"name": "Acción"}, true},
+		{"genre", models.Translations{"name": "Ac"}, false},
+		{"cast_member", models.Translations{"description": description}, false},
+	}
+	for _, sample := range samples {
+		err := sample.values.Validate(sample.entityType)
+		assert.Equal(t, sample.valid, err == nil, sample)
+	}
+}
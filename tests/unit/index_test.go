@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"video-catalog/search"
+
+	"github.com/stretchr/testify/require"
+)
+
+func videoDocument(id, title, description string) search.Document {
+	return search.Document{Type: search.TypeVideo, ID: id, Title: title, Fields: []search.Field{
+		{Name: "title", Text: title, Weight: search.WeightTitle},
+		{Name: "description", Text: description, Weight: search.WeightBody},
+	}}
+}
+
+func TestIndexMatch(t *testing.T) {
+	index := search.NewIndex()
+	index.Put(videoDocument("1", "Filme de Ação", "perseguições e explosões"))
+	index.Put(videoDocument("2", "Drama", "um filme lento sobre ação social"))
+	index.Put(search.Document{Type: search.TypeGenre, ID: "3", Title: "Ação", Fields: []search.Field{
+		{Name: "name", Text: "Ação", Weight: search.WeightTitle},
+	}})
+
+	hits := index.Match(search.Terms("ACAO"), search.Types)
+	require.Len(t, hits, 3)
+	// title matches rank above description ones
+	require.NotEqual(t, "2", hits[0].ID)
+	require.Equal(t, "2", hits[2].ID)
+
+	hits = index.Match(search.Terms("filme acao"), []string{search.TypeVideo})
+	require.Len(t, hits, 2)
+	require.Empty(t, index.Match(search.Terms("filme comedia"), search.Types))
+
+	hit := hits[0]
+	index.Highlight(&hit, search.Terms("filme acao"))
+	require.Equal(t, "<mark>Filme</mark> de <mark>Ação</mark>", hit.Highlights["title"])
+
+	index.Put(videoDocument("1", "Comédia", "risadas"))
+	require.Len(t, index.Match(search.Terms("acao"), search.Types), 2)
+	index.Remove(search.TypeGenre, "3")
+	require.Len(t, index.Match(search.Terms("acao"), search.Types), 1)
+	require.Equal(t, 2, index.Len())
+}
+
+func TestIndexStemsLikePostgres(t *testing.T) {
+	index := search.NewIndex()
+	index.Put(videoDocument("1", "Os Filmes de Aventura", "aventuras brasileiras"))
+
+	// inflected forms share the Portuguese stem
+	require.Len(t, index.Match(search.Terms("filme"), search.Types), 1)
+	require.Len(t, index.Match(search.Terms("aventureiro brasileira"), search.Types), 0)
+	require.Len(t, index.Match(search.Terms("aventura brasileira"), search.Types), 1)
+	// stop words are ignored, in the query and in the document
+	require.Len(t, index.Match(search.Terms("filme da aventura"), search.Types), 1)
+	require.Empty(t, index.Match(search.Terms("de os"), search.Types))
+
+	hit := search.Hit{Type: search.TypeVideo, ID: "1"}
+	index.Highlight(&hit, search.Terms("filme"))
+	require.Equal(t, "Os <mark>Filmes</mark> de Aventura", hit.Highlights["title"])
+}
+
+func TestIndexSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "index")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = search.LoadIndex(dir)
+	require.True(t, os.IsNotExist(err))
+
+	index := search.NewIndex()
+	index.Put(videoDocument("1", "Filme de Ação", "perseguições e explosões"))
+	index.SetSequence(42)
+	require.True(t, index.Dirty())
+	require.Nil(t, index.Save(dir))
+	require.False(t, index.Dirty())
+
+	loaded, err := search.LoadIndex(dir)
+	require.Nil(t, err)
+	require.Equal(t, uint64(42), loaded.Sequence())
+	require.Len(t, loaded.Match(search.Terms("explosoes"), search.Types), 1)
+}
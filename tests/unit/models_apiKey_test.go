@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"video-catalog/auth"
+	"video-catalog/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	apiKey := models.APIKey{}
+	key, err := apiKey.Generate()
+	require.Nil(t, err)
+	require.True(t, strings.HasPrefix(key, models.APIKeyPrefix))
+	require.True(t, strings.HasPrefix(key, apiKey.Prefix))
+	require.Equal(t, models.HashAPIKey(key), apiKey.Hash)
+	require.NotContains(t, apiKey.Hash, key)
+
+	other := models.APIKey{}
+	otherKey, err := other.Generate()
+	require.Nil(t, err)
+	require.NotEqual(t, key, otherKey)
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	samples := []struct {
+		apiKey models.APIKey
+		valid  bool
+	}{
+		{models.APIKey{Name: "encoder", Scopes: models.StringList{"video:read"}}, true},
+		{models.APIKey{Name: "en", Scopes: models.StringList{"video:read"}}, false},
+		{models.APIKey{Name: "encoder"}, false},
+		{models.APIKey{Name: "encoder", Scopes: models.StringList{"video:read"}, ExpiresAt: &past}, false},
+	}
+
+	for _, v := range samples {
+		err := v.apiKey.Validate()
+		require.Equal(t, v.valid, err == nil)
+	}
+}
+
+func TestAPIKeyIsActive(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	require.True(t, (&models.APIKey{}).IsActive(now))
+	require.True(t, (&models.APIKey{ExpiresAt: &future}).IsActive(now))
+	require.False(t, (&models.APIKey{ExpiresAt: &past}).IsActive(now))
+	require.False(t, (&models.APIKey{RevokedAt: &past}).IsActive(now))
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	_, err := auth.ParsePermission("video:read")
+	require.Nil(t, err)
+	_, err = auth.ParsePermission("genre:*")
+	require.Nil(t, err)
+	_, err = auth.ParsePermission("video")
+	require.Error(t, err)
+	_, err = auth.ParsePermission("movie:read")
+	require.Error(t, err)
+	_, err = auth.ParsePermission("video:publish")
+	require.Error(t, err)
+
+	principal := auth.Principal{APIKeyID: "id", Scopes: []string{"video:read", "genre:*"}}
+	require.True(t, principal.Can(auth.Permission{Resource: auth.ResourceVideo, Action: auth.ActionRead}))
+	require.False(t, principal.Can(auth.Permission{Resource: auth.ResourceVideo, Action: auth.ActionUpdate}))
+	require.True(t, principal.Can(auth.Permission{Resource: auth.ResourceGenre, Action: auth.ActionDelete}))
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/controllers"
+	"video-catalog/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffChangedFields(t *testing.T) {
+	before, err := models.ToJSON(models.Category{ID: "1", Name: "old name", Description: "same"})
+	require.Nil(t, err)
+	after, err := models.ToJSON(models.Category{ID: "1", Name: "new name", Description: "same"})
+	require.Nil(t, err)
+
+	diff, err := models.Diff(before, after)
+	require.Nil(t, err)
+	require.Equal(t, map[string]models.FieldChange{
+		"name": {Before: "old name", After: "new name"},
+	}, diff)
+}
+
+func TestDiffCreateAndDelete(t *testing.T) {
+	doc, err := models.ToJSON(models.Genre{ID: "1", Name: "Drama"})
+	require.Nil(t, err)
+
+	created, err := models.Diff(nil, doc)
+	require.Nil(t, err)
+	require.Equal(t, "Drama", created["name"].After)
+	require.Nil(t, created["name"].Before)
+
+	deleted, err := models.Diff(doc, nil)
+	require.Nil(t, err)
+	require.Equal(t, "Drama", deleted["name"].Before)
+	require.Nil(t, deleted["name"].After)
+}
+
+func TestToJSONNil(t *testing.T) {
+	var category *models.Category
+	doc, err := models.ToJSON(category)
+	require.Nil(t, err)
+	require.Nil(t, doc)
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := controllers.Server{}
+	r := gin.New()
+	r.GET("/", server.RequestID, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("request_id"))
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, "abc-123", rr.Body.String())
+	require.Equal(t, "abc-123", rr.Header().Get("X-Request-ID"))
+
+	req, _ = http.NewRequest(http.MethodGet, "/", nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.NotEmpty(t, rr.Header().Get("X-Request-ID"))
+	require.Equal(t, rr.Header().Get("X-Request-ID"), rr.Body.String())
+}
@@ -50,6 +50,18 @@ func TestValidateCategoryIDisNotUUID(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestCategoryParentIDIsNotUUID(t *testing.T) {
+	parentID := "1 OR 1=1"
+	category := models.Category{
+		ID:       uuid.NewV4().String(),
+		Name:     "name",
+		ParentID: &parentID,
+	}
+	category.Prepare()
+	err := category.Validate()
+	require.Equal(t, models.ErrParentID, err)
+}
+
 func TestCategoryNameIsEmpty(t *testing.T) {
 	x := true
 	category := models.Category{
@@ -103,3 +115,24 @@ func TestCategoryDescriptionIsMoreThan255Characters(t *testing.T) {
 	err := category.Validate()
 	require.Error(t, err)
 }
+
+func TestBuildCategoryTree(t *testing.T) {
+	movies := uuid.NewV4().String()
+	kids := uuid.NewV4().String()
+	missing := uuid.NewV4().String()
+	categories := []models.Category{
+		{ID: movies, Name: "Movies"},
+		{ID: kids, Name: "Kids", ParentID: &movies},
+		{ID: uuid.NewV4().String(), Name: "Animation", ParentID: &kids},
+		{ID: uuid.NewV4().String(), Name: "Orphan", ParentID: &missing},
+	}
+
+	roots := models.BuildCategoryTree(categories)
+	require.Len(t, roots, 2)
+	require.Equal(t, "Movies", roots[0].Name)
+	require.Equal(t, "Kids", roots[0].Children[0].Name)
+	require.Equal(t, "Animation", roots[0].Children[0].Children[0].Name)
+	require.Empty(t, roots[0].Children[0].Children[0].Children)
+	// a category whose parent is not listed is a root
+	require.Equal(t, "Orphan", roots[1].Name)
+}
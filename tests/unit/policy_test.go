@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/auth"
+	"video-catalog/config"
+	"video-catalog/controllers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyAllowed(t *testing.T) {
+	samples := []struct {
+		roles      []auth.Role
+		permission auth.Permission
+		allowed    bool
+	}{
+		{[]auth.Role{auth.RoleViewer}, auth.Permission{Resource: auth.ResourceVideo, Action: auth.ActionRead}, true},
+		{[]auth.Role{auth.RoleViewer}, auth.Permission{Resource: auth.ResourceVideo, Action: auth.ActionCreate}, false},
+		{[]auth.Role{auth.RoleEditor}, auth.Permission{Resource: auth.ResourceGenre, Action: auth.ActionUpdate}, true},
+		{[]auth.Role{auth.RoleEditor}, auth.Permission{Resource: auth.ResourceGenre, Action: auth.ActionDelete}, false},
+		{[]auth.Role{auth.RoleViewer, auth.RoleAdmin}, auth.Permission{Resource: auth.ResourceCategory, Action: auth.ActionDelete}, true},
+		{[]auth.Role{}, auth.Permission{Resource: auth.ResourceCategory, Action: auth.ActionRead}, false},
+	}
+
+	for _, v := range samples {
+		require.Equal(t, v.allowed, auth.Allowed(v.roles, v.permission), v.permission.String())
+	}
+}
+
+func TestPrincipalCanGrant(t *testing.T) {
+	editor := &auth.Principal{Roles: []auth.Role{auth.RoleEditor}}
+	key := &auth.Principal{APIKeyID: "key", Scopes: []string{"video:*", "genre:read"}}
+	samples := []struct {
+		principal *auth.Principal
+		scope     string
+		allowed   bool
+	}{
+		{editor, "genre:update", true},
+		{editor, "genre:delete", false},
+		{editor, "genre:*", false},
+		{key, "video:*", true},
+		{key, "video:delete", true},
+		{key, "genre:read", true},
+		{key, "genre:*", false},
+		{key, "api_key:create", false},
+	}
+
+	for _, v := range samples {
+		permission, err := auth.ParsePermission(v.scope)
+		require.Nil(t, err)
+		require.Equal(t, v.allowed, v.principal.CanGrant(permission), v.scope)
+	}
+}
+
+func TestRolesFromClaims(t *testing.T) {
+	claims := map[string]interface{}{
+		"roles":        []interface{}{"Editor", "unknown"},
+		"role":         "viewer",
+		"realm_access": map[string]interface{}{"roles": []interface{}{"admin", "editor"}},
+	}
+	require.Equal(t, []auth.Role{auth.RoleEditor, auth.RoleViewer, auth.RoleAdmin}, auth.RolesFromClaims(claims))
+	require.Equal(t, []auth.Role{}, auth.RolesFromClaims(map[string]interface{}{}))
+}
+
+func TestAuthorizeMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{Secret: testSecret})
+	require.Nil(t, err)
+	server := controllers.Server{Auth: authenticator}
+
+	r := gin.New()
+	r.DELETE("/video/:id", server.Authenticate, server.Authorize(auth.ResourceVideo, auth.ActionDelete), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	samples := []struct {
+		role       string
+		statusCode int
+	}{
+		{role: "admin", statusCode: http.StatusNoContent},
+		{role: "editor", statusCode: http.StatusForbidden},
+		{role: "viewer", statusCode: http.StatusForbidden},
+	}
+
+	for _, v := range samples {
+		claims := validClaims()
+		claims["roles"] = []string{v.role}
+		req, _ := http.NewRequest(http.MethodDelete, "/video/1", nil)
+		req.Header.Set("Authorization", "Bearer "+signHS256(t, claims))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		require.Equal(t, v.statusCode, rr.Code)
+		if v.statusCode == http.StatusForbidden {
+			body := map[string]interface{}{}
+			require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Equal(t, "video:delete", body["missing_permission"])
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"testing"
+	"video-catalog/models"
+	"video-catalog/rating"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRatingEquivalences(t *testing.T) {
+	equivalences, err := rating.ParseEquivalences(rating.DefaultEquivalences)
+	require.Nil(t, err)
+
+	ceiling, err := equivalences.Ceiling("mpaa", "PG-13")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"G", "PG", "PG-13"}, ceiling.Levels)
+	assert.Equal(t, []string{"L", "10", "12"}, ceiling.Reference)
+
+	ceiling, err = equivalences.Ceiling("classind", "14")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"L", "10", "12", "14"}, ceiling.Levels)
+	assert.Equal(t, ceiling.Levels, ceiling.Reference)
+
+	_, err = equivalences.Ceiling("bbfc", "15")
+	assert.Equal(t, rating.ErrUnknownSystem, err)
+	_, err = equivalences.Ceiling("pegi", "PG")
+	assert.Equal(t, rating.ErrUnknownLevel, err)
+
+	equivalent, err := equivalences.Equivalent("pegi", "7")
+	require.Nil(t, err)
+	assert.Equal(t, "10", equivalent)
+
+	invalid := []string{
+		"",
+		"mpaa:G=L",
+		rating.DefaultEquivalences + ",mpaa:X=L",
+		rating.DefaultEquivalences + ",bbfc:15=14",
+		rating.DefaultEquivalences + ",classind:L=10",
+		rating.DefaultEquivalences + ",pegi:18=21",
+		rating.DefaultEquivalences + ",pegi:16",
+		// more restrictive levels can't be equivalent to less restrictive ones
+		rating.DefaultEquivalences + ",mpaa:R=10",
+	}
+	for _, sample := range invalid {
+		_, err := rating.ParseEquivalences(sample)
+		assert.NotNil(t, err, sample)
+	}
+}
+
+func TestValidateVideoRatings(t *testing.T) {
+	video := models.Video{
+		ID:           uuid.New().String(),
+		Title:        "Film title",
+		Description:  "Film description with many details, sinopse, cast and marketing descriptions",
+		YearLaunched: 2020,
+		Duration:     90,
+	}
+
+	samples := []struct {
+		rating  string
+		ratings models.Ratings
+		valid   bool
+	}{
+		{"12", models.Ratings{"mpaa": "PG-13", "pegi": "12"}, true},
+		{"", models.Ratings{"classind": "12", "mpaa": "PG-13"}, true},
+		{"12", models.Ratings{"classind": "12"}, true},
+		{"12", models.Ratings{"classind": "14"}, false},
+		{"12", models.Ratings{"mpaa": "12"}, false},
+		{"12", models.Ratings{"bbfc": "12A"}, false},
+		{"", models.Ratings{"mpaa": "PG-13"}, true},
+		{"", models.Ratings{"pegi": "PG-13"}, false},
+		{"", models.Ratings{}, false},
+		{"", nil, false},
+	}
+	for _, sample := range samples {
+		v := video
+		v.Rating = sample.rating
+		v.Ratings = sample.ratings
+		v.Prepare()
+		err := v.Validate("create")
+		assert.Equal(t, sample.valid, err == nil, sample)
+	}
+
+	update := models.Video{ID: video.ID, Ratings: models.Ratings{"pegi": "16"}}
+	assert.Nil(t, update.Validate("update"))
+	update.Ratings = models.Ratings{"pegi": "PG"}
+	assert.NotNil(t, update.Validate("update"))
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/auth"
+	"video-catalog/controllers"
+	"video-catalog/search"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingBackend answers every search with one hit and keeps the query
+type recordingBackend struct {
+	query search.Query
+}
+
+func (b *recordingBackend) Search(ctx context.Context, q search.Query) (search.Result, error) {
+	b.query = q
+	return search.Result{Total: 1, Hits: []search.Hit{{Type: search.TypeVideo, ID: "1", Title: "Ação"}}}, nil
+}
+
+func TestSearchCatalog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	backend := &recordingBackend{}
+	server := controllers.Server{Search: backend}
+
+	r := gin.New()
+	r.GET("/search", func(c *gin.Context) {
+		c.Set("principal", &auth.Principal{APIKeyID: "key", Scopes: []string{"video:read", "genre:read"}})
+	}, server.SearchCatalog)
+
+	samples := []struct {
+		query      string
+		statusCode int
+		types      []string
+	}{
+		{query: "q=acao", statusCode: http.StatusOK, types: []string{"genre", "video"}},
+		{query: "q=acao&types=video&limit=5", statusCode: http.StatusOK, types: []string{"video"}},
+		{query: "q=acao&types=category", statusCode: http.StatusForbidden},
+		{query: "q=acao&types=series", statusCode: http.StatusUnprocessableEntity},
+		{query: "q=acao&limit=1000", statusCode: http.StatusUnprocessableEntity},
+		{query: "q=+", statusCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, v := range samples {
+		backend.query = search.Query{}
+		req, _ := http.NewRequest(http.MethodGet, "/search?"+v.query, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		require.Equal(t, v.statusCode, rr.Code, v.query)
+		if v.statusCode == http.StatusOK {
+			require.Equal(t, v.types, backend.query.Types)
+			require.Equal(t, "acao", backend.query.Text)
+
+			result := search.Result{}
+			require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &result))
+			require.Len(t, result.Hits, 1)
+		}
+	}
+}
+
+func TestSearchCatalogFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	backend := &recordingBackend{}
+	server := controllers.Server{Search: backend}
+
+	r := gin.New()
+	r.GET("/search", func(c *gin.Context) {
+		c.Set("principal", &auth.Principal{APIKeyID: "key", Scopes: []string{"video:read"}})
+	}, server.SearchCatalog)
+
+	samples := []struct {
+		query      string
+		statusCode int
+	}{
+		{query: "q=acao&rating=12,14&rating=L&decade=2010&duration=long&opened=true&facets=true", statusCode: http.StatusOK},
+		{query: "q=acao&rating=99", statusCode: http.StatusUnprocessableEntity},
+		{query: "q=acao&decade=2015", statusCode: http.StatusUnprocessableEntity},
+		{query: "q=acao&duration=endless", statusCode: http.StatusUnprocessableEntity},
+		{query: "q=acao&category=abc", statusCode: http.StatusUnprocessableEntity},
+		{query: "q=acao&opened=maybe", statusCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, v := range samples {
+		req, _ := http.NewRequest(http.MethodGet, "/search?"+v.query, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		require.Equal(t, v.statusCode, rr.Code, v.query)
+	}
+
+	require.Equal(t, []string{"12", "14", "L"}, backend.query.Filter.Ratings)
+	require.Equal(t, []int{2010}, backend.query.Filter.Decades)
+	require.Equal(t, []string{"long"}, backend.query.Filter.Durations)
+	require.True(t, *backend.query.Filter.Opened)
+	require.True(t, backend.query.Facets)
+}
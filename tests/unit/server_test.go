@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+	"video-catalog/changefeed"
+	"video-catalog/config"
+	"video-catalog/controllers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// slowWorker takes stopFor to return once cancelled, and records when it
+// was cancelled and whether it returned
+type slowWorker struct {
+	stopFor   time.Duration
+	cancelled atomic.Value
+	stopped   int32
+}
+
+func (w *slowWorker) Run(ctx context.Context) error {
+	<-ctx.Done()
+	w.cancelled.Store(time.Now())
+	time.Sleep(w.stopFor)
+	atomic.StoreInt32(&w.stopped, 1)
+	return ctx.Err()
+}
+
+func TestServeShutsDownGracefully(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	// the drain uses most of its timeout, the worker still gets its own
+	cfg.HTTP.ShutdownTimeout = 500 * time.Millisecond
+	cfg.HTTP.WorkerShutdownTimeout = time.Second
+
+	server := controllers.Server{Config: cfg, Changes: changefeed.NewLog(10)}
+	worker := &slowWorker{stopFor: 300 * time.Millisecond}
+	server.AddWorker("slow", worker)
+
+	started := make(chan struct{})
+	var finished atomic.Value
+	server.Router = gin.New()
+	server.Router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		time.Sleep(400 * time.Millisecond)
+		finished.Store(time.Now())
+		c.Status(http.StatusOK)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, listener) }()
+
+	status := make(chan int, 1)
+	go func() {
+		res, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			status <- 0
+			return
+		}
+		res.Body.Close()
+		status <- res.StatusCode
+	}()
+	<-started
+	stop()
+
+	select {
+	case err := <-served:
+		require.Nil(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Serve did not return")
+	}
+	require.Equal(t, http.StatusOK, <-status)
+	require.Equal(t, int32(1), atomic.LoadInt32(&worker.stopped))
+	// workers are stopped once requests are drained
+	require.True(t, worker.cancelled.Load().(time.Time).After(finished.Load().(time.Time)))
+}
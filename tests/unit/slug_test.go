@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"video-catalog/slug"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeSlug(t *testing.T) {
+	samples := []struct {
+		text string
+		slug string
+	}{
+		{"Ação", "acao"},
+		{"  O Poderoso Chefão: Parte II  ", "o-poderoso-chefao-parte-ii"},
+		{"Tom &amp; Jerry", "tom-jerry"},
+		{"Straße", "strasse"},
+		{"Æon Flux", "aeon-flux"},
+		{"Łódź", "lodz"},
+		{"İstanbul", "istanbul"},
+		{"2001: A Space Odyssey", "2001-a-space-odyssey"},
+		{"---", ""},
+		{"日本", ""},
+	}
+	for _, sample := range samples {
+		assert.Equal(t, sample.slug, slug.Make(sample.text), sample.text)
+	}
+
+	long := slug.Make(strings.Repeat("word ", 100))
+	assert.True(t, len(long) <= slug.MaxLength)
+	assert.False(t, strings.HasSuffix(long, "-"))
+	assert.True(t, strings.HasSuffix(long, "word"))
+}
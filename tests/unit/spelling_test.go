@@ -0,0 +1,22 @@
+package tests
+
+import (
+	"testing"
+	"video-catalog/search"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVocabularySuggest(t *testing.T) {
+	vocabulary := search.NewVocabulary([]string{"Stranger Things", "Strange Days", "Cidade de Deus", "Ação"})
+
+	require.Equal(t, []string{"stranger things", "strange things"}, vocabulary.Suggest("stranjer things", 3))
+	require.Equal(t, []string{"stranger things"}, vocabulary.Suggest("stranjer things", 1))
+	// transpositions and accents
+	require.Equal(t, []string{"cidade de deus"}, vocabulary.Suggest("cidaed de deus", 3))
+	require.Equal(t, []string{"ação"}, vocabulary.Suggest("acoa", 3))
+
+	// known words and words too far from any are not corrected
+	require.Empty(t, vocabulary.Suggest("Stranger Things", 3))
+	require.Empty(t, vocabulary.Suggest("xyzzyx things", 3))
+}
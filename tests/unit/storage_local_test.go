@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"video-catalog/storage"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorageSizeAndURL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	local, err := storage.NewLocal(dir, "http://cdn.local/")
+	require.Nil(t, err)
+	ctx := context.Background()
+
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "videos", "1"), 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "videos", "1", "file.mp4"), []byte("content"), 0644))
+
+	n, err := local.Size(ctx, "videos/1/file.mp4")
+	require.Nil(t, err)
+	require.Equal(t, int64(7), n)
+	require.Equal(t, "http://cdn.local/videos/1/file.mp4", local.URL("videos/1/file.mp4"))
+
+	_, err = local.Size(ctx, "videos/1/missing.mp4")
+	require.Equal(t, storage.ErrNotFound, err)
+	_, err = local.Size(ctx, "videos/1")
+	require.Equal(t, storage.ErrNotFound, err)
+	require.Nil(t, local.Ping(ctx))
+}
+
+func TestLocalStorageRejectsKeysOutsideRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	local, err := storage.NewLocal(dir, "")
+	require.Nil(t, err)
+
+	_, err = local.Size(context.Background(), "../escape.txt")
+	require.Equal(t, storage.ErrInvalidKey, err)
+}
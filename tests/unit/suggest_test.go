@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"video-catalog/auth"
+	"video-catalog/controllers"
+	"video-catalog/search"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSuggester answers every request with one suggestion and keeps
+// the query
+type recordingSuggester struct {
+	query search.SuggestQuery
+}
+
+func (s *recordingSuggester) Suggest(ctx context.Context, q search.SuggestQuery) ([]search.Suggestion, error) {
+	s.query = q
+	return []search.Suggestion{{Type: search.TypeGenre, ID: "1", Name: "Ação", Match: search.MatchPrefix}}, nil
+}
+
+func TestSuggest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	suggester := &recordingSuggester{}
+	server := controllers.Server{Suggester: suggester}
+
+	r := gin.New()
+	r.GET("/suggest", func(c *gin.Context) {
+		c.Set("principal", &auth.Principal{APIKeyID: "key", Scopes: []string{"genre:read", "cast_member:read", "video:read"}})
+	}, server.Suggest)
+
+	samples := []struct {
+		query      string
+		statusCode int
+		types      []string
+		limit      int
+	}{
+		{query: "q=ac", statusCode: http.StatusOK, types: []string{"genre", "cast_member"}, limit: 10},
+		{query: "q=ac&types=cast_member&limit=3", statusCode: http.StatusOK, types: []string{"cast_member"}, limit: 3},
+		{query: "q=ac&types=category", statusCode: http.StatusForbidden},
+		{query: "q=ac&types=video", statusCode: http.StatusUnprocessableEntity},
+		{query: "q=ac&limit=100", statusCode: http.StatusUnprocessableEntity},
+		{query: "q=", statusCode: http.StatusUnprocessableEntity},
+	}
+
+	for _, v := range samples {
+		suggester.query = search.SuggestQuery{}
+		req, _ := http.NewRequest(http.MethodGet, "/suggest?"+v.query, nil)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		require.Equal(t, v.statusCode, rr.Code, v.query)
+		if v.statusCode == http.StatusOK {
+			require.Equal(t, v.types, suggester.query.Types)
+			require.Equal(t, v.limit, suggester.query.Limit)
+
+			suggestions := []search.Suggestion{}
+			require.Nil(t, json.Unmarshal(rr.Body.Bytes(), &suggestions))
+			require.Len(t, suggestions, 1)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"video-catalog/models"
+	"video-catalog/webhooks"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"type":"video.deleted"}`)
+	mac := hmac.New(sha256.New, []byte("a-webhook-secret"))
+	mac.Write([]byte("1603756800." + string(body)))
+
+	signature := webhooks.Sign("a-webhook-secret", 1603756800, body)
+	require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), signature)
+	require.NotEqual(t, signature, webhooks.Sign("a-webhook-secret", 1603756801, body))
+	require.NotEqual(t, signature, webhooks.Sign("another-webhook-secret", 1603756800, body))
+}
+
+func TestValidateWebhook(t *testing.T) {
+	samples := []struct {
+		webhook models.Webhook
+		valid   bool
+	}{
+		{models.Webhook{URL: "https://partner.example/hooks", EventTypes: models.StringList{"video.*"}, Secret: "0123456789abcdef"}, true},
+		{models.Webhook{URL: "ftp://partner.example/hooks", EventTypes: models.StringList{"video.*"}, Secret: "0123456789abcdef"}, false},
+		{models.Webhook{URL: "/hooks", EventTypes: models.StringList{"video.*"}, Secret: "0123456789abcdef"}, false},
+		{models.Webhook{URL: "https://partner.example/hooks", Secret: "0123456789abcdef"}, false},
+		{models.Webhook{URL: "https://partner.example/hooks", EventTypes: models.StringList{"video.*"}, Secret: "short"}, false},
+	}
+
+	for _, v := range samples {
+		v.webhook.Prepare()
+		err := v.webhook.Validate()
+		if v.valid {
+			require.Nil(t, err, v.webhook.URL)
+		} else {
+			require.Error(t, err, v.webhook.URL)
+		}
+	}
+}
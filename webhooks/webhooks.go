@@ -0,0 +1,252 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"video-catalog/config"
+	"video-catalog/events"
+	"video-catalog/models"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// headers sent with every delivery
+const (
+	SignatureHeader = "X-Catalog-Signature"
+	TimestampHeader = "X-Catalog-Timestamp"
+	EventHeader     = "X-Catalog-Event"
+	DeliveryHeader  = "X-Catalog-Delivery"
+)
+
+// senderLockID is the postgres advisory lock held while claiming
+// deliveries, so a delivery isn't sent by two replicas at once
+const senderLockID = 4242002
+
+// Sign returns the signature of a delivery: the hex HMAC-SHA256, keyed with
+// the webhook secret, of the timestamp, a dot and the body. Receivers
+// should compare it in constant time and reject old timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Queue stores a delivery of event for every matching active webhook.
+// Catalog writes call it in the transaction writing the event to the
+// outbox, so webhooks get the event even while the broker is down.
+// Queueing is idempotent, so an event queued twice is delivered once.
+func Queue(tx *gorm.DB, event events.Event) error {
+	webhook := models.Webhook{}
+	webhooks, err := webhook.FindActive(tx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	for _, w := range *webhooks {
+		if !subscribed(w, event.Type) {
+			continue
+		}
+		delivery := models.WebhookDelivery{
+			ID:            uuid.NewV4().String(),
+			WebhookID:     w.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       models.JSON(payload),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		if _, err := delivery.Create(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func subscribed(w models.Webhook, eventType string) bool {
+	for _, pattern := range w.EventTypes {
+		if events.MatchRoutingKey(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sender posts the queued deliveries, retrying failed ones with
+// exponential backoff
+type Sender struct {
+	db     *gorm.DB
+	client *http.Client
+	cfg    config.WebhookConfig
+}
+
+// NewSender returns a sender of the deliveries stored in db
+func NewSender(db *gorm.DB, cfg config.WebhookConfig) *Sender {
+	return &Sender{db: db, client: &http.Client{Timeout: cfg.Timeout}, cfg: cfg}
+}
+
+// Run sends due deliveries every poll interval until ctx is cancelled
+func (s *Sender) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SendDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error sending webhooks: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends one batch of due deliveries and returns how many were
+// delivered. The batch is claimed in a short transaction and posted
+// outside of it, so slow endpoints hold no locks; the claim keeps other
+// senders off the batch for as long as posting it may take.
+func (s *Sender) SendDue(ctx context.Context) (int, error) {
+	deliveries, err := s.claim()
+	if err != nil {
+		return 0, err
+	}
+
+	webhooks := map[string]*models.Webhook{}
+	delivered := 0
+	for i := range *deliveries {
+		d := &(*deliveries)[i]
+		webhook, ok := webhooks[d.WebhookID]
+		if !ok {
+			webhook = &models.Webhook{ID: d.WebhookID}
+			if err := webhook.FindByID(s.db); err != nil {
+				return delivered, err
+			}
+			webhooks[d.WebhookID] = webhook
+		}
+		// an earlier delivery of this batch may have disabled it
+		if !webhook.Active || ctx.Err() != nil {
+			if err := d.Release(s.db, time.Now()); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		sendErr := s.send(ctx, webhook, d)
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			return s.record(tx, webhook, d, sendErr)
+		})
+		if err != nil {
+			return delivered, err
+		}
+		if sendErr == nil {
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+// claim takes the next batch of due deliveries, nothing while another
+// sender is claiming
+func (s *Sender) claim() (*[]models.WebhookDelivery, error) {
+	deliveries := &[]models.WebhookDelivery{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if s.db.Dialect().GetName() == "postgres" {
+			lock := struct{ Locked bool }{}
+			if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?) AS locked", senderLockID).Scan(&lock).Error; err != nil {
+				return err
+			}
+			if !lock.Locked {
+				return nil
+			}
+		}
+
+		now := time.Now()
+		until := now.Add(time.Duration(s.cfg.BatchSize+1) * s.cfg.Timeout)
+		delivery := models.WebhookDelivery{}
+		claimed, err := delivery.ClaimDue(tx, now, until, s.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		deliveries = claimed
+		return nil
+	})
+	return deliveries, err
+}
+
+// send posts the delivery payload and stores the response status on d
+func (s *Sender) send(ctx context.Context, webhook *models.Webhook, d *models.WebhookDelivery) error {
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "video-catalog-webhooks")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, d.Payload))
+
+	d.ResponseStatus = 0
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	d.ResponseStatus = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+// record stores the outcome of an attempt on the delivery and its webhook
+func (s *Sender) record(tx *gorm.DB, webhook *models.Webhook, d *models.WebhookDelivery, sendErr error) error {
+	d.Attempts++
+	if sendErr == nil {
+		now := time.Now()
+		d.Status = models.WebhookDeliveryDelivered
+		d.DeliveredAt = &now
+		d.LastError = ""
+	} else {
+		d.LastError = sendErr.Error()
+		if d.Attempts >= s.cfg.MaxAttempts {
+			d.Status = models.WebhookDeliveryFailed
+		} else {
+			d.NextAttemptAt = time.Now().Add(events.Backoff(d.Attempts, s.cfg.MaxBackoff))
+		}
+	}
+
+	if err := d.Save(tx); err != nil {
+		return err
+	}
+	if err := webhook.RecordAttempt(tx, sendErr == nil, s.cfg.DisableAfter); err != nil {
+		return err
+	}
+	if !webhook.Active {
+		log.Printf("Webhook %s disabled after %d consecutive failures", webhook.ID, webhook.ConsecutiveFailures)
+	}
+	return nil
+}